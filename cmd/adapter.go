@@ -22,8 +22,6 @@ THE SOFTWARE.
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
 )
 
@@ -31,9 +29,6 @@ import (
 var adapterCmd = &cobra.Command{
 	Use:   "adapter",
 	Short: "Generates idiomatic go code for the infrastructure layer",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("adapter called")
-	},
 }
 
 func init() {
@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter"
+)
+
+// adapterAuditlogCmd represents the adapter auditlog command
+var adapterAuditlogCmd = &cobra.Command{
+	Use:   "auditlog",
+	Short: "Generates an audit adapter writing JSON lines",
+	Long: `Generates a reference implementation of the audit interface (see 'ddd-gen app command --audit')
+which writes one JSON line per command outcome to an io.Writer.
+
+  Every line records the time, user, target, command, topic, outcome, the error returned
+  to the caller and - on fact-based storage - the facts raised by the domain. The elevation
+  token of the actor is never written, only whether it was present.
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+  Expected / Recomended Folder Structure:
+    ./adapter
+    ├── auditlog
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── auditlog_gen.go         // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml adapter auditlog --type Logger
+
+  Code:
+    l := auditlog.NewLogger(os.Stderr)
+    h := command.NewBlockAccountHandlerWrapper(rw, p, l)
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_adapter.NewConfig(
+			viper.GetString("app"),
+			"",
+		)
+		if err != nil {
+			return err
+		}
+		return gen_adapter.GenAuditLog(sourceType, cfg)
+	},
+}
+
+func init() {
+	adapterCmd.AddCommand(adapterAuditlogCmd)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter"
+)
+
+// adapterEventstoreCmd represents the adapter eventstore command
+var adapterEventstoreCmd = &cobra.Command{
+	Use:   "eventstore",
+	Short: "Generates a fact-based storage adapter with in-memory and file backends",
+	Long: `Generates a storage adapter for fact-based (event sourced) applications, which appends the facts
+of an entity to the stream of its target identifier and rehydrates the entity by applying them in order.
+
+  The facts are encoded with the fact registry generated by protoc-gen-ddd next to the entity (Encode<Entity>Fact)
+  and upcasted on load (<Entity>.ApplyStored), so facts stored in older schema versions remain readable.
+
+  Backends:
+    Memory<Type>Backend         - keeps streams in memory, e.g. for tests
+    File<Type>Backend           - appends each stream to its own file in a directory; records are length
+                                  prefixed and checksummed, appends are synced to disk and a torn tail
+                                  left behind by a crash is truncated on the next access of the stream
+
+  Available Variants:
+    --snapshots               - storage also keeps the latest snapshot of each stream, so that only later
+                                facts are replayed (see 'ddd-gen app command --snapshot-every')
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    entity:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain/account.Account"
+
+  Expected / Recomended Folder Structure:
+    ./adapter
+    ├── eventstore
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── eventstore_gen.go       // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml adapter eventstore --type EventStore
+
+  Code:
+    b, err := eventstore.NewFileEventStoreBackend("/var/lib/svc/facts")
+    if err != nil {
+      ...
+    }
+    rw := eventstore.NewEventStore(b, account.AccountUpcasterChain{
+      "domain.facts.BalanceModified": {1: upcastBalanceModifiedV1},
+    })
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_adapter.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("entity"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_adapter.GenEventStore(sourceType, useSnapshots, cfg)
+	},
+}
+
+func init() {
+	adapterCmd.AddCommand(adapterEventstoreCmd)
+	adapterEventstoreCmd.Flags().BoolVar(&useSnapshots, "snapshots", false, "Snapshot variant: storage persists snapshots")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter"
+)
+
+// adapterKvCmd represents the adapter kv command
+var adapterKvCmd = &cobra.Command{
+	Use:   "kv",
+	Short: "Generates a key-value storage adapter for model-based applications",
+	Long: `Generates a storage adapter which stores the entity in a key-value backend, one key per target identifier.
+
+  The backend is a tiny generated interface (<Type>Backend: Get / Put / Delete / Iterate), so that any embedded
+  key-value store can be plugged in. File<Type>Backend is a reference implementation which stores each key in
+  its own file; values are replaced atomically by writing, syncing and renaming a temporary file.
+
+  The entity is encoded by a generated codec through MarshalToStore / UnmarshalFromStore, so private fields
+  round-trip as well. 'ddd-gen domain entity' must have been run on the entity before. The codec is JSON based:
+  fields which do not survive a JSON round trip (e.g. interfaces, unexported fields of a struct) are rejected,
+  unless their type implements json.Marshaler and json.Unmarshaler.
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    entity:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain/account.Account"
+
+  Expected / Recomended Folder Structure:
+    ./adapter
+    ├── kvstore
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── kvstore_gen.go          // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml adapter kv --type KVStore
+
+  Code:
+    b, err := kvstore.NewFileKVStoreBackend("/var/lib/svc/accounts")
+    if err != nil {
+      ...
+    }
+    rw := kvstore.NewKVStore(b)
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_adapter.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("entity"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_adapter.GenKVStore(sourceType, cfg)
+	},
+}
+
+func init() {
+	adapterCmd.AddCommand(adapterKvCmd)
+}
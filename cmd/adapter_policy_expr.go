@@ -0,0 +1,100 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter"
+)
+
+// adapterPolicyExprCmd represents the adapter policy-expr command
+var adapterPolicyExprCmd = &cobra.Command{
+	Use:   "policy-expr",
+	Short: "Generates an attribute based policy adapter from policy expressions",
+	Long: `Generates an implementation of the policy interfaces (Can / CanOnTarget) which decides on commands by boolean
+  expressions over the actor, the entity and - with --policy-payload - the command. The expressions are declared
+  per command in the config file and compiled into go code at generation time.
+
+  Within an expression:
+
+    actor   - the actor (OffersAuthorizable), e.g. actor.GetUser, actor.GetElevationToken
+    entity  - the entity (a pointer), e.g. entity.Holder()
+    cmd     - the domain command named by the policy, e.g. cmd.Amount (requires --policy-payload)
+
+  Getters of actor, entity and cmd may be written without parentheses. The expressions are type checked against
+  the authorizable interface, the entity and the domain commands: references to unknown or unexported members and
+  expressions which are not boolean fail the generation with an error naming the policy.
+
+  Decisions deny by default: nil actors or entities and commands without a policy are denied. CanOnTarget is
+  called before the entity is loaded, it denies commands whose expression refers to the entity.
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    domain:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain"
+    entity:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain/account.Account"
+
+    # Policies
+    policies:
+      - command:                  ArchiveAccount
+        allow:                    "actor.GetUser == entity.Holder() && entity.Balance() == 0"
+      - command:                  BlockAccount
+        allow:                    "actor.GetElevationToken != \"\""
+
+  Expected / Recomended Folder Structure:
+    ./adapter
+    ├── policy
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── policy_expr_gen.go      // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml adapter policy-expr --type Policy
+
+  Code:
+    h := command.NewArchiveAccountHandlerWrapper(rw, policy.NewPolicy())
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_adapter.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("entity"),
+		)
+		if err != nil {
+			return err
+		}
+		var policies []gen_adapter.PolicyExpr
+		if err := viper.UnmarshalKey("policies", &policies); err != nil {
+			return err
+		}
+		return gen_adapter.GenPolicyExpr(sourceType, viper.GetString("domain"), policies, usePolicyPayload, cfg)
+	},
+}
+
+func init() {
+	adapterCmd.AddCommand(adapterPolicyExprCmd)
+	adapterPolicyExprCmd.Flags().BoolVar(&usePolicyPayload, "policy-payload", false, "Policy payload variant: the policy adapter receives the command payload")
+}
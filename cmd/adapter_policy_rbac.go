@@ -0,0 +1,135 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter"
+)
+
+// adapterPolicyRbacCmd represents the adapter policy-rbac command
+var adapterPolicyRbacCmd = &cobra.Command{
+	Use:   "policy-rbac",
+	Short: "Generates a role based policy adapter driven by a YAML policy file",
+	Long: `Generates an implementation of the policy interfaces (Can / CanOnTarget) which grants commands by the roles
+  of the actor, as declared in a YAML policy file. The action is the name of the command.
+
+    # ./policy.yaml
+    roles:
+      teller:  [ModifyBalance, BlockAccount]
+      auditor: [ValidateHolder]
+      admin:   ["*"]                # grants all commands
+    users:
+      alice:   [teller]
+      bob:     [admin]
+
+  The roles of an actor are the roles bound to its GetUser() in the policy file, plus - if the actor implements
+  RoleHolder (Roles() []string) - its own roles, e.g. mapped from a token claim. Those roles are trusted as-is:
+  they must stem from a verified source, such as the claims of a token verified by 'ddd-gen ports actorauth',
+  never from an actor a client asserts unverified, such as the default 'X-Actor' header of the http port.
+
+  Decisions deny by default: actors without roles, roles which are not declared and commands which are not
+  granted to any role of the actor are denied. The policy file is parsed strictly, unknown keys and users
+  bound to undeclared roles are rejected.
+
+  Reload reloads the policy file when its modification time or size changed, Watch does so periodically. An
+  invalid policy file is not loaded, the last valid policy stays in force.
+
+  The policy test harness (Load<Type>Tests / Test) checks the policy against a YAML file of cases:
+
+    # ./policy_test.yaml
+    - {name: tellers modify, user: alice, action: ModifyBalance, allow: true}
+    - {name: roles from token, roles: [auditor], action: BlockAccount, allow: false}
+
+  Available Variants:
+    --policy-payload          - the policy adapter receives the command payload
+    --tenancy                 - actors belong to a tenant (GetTenant()): users are bound to roles per tenant,
+                                bindings of one tenant never apply to the actors of another
+
+                                  # ./policy.yaml
+                                  roles:
+                                    teller:  [ModifyBalance, BlockAccount]
+                                  tenants:
+                                    acme:
+                                      alice: [teller]
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    entity:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain/account.Account"
+
+  Expected / Recomended Folder Structure:
+    ./adapter
+    ├── policy
+    │   ├── doc.go                  // place the go:generate directive here
+    │   ├── policy_rbac_gen.go      // generated by this command
+    │   └── policy_test.go          // runs the policy test harness
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml adapter policy-rbac --type Policy
+
+  Code:
+    p, err := policy.NewPolicy("/etc/svc/policy.yaml")
+    if err != nil {
+      ...
+    }
+    go p.Watch(ctx, 5*time.Second, func(err error) { log.Print(err) })
+    h := command.NewBlockAccountHandlerWrapper(rw, p)
+
+  Test:
+    func TestPolicy(t *testing.T) {
+      p, err := NewPolicy("testdata/policy.yaml")
+      if err != nil {
+        t.Fatal(err)
+      }
+      tests, err := LoadPolicyTests("testdata/policy_test.yaml")
+      if err != nil {
+        t.Fatal(err)
+      }
+      for _, err := range p.Test(tests) {
+        t.Error(err)
+      }
+    }
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_adapter.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("entity"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_adapter.GenPolicyRBAC(sourceType, usePolicyPayload, useTenancy, cfg)
+	},
+}
+
+func init() {
+	adapterCmd.AddCommand(adapterPolicyRbacCmd)
+	adapterPolicyRbacCmd.Flags().BoolVar(&usePolicyPayload, "policy-payload", false, "Policy payload variant: the policy adapter receives the command payload")
+	adapterPolicyRbacCmd.Flags().BoolVar(&useTenancy, "tenancy", false, "Multi-tenancy variant: users are bound to roles per tenant")
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter"
+)
+
+var (
+	useSnapshots bool
+)
+
+// adapterShardrouterCmd represents the adapter shardrouter command
+var adapterShardrouterCmd = &cobra.Command{
+	Use:   "shardrouter",
+	Short: "Generates a storage adapter routing to shards",
+	Long: `Generates a storage adapter which wraps several storage adapters (one per shard) and routes
+Load / Save / SaveFacts to the shard selected by a key function over the target.
+
+  Targets of an unknown shard are rejected with an UnknownShardError naming shard and target.
+  <Type>KeyFromIdentifier(sep, esc, n) returns a key function which joins the first n parts of the target
+  identifier, e.g. continent and zone of a target generated by protoc-gen-ddd with ordered keys. Separators
+  escaped by esc within a part (<Target>Escape of protoc-gen-ddd) do not split it.
+
+  Available Variants:
+    --fact-based              - storage persists domain facts (event sourcing) instead of the entity
+    --snapshots               - storage persists snapshots (see 'ddd-gen app command --snapshot-every')
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    entity:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain/account.Account"
+
+  Expected / Recomended Folder Structure:
+    ./adapter
+    ├── shardrouter
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── shardrouter_gen.go      // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml adapter shardrouter --fact-based --type ShardRouter
+
+  Code:
+    rw := shardrouter.NewShardRouter(
+      shardrouter.ShardRouterKeyFromIdentifier(distinguishable.TargetSeparator, distinguishable.TargetEscape, 2),
+      map[string]app.RequiresStorageWriterReader{
+        "eu-west": euWest,
+        "us-east": usEast,
+      },
+    )
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_adapter.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("entity"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_adapter.GenShardRouter(sourceType, useFactStorage, useSnapshots, cfg)
+	},
+}
+
+func init() {
+	adapterCmd.AddCommand(adapterShardrouterCmd)
+	adapterShardrouterCmd.Flags().BoolVarP(&useFactStorage, "fact-based", "f", false, "Event sourcing variant")
+	adapterShardrouterCmd.Flags().BoolVar(&useSnapshots, "snapshots", false, "Snapshot variant: storage persists snapshots")
+}
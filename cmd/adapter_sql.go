@@ -0,0 +1,102 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter"
+)
+
+var (
+	sqlDialect string
+	sqlTable   string
+)
+
+// adapterSqlCmd represents the adapter sql command
+var adapterSqlCmd = &cobra.Command{
+	Use:   "sql",
+	Short: "Generates a database/sql storage adapter for model-based applications",
+	Long: `Generates a database/sql storage adapter which stores the entity in a table, one row per target identifier.
+
+  The columns are derived from the fields of the entity as enumerated by UnmarshalFromStore (public and private),
+  so 'ddd-gen domain entity' must have been run on the entity before. Load hydrates the entity through
+  UnmarshalFromStore, Save upserts the fields returned by MarshalToStore. Fields without a column type of their
+  own (slices, maps, structs) are stored JSON encoded.
+
+  Table and column names are quoted, so that they may be reserved words (e.g. user or order).
+
+  Generates an integration test which round-trips the entity through a database. It is skipped unless
+  <TYPE>_DRIVER and <TYPE>_DSN are set, e.g. SQL_STORE_DRIVER=pgx SQL_STORE_DSN=postgres://... with the
+  driver registered by a blank import in a test file of the package. With the sqlite dialect, a test file
+  under the 'sqlite' build tag registers github.com/mattn/go-sqlite3 (cgo) and defaults to an in-memory
+  database, so 'go test -tags sqlite' runs it out of the box. The entity is built from sample values,
+  which may not pass your validator; replace test<Type>Entity in an init function of a test file if so.
+
+  Available Variants:
+    --dialect postgres|sqlite - column types and placeholders (default postgres)
+    --table NAME              - table name (default: entity name in snake case)
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    entity:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain/account.Account"
+
+  Expected / Recomended Folder Structure:
+    ./adapter
+    ├── sqlstore
+    │   ├── doc.go                  // place the go:generate directive here
+    │   ├── sqlstore_gen.go         // generated by this command
+    │   ├── sqlstore_gen_test.go    // generated by this command
+    │   └── sqlstore_sqlite_gen_test.go // generated by this command with --dialect sqlite
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml adapter sql --dialect postgres --type SQLStore
+
+  Code:
+    s := sqlstore.NewSQLStore(db)
+    if err := s.Migrate(ctx); err != nil {
+      ...
+    }
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_adapter.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("entity"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_adapter.GenSQLStore(sourceType, sqlDialect, sqlTable, cfg)
+	},
+}
+
+func init() {
+	adapterCmd.AddCommand(adapterSqlCmd)
+	adapterSqlCmd.Flags().StringVar(&sqlDialect, "dialect", "postgres", "SQL dialect: postgres or sqlite")
+	adapterSqlCmd.Flags().StringVar(&sqlTable, "table", "", "Table name (default: entity name in snake case)")
+}
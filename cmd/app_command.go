@@ -27,6 +27,14 @@ import (
 	"github.com/xoe-labs/ddd-gen/pkg/gen_app"
 )
 
+var (
+	useTenancy       bool
+	useAudit         bool
+	usePolicyPayload bool
+	useScheduling    bool
+	snapshotEvery    uint
+)
+
 // appCommandCmd represents the app command
 var appCommandCmd = &cobra.Command{
 	Use:   "command",
@@ -36,10 +44,33 @@ var appCommandCmd = &cobra.Command{
   Available Annotations:
     Key "command" | Separator: ";"
       w/o policy              - handler that will not know how to check access against the policy interface
+      authorize-before-load   - handler that checks access against the target policy interface (CanOnTarget)
+                                before loading the entity: denials are cheap and do not reveal existence
       topic,<topic>           - topic is generated from the last Word, if not desired, it can be mannually overridden
       adapters,key:import/path,key2:import/path2
                               - add additional domain service adapters for this command handler
 
+  Available Variants:
+    --fact-based              - storage persists domain facts (event sourcing) instead of the entity;
+                                commands load through LoadSince and SaveFacts fails with a StorageSaving error
+                                if facts of a concurrent command were persisted since loading
+    --tenancy                 - actors and targets belong to a tenant: cross-tenant commands, as well as
+                                commands of actors or on targets without tenant, are rejected before loading
+                                and storage is scoped per tenant
+                                (both know their GetTenant(), e.g. a 'tenant' field of their message)
+    --audit                   - every exit path of a command handler is recorded by an auditor
+                                (see 'ddd-gen adapter auditlog' for a reference implementation)
+    --policy-payload          - the policy adapter receives the command payload, so that attribute
+                                based rules can look at the command's fields
+    --scheduling              - commands can be scheduled (Schedule<Cmd>) and are handled by a generated
+                                scheduler runner once due (with an in-memory store and an injectable clock);
+                                commands failing with storage errors stay scheduled and are retried with
+                                exponential backoff, up to a maximum number of attempts (see NewSchedulerRunner),
+                                which requires the errors generated by 'ddd-gen app errors'
+    --snapshot-every N        - fact-based storage snapshots the entity every N facts (requires --fact-based),
+                                see MarshalSnapshot / UnmarshalSnapshot generated by 'ddd-gen domain entity --snapshots';
+                                commands load from LoadSnapshot and replay later facts through LoadSince
+
   Config File: (will be complemented by this command)
 
     # ./ddd-config.yaml
@@ -59,9 +90,12 @@ var appCommandCmd = &cobra.Command{
     ├── command
     │   ├── commands.go             // define your tags here (see example)
     │   ├── make_new_account_gen.go // generated by this command
+    │   ├── scheduler_gen.go        // generated by this command (--scheduling)
     │   └── ...                     // generated by this command
     ├── storage.go                  // generated storage interface
     ├── policy.go                   // generated policy interface
+    ├── audit.go                    // generated audit interface (--audit)
+    ├── scheduler.go                // generated scheduler interface (--scheduling)
     ├── domain.go                   // generated domain interface
     ├── identiy.go                  // generated identity assertion interface
     ├── distinguishable.go          // generated stub of distinguishable interface (edit & implement!)
@@ -70,8 +104,8 @@ var appCommandCmd = &cobra.Command{
     │   └── actor.go                // implement your actor here
     ├── distinguishable
     │   └── target.go               // implement your target here
-    ├── error
-    │   └── errors.go               // implement your errors & their constructors here
+    ├── errors
+    │   └── errors_gen.go           // generated by 'ddd-gen app errors' (or implement your own)
     └── ...`,
 	Example: `  Command:
     //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml app command --type Commands
@@ -100,6 +134,11 @@ var appCommandCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		cfg.Variants.UseTenancy = useTenancy
+		cfg.Variants.UseAudit = useAudit
+		cfg.Variants.UsePolicyPayload = usePolicyPayload
+		cfg.Variants.UseScheduling = useScheduling
+		cfg.Variants.SnapshotEvery = snapshotEvery
 		return gen_app.Gen(sourceType, useFactStorage, cfg)
 	},
 }
@@ -107,4 +146,9 @@ var appCommandCmd = &cobra.Command{
 func init() {
 	appCmd.AddCommand(appCommandCmd)
 	appCommandCmd.Flags().BoolVarP(&useFactStorage, "fact-based", "f", false, "Event sourcing variant")
+	appCommandCmd.Flags().BoolVar(&useTenancy, "tenancy", false, "Multi-tenancy variant: actors and targets belong to a tenant, storage is scoped per tenant")
+	appCommandCmd.Flags().BoolVar(&useAudit, "audit", false, "Audit variant: every command outcome is recorded by an auditor")
+	appCommandCmd.Flags().BoolVar(&usePolicyPayload, "policy-payload", false, "Policy payload variant: the policy adapter receives the command payload")
+	appCommandCmd.Flags().BoolVar(&useScheduling, "scheduling", false, "Scheduling variant: commands can be scheduled to be handled later")
+	appCommandCmd.Flags().UintVar(&snapshotEvery, "snapshot-every", 0, "Snapshot variant: fact-based storage snapshots the entity every N facts")
 }
@@ -0,0 +1,82 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_app"
+)
+
+// appErrorsCmd represents the app errors command
+var appErrorsCmd = &cobra.Command{
+	Use:   "errors",
+	Short: "Generates application error package",
+	Long: `Generates the application error package that the command handler wrappers use to signal failures.
+
+  Generated Error Kinds:
+    Authorization           - the caller is not authorized
+    TargetIdentification    - the target could not be identified
+    StorageLoading          - the storage failed to load the entity
+    StorageSaving           - the storage failed to save the entity
+    Domain                  - the domain failed to handle the command
+
+  Every error carries a kind and a code, supports errors.Is against its kind
+  sentinel (e.g. ErrAuthorization) and can be enriched with structured fields.
+
+  Config File: (will be complemented by this command)
+
+    # ./ddd-config.yaml
+
+    # Error Contructors
+    authorizationErrorNew:        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewAuthorizationError"
+    targetIdentificationErrorNew: "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewTargetIdentificationError"
+    storageLoadingErrorNew:       "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewStorageLoadingError"
+    storageSavingErrorNew:        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewStorageSavingError"
+    domainErrorNew:               "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewDomainError"
+
+  Expected / Recomended Folder Structure:
+    ./app
+    ├── errors
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── errors_gen.go           // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml app errors --type Error
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := gen_app.GenErrors(sourceType)
+		if err != nil {
+			return err
+		}
+		if viper.ConfigFileUsed() == "" {
+			return fmt.Errorf("no config file in use: cannot record the error constructors")
+		}
+		return complementConfig(viper.ConfigFileUsed(), entries)
+	},
+}
+
+func init() {
+	appCmd.AddCommand(appErrorsCmd)
+}
@@ -44,6 +44,27 @@ var domainEntityCmd = &cobra.Command{
       setter              - generates a simple setter for this field
       stringer            - generates a stringer for this field
       equal[,reflect]     - incorporates this field into the equality tester method, with reflect option: use reflect.DeepEqual
+      copy                - deep copies the value in constructors, getters, setters and the storage marshalers,
+                            required for pointer fields, which equal compares by the pointed-to values
+      nocopy              - opts slice and map fields out of deep copying, e.g. on hot paths
+
+  Defensive Copies:
+    Slice and map fields, including nested collections (e.g. map[string][]string), are deep copied in
+    constructors, getters, setters and the storage marshalers, so callers can not mutate internal state.
+    Types with a Clone() or Copy() method returning their own type are copied by it; pointers to types of other
+    packages holding references in unexported fields (e.g. *big.Int) can not be copied otherwise and are rejected,
+    values of such types (e.g. time.Time) are copied by assignment
+
+  Storage:
+    UnmarshalFromStore / MarshalToStore initialize and expose the full state, including private fields,
+    in the same field order, so that repositories (e.g. 'ddd-gen adapter sql') can round-trip the entity
+
+  Available Variants:
+    --snapshots               - MarshalSnapshot / UnmarshalSnapshot encode the full state, including private fields,
+                                as JSON, so that a fact-based repository does not need to replay every fact
+                                (see 'ddd-gen app command --snapshot-every'); fields which do not survive a JSON
+                                round trip (e.g. interfaces, unexported fields of a struct) are rejected, unless
+                                their type implements json.Marshaler and json.Unmarshaler
 
   Expected Folder Structure:
     ./domain
@@ -59,21 +80,23 @@ var domainEntityCmd = &cobra.Command{
 
   Code:
     type Account struct {
-        uuid    string ` + "`" + `entity:"required,field uuid is empty;equal,reflect"` + "`" + `
-        holder  string ` + "`" + `entity:"setter;getter;stringer"` + "`" + `
-        balance int64  ` + "`" + `entity:"private;equal;getter"` + "`" + `
+        uuid    string   ` + "`" + `entity:"required,field uuid is empty;equal,reflect"` + "`" + `
+        holder  string   ` + "`" + `entity:"setter;getter;stringer"` + "`" + `
+        balance int64    ` + "`" + `entity:"private;equal;getter"` + "`" + `
+        address *Address ` + "`" + `entity:"copy;getter;setter;equal"` + "`" + `
     }
 
-    Important: expects non-pointer type fields.
+    Important: pointer fields must be tagged 'copy'.
     Reason: pointers can inadvertedly corrupt your domain.
     Example: a caller can mutate the value.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return gen_domain.GenEntity(sourceType, validatorMethod)
+		return gen_domain.GenEntity(sourceType, validatorMethod, useSnapshots)
 	},
 }
 
 func init() {
 	domainCmd.AddCommand(domainEntityCmd)
 	domainEntityCmd.Flags().StringVarP(&validatorMethod, "validator", "v", "", "The validator method that constructors should reach out to")
+	domainEntityCmd.Flags().BoolVar(&useSnapshots, "snapshots", false, "Snapshot variant: generates MarshalSnapshot / UnmarshalSnapshot")
 }
@@ -0,0 +1,85 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_domain"
+)
+
+// domainValueCmd represents the value command
+var domainValueCmd = &cobra.Command{
+	Use:   "value",
+	Short: "Generates idiomatic go code for a value object within the domain layer",
+	Long: `Generates idiomatic go code for an immutable value object based on struct field tags.
+
+  Available Annotations:
+    Key "value" | Separator: ";"
+      required,error msg  - if not present in the constructor, an error with the provided message will be returned
+
+  Generated:
+    New<Type> / MustNew<Type>   - constructors, which validate the fields (and reach out to --validator)
+    <Field>                     - a getter per field
+    With<Field>                 - returns a copy with the field changed, validated like New<Type>
+    Equal                       - compares all fields, pointer fields by the pointed-to values
+    String                      - the native format of all fields
+    MarshalText / UnmarshalText - for values wrapping a single string, e.g. an Email
+    MarshalJSON / UnmarshalJSON - for all other values, encode the fields as an object
+
+  Unmarshaling goes through New<Type>, so invalid values can not enter the domain from the outside.
+  Values are immutable: fields must be unexported and pointers, slices and maps are deep copied.
+
+  Expected Folder Structure:
+    ./domain
+    ├── values.go
+    ├── values_money_gen.go // generated by this tool
+    ├── values_email_gen.go // generated by this tool
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen domain value --type YOURTYPE
+
+  Code:
+    //go:generate go run github.com/xoe-labs/ddd-gen domain value --type Money --validator validate
+    type Money struct {
+        amount   int64
+        currency string ` + "`" + `value:"required,currency is empty"` + "`" + `
+    }
+
+    func (m Money) validate() error { ... }
+
+    //go:generate go run github.com/xoe-labs/ddd-gen domain value --type Email
+    type Email struct {
+        address string ` + "`" + `value:"required,email address is empty"` + "`" + `
+    }
+
+    Important: expects unexported fields.
+    Reason: exported fields can be mutated, which evades validation.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return gen_domain.GenValue(sourceType, validatorMethod)
+	},
+}
+
+func init() {
+	domainCmd.AddCommand(domainValueCmd)
+	domainValueCmd.Flags().StringVarP(&validatorMethod, "validator", "v", "", "The validator method that constructors should reach out to")
+}
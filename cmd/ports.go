@@ -22,8 +22,6 @@ THE SOFTWARE.
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
 )
 
@@ -31,9 +29,6 @@ import (
 var portsCmd = &cobra.Command{
 	Use:   "ports",
 	Short: "Generates idiomatic go code for the interfaces layer",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("ports called")
-	},
 }
 
 func init() {
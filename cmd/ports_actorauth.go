@@ -0,0 +1,124 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports"
+)
+
+var actorAuthClaims map[string]string
+
+// portsActorauthCmd represents the ports actorauth command
+var portsActorauthCmd = &cobra.Command{
+	Use:   "actorauth",
+	Short: "Generates a JWT based actor extractor for the ports",
+	Long: `Generates a verifier which verifies JWTs offline and maps their claims onto the actor, so that ports do not
+  need to parse tokens by hand.
+
+  Tokens are verified against the keys of a local JSON Web Key Set (LoadJWKS), signed with one of:
+
+    HS256, HS384, HS512     -> oct keys
+    RS256, RS384, RS512     -> RSA keys
+    PS256, PS384, PS512     -> RSA keys
+    EdDSA                   -> OKP keys (Ed25519)
+
+  Tokens must carry an exp claim; nbf is validated if present, iss and aud if configured (Config). Claims are
+  mapped onto the fields of the actor by their proto name (--claim sub=user), as if they were its protojson
+  encoded fields. Without --claim, each field is mapped from the claim of the same name.
+
+  Failed verifications return a *Error, classified by its Kind (KindOf): NoToken, Malformed,
+  UnsupportedAlgorithm, UnknownKey, InvalidSignature, Expired, NotYetValid, InvalidIssuer, InvalidAudience
+  and InvalidClaims.
+
+  The verifier implements the ActorExtractor of the http port, reading the bearer token of the
+  'Authorization' header. ContextExtractor implements the ActorExtractor of the grpc port, reading the
+  token through a function, e.g. from the incoming metadata.
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    domain:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain"
+    commands:                     "github.com/xoe-labs/ddd-gen/internal/test-svc/app/command.Commands"
+    actor:                        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/authorizable.Actor"
+    target:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/app/distinguishable.Target"
+
+    # Error Contructors
+    authorizationErrorNew:        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewAuthorizationError"
+
+  Expected / Recomended Folder Structure:
+    ./port
+    ├── actorauth
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── actorauth_gen.go        // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml ports actorauth --type Verifier --claim sub=user --claim elev=elevation_token
+
+  Code:
+    keys, err := actorauth.LoadJWKS("/etc/svc/jwks.json")
+    if err != nil {
+      ...
+    }
+    v, err := actorauth.NewVerifier(keys, actorauth.Config{Issuer: "https://id.example.com", Leeway: time.Minute})
+    if err != nil {
+      ...
+    }
+
+    // http
+    h := httpport.NewHandler(&cmds, v, httpport.QueryTargetExtractor{})
+
+    // grpc
+    srv := grpcport.NewServer(&cmds, actorauth.ContextExtractor{Verifier: v, Token: func(ctx context.Context) string {
+      md, _ := metadata.FromIncomingContext(ctx)
+      if vs := md.Get("authorization"); len(vs) > 0 {
+        return actorauth.BearerToken(vs[0])
+      }
+      return ""
+    }})
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_ports.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("domain"),
+			viper.GetString("commands"),
+			viper.GetString("actor"),
+			viper.GetString("target"),
+			viper.GetString("authorizationErrorNew"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_ports.GenActorAuth(sourceType, actorAuthClaims, cfg)
+	},
+}
+
+func init() {
+	portsCmd.AddCommand(portsActorauthCmd)
+	portsActorauthCmd.Flags().StringToStringVar(&actorAuthClaims, "claim", nil, "Maps a token claim onto an actor field by its proto name, e.g. sub=user (repeatable)")
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports"
+)
+
+// portsCliCmd represents the ports cli command
+var portsCliCmd = &cobra.Command{
+	Use:   "cli",
+	Short: "Generates a CLI port for the application commands",
+	Long: `Generates cobra commands which let operators run the application commands from a shell, one subcommand
+  per entry of the commands struct, grouped under the command topic (e.g. 'ops account block-account').
+
+  Each subcommand has a flag per field of the domain command, derived from its proto descriptor: scalar
+  fields take plain values (enums by name), message, list and map fields take json values. Alternatively,
+  the command is read from a json or yaml file (--file), field flags take precedence.
+
+  The persistent flags --actor and --target are mapped to the actor (protojson encoded) and to the target
+  (parsed with Parse<Target>, as generated by protoc-gen-ddd).
+
+  The command handler wrappers are wired by a CommandsFunc against the configured adapters, only once a
+  subcommand is run.
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    domain:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain"
+    commands:                     "github.com/xoe-labs/ddd-gen/internal/test-svc/app/command.Commands"
+    actor:                        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/authorizable.Actor"
+    target:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/app/distinguishable.Target"
+
+    # Error Contructors
+    authorizationErrorNew:        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewAuthorizationError"
+
+  Expected / Recomended Folder Structure:
+    ./port
+    ├── cli
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── cli_gen.go              // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml ports cli --type CLI
+
+  Code:
+    root := cli.NewCLI("ops", func(ctx context.Context) (*command.Commands, error) {
+      // wire the command handler wrappers against the configured adapters
+      ...
+    })
+    if err := root.Execute(); err != nil {
+      os.Exit(1)
+    }
+
+  Shell:
+    ops account block-account --actor '{"user": "ops"}' --target eu-west-zrh-42 --file block.yaml
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_ports.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("domain"),
+			viper.GetString("commands"),
+			viper.GetString("actor"),
+			viper.GetString("target"),
+			viper.GetString("authorizationErrorNew"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_ports.GenCLI(sourceType, cfg)
+	},
+}
+
+func init() {
+	portsCmd.AddCommand(portsCliCmd)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports"
+)
+
+// portsConsumerCmd represents the ports consumer command
+var portsConsumerCmd = &cobra.Command{
+	Use:   "consumer",
+	Short: "Generates a message queue consumer port for the application commands",
+	Long: `Generates a consumer which receives commands from a message broker and hands them to the command handler
+  wrappers.
+
+  The broker is a minimal interface (Broker: Receive / Ack / Nack), so that any message queue can be plugged
+  in. MemoryBroker is an in-memory implementation for tests. Brokers count the deliveries of a message
+  (Message.Deliveries, e.g. from a redelivery header), so that poison messages are dead-lettered.
+
+  Messages carry an Envelope (EncodeEnvelope / DecodeEnvelope): the name of the command, the proto encoded
+  command, the proto encoded actor and the target identifier (parsed with Parse<Target>, as generated by
+  protoc-gen-ddd).
+
+  Messages are settled by the kind of the error, which requires the errors generated by 'ddd-gen app errors':
+
+    (no error)                                   -> Ack
+    StorageLoading, StorageSaving                -> Nack, requeue (no requeue once delivered maxDeliveries times)
+    Authorization, TargetIdentification, Domain  -> Nack, no requeue (e.g. dead-letter)
+    (malformed envelopes, unknown commands)      -> Nack, no requeue
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    domain:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain"
+    commands:                     "github.com/xoe-labs/ddd-gen/internal/test-svc/app/command.Commands"
+    actor:                        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/authorizable.Actor"
+    target:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/app/distinguishable.Target"
+
+    # Error Contructors
+    authorizationErrorNew:        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewAuthorizationError"
+
+  Expected / Recomended Folder Structure:
+    ./port
+    ├── consumer
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── consumer_gen.go         // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml ports consumer --type Consumer
+
+  Code:
+    b := consumer.NewMemoryBroker()
+    c := consumer.NewConsumer(b, &cmds, 5, func(m consumer.Message, err error) {
+      log.Printf("message %s: %v", m.Id, err)
+    })
+    go c.Run(ctx)
+
+    env, err := consumer.EncodeEnvelope(&domain.BlockAccount{}, actor, target)
+    if err != nil {
+      ...
+    }
+    b.Publish(env)
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_ports.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("domain"),
+			viper.GetString("commands"),
+			viper.GetString("actor"),
+			viper.GetString("target"),
+			viper.GetString("authorizationErrorNew"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_ports.GenConsumer(sourceType, cfg)
+	},
+}
+
+func init() {
+	portsCmd.AddCommand(portsConsumerCmd)
+}
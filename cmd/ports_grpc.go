@@ -0,0 +1,113 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports"
+)
+
+var (
+	grpcProtoPackage  string
+	grpcProtoPath     string
+	grpcCommandsProto string
+)
+
+// portsGrpcCmd represents the ports grpc command
+var portsGrpcCmd = &cobra.Command{
+	Use:   "grpc",
+	Short: "Generates a gRPC port for the application commands",
+	Long: `Generates a gRPC service definition with one rpc per entry of the commands struct, and a server which
+  hands each request to its command handler wrapper.
+
+  Each request carries the target identifier (parsed with Parse<Target>, as generated by protoc-gen-ddd) and
+  the domain command. The actor is extracted by an ActorExtractor: MetadataActorExtractor reads it, binary
+  encoded, from the 'actor-bin' metadata. Authenticate the actor upstream, e.g. in an interceptor.
+
+  The sentinel errors of the command handler wrappers are mapped to status codes by their kind, which requires
+  the errors generated by 'ddd-gen app errors':
+
+    Authorization        -> PermissionDenied
+    TargetIdentification -> InvalidArgument
+    StorageLoading       -> Unavailable
+    StorageSaving        -> Aborted
+    Domain               -> FailedPrecondition
+    (others)             -> Internal
+
+  The generated grpc_gen.proto must be compiled with protoc-gen-go and protoc-gen-go-grpc.
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    domain:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain"
+    commands:                     "github.com/xoe-labs/ddd-gen/internal/test-svc/app/command.Commands"
+    actor:                        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/authorizable.Actor"
+    target:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/app/distinguishable.Target"
+
+    # Error Contructors
+    authorizationErrorNew:        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewAuthorizationError"
+
+  Expected / Recomended Folder Structure:
+    ./port
+    ├── grpc
+    │   ├── doc.go                  // place the go:generate directives here
+    │   ├── grpc_gen.proto          // generated by this command
+    │   ├── grpc_gen.pb.go          // generated by protoc-gen-go
+    │   ├── grpc_gen_grpc.pb.go     // generated by protoc-gen-go-grpc
+    │   └── grpc_gen.go             // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml ports grpc --type Server --proto-path ../.. --commands-proto domain/commands.proto
+    //go:generate protoc -I ../.. -I . --go_out=paths=source_relative:. --go-grpc_out=paths=source_relative:. grpc_gen.proto
+
+  Code:
+    s := grpc.NewServer()
+    grpcport.RegisterCommandsServer(s, grpcport.NewServer(&cmds, grpcport.MetadataActorExtractor{}))
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_ports.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("domain"),
+			viper.GetString("commands"),
+			viper.GetString("actor"),
+			viper.GetString("target"),
+			viper.GetString("authorizationErrorNew"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_ports.GenGRPC(sourceType, grpcProtoPackage, grpcProtoPath, grpcCommandsProto, cfg)
+	},
+}
+
+func init() {
+	portsCmd.AddCommand(portsGrpcCmd)
+	portsGrpcCmd.Flags().StringVar(&grpcProtoPackage, "proto-package", "", "Proto package of the service definition (default: go package)")
+	portsGrpcCmd.Flags().StringVar(&grpcProtoPath, "proto-path", ".", "Proto import path, relative to the working directory")
+	portsGrpcCmd.Flags().StringVar(&grpcCommandsProto, "commands-proto", "", "Proto file of the domain commands, relative to --proto-path")
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports"
+)
+
+// portsHttpCmd represents the ports http command
+var portsHttpCmd = &cobra.Command{
+	Use:   "http",
+	Short: "Generates a HTTP/JSON port for the application commands",
+	Long: `Generates a net/http handler with one route per entry of the commands struct, grouped under the command
+  topic (e.g. POST /account/block-account), which hands each request to its command handler wrapper.
+
+  The request body is the protojson encoded domain command. Actor and target are built by pluggable
+  extractors (ActorExtractor / TargetExtractor): HeaderActorExtractor reads the protojson encoded actor
+  from the 'X-Actor' header (authenticate it upstream) and QueryTargetExtractor parses the 'target' query
+  parameter with Parse<Target>, as generated by protoc-gen-ddd.
+
+  Successful commands respond '204 No Content'. Errors respond a json encoded ErrorBody, with a status by
+  the kind of the error, which requires the errors generated by 'ddd-gen app errors':
+
+    Authorization        -> 403 Forbidden
+    TargetIdentification -> 400 Bad Request
+    StorageLoading       -> 503 Service Unavailable
+    StorageSaving        -> 409 Conflict
+    Domain               -> 422 Unprocessable Entity
+    (others)             -> 500 Internal Server Error
+
+  The handler is a plain http.Handler, so that it can be exercised with net/http/httptest.
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    domain:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain"
+    commands:                     "github.com/xoe-labs/ddd-gen/internal/test-svc/app/command.Commands"
+    actor:                        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/authorizable.Actor"
+    target:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/app/distinguishable.Target"
+
+    # Error Contructors
+    authorizationErrorNew:        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewAuthorizationError"
+
+  Expected / Recomended Folder Structure:
+    ./port
+    ├── http
+    │   ├── doc.go                  // place the go:generate directive here
+    │   └── http_gen.go             // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml ports http --type Handler
+
+  Code:
+    h := httpport.NewHandler(&cmds, httpport.HeaderActorExtractor{}, httpport.QueryTargetExtractor{})
+    log.Fatal(http.ListenAndServe(":8080", h))
+
+    // in tests
+    srv := httptest.NewServer(h)
+    defer srv.Close()
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_ports.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("domain"),
+			viper.GetString("commands"),
+			viper.GetString("actor"),
+			viper.GetString("target"),
+			viper.GetString("authorizationErrorNew"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_ports.GenHTTP(sourceType, cfg)
+	},
+}
+
+func init() {
+	portsCmd.AddCommand(portsHttpCmd)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2020 David Arnold <dar@xoe.solutions>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports"
+)
+
+var openAPIVersion string
+
+// portsOpenapiCmd represents the ports openapi command
+var portsOpenapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Generates an OpenAPI 3 document of the HTTP port",
+	Long: `Generates an OpenAPI 3 document (openapi_gen.yaml) of the HTTP port generated by 'ddd-gen ports http', so that
+  clients can be generated from it.
+
+  Operations are derived from the commands struct: one path per command, tagged with the command topic.
+  Request schemas are derived from the proto messages of the domain commands (as embedded by protoc-gen-go
+  into the domain package), proto comments are carried into descriptions. Responses document the error
+  taxonomy of the five error constructors: the sentinel errors of each command, grouped by http status.
+
+  Available Variants (must match the ones of 'ddd-gen app command'):
+    --tenancy                 - documents the Err<Command>CrossTenant authorization errors
+    --scheduling              - documents the Err<Command>SchedulingFailed storage saving errors
+
+  The document is deterministic across runs: paths follow the order of the commands struct and components
+  are sorted by name.
+
+  Config File:
+
+    # ./ddd-config.yaml
+
+    # Application Interfaces
+    app:                          "github.com/xoe-labs/ddd-gen/internal/test-svc/app"
+
+    # Objects
+    domain:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/domain"
+    commands:                     "github.com/xoe-labs/ddd-gen/internal/test-svc/app/command.Commands"
+    actor:                        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/authorizable.Actor"
+    target:                       "github.com/xoe-labs/ddd-gen/internal/test-svc/app/distinguishable.Target"
+
+    # Error Contructors
+    authorizationErrorNew:        "github.com/xoe-labs/ddd-gen/internal/test-svc/app/errors.NewAuthorizationError"
+
+  Expected / Recomended Folder Structure:
+    ./port
+    ├── http
+    │   ├── doc.go                  // place the go:generate directives here
+    │   ├── http_gen.go             // generated by 'ddd-gen ports http'
+    │   └── openapi_gen.yaml        // generated by this command
+    └── ...`,
+	Example: `  Command:
+    //go:generate go run github.com/xoe-labs/ddd-gen --config ../../ddd-config.yaml ports openapi --type Accounts --api-version 1.0.0
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := gen_ports.NewConfig(
+			viper.GetString("app"),
+			viper.GetString("domain"),
+			viper.GetString("commands"),
+			viper.GetString("actor"),
+			viper.GetString("target"),
+			viper.GetString("authorizationErrorNew"),
+		)
+		if err != nil {
+			return err
+		}
+		return gen_ports.GenOpenAPI(sourceType, openAPIVersion, useTenancy, useScheduling, cfg)
+	},
+}
+
+func init() {
+	portsCmd.AddCommand(portsOpenapiCmd)
+	portsOpenapiCmd.Flags().StringVar(&openAPIVersion, "api-version", "0.1.0", "Version of the API")
+	portsOpenapiCmd.Flags().BoolVar(&useTenancy, "tenancy", false, "Multi-tenancy variant of the application layer")
+	portsOpenapiCmd.Flags().BoolVar(&useScheduling, "scheduling", false, "Scheduling variant of the application layer")
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// genActor generates the authorizable implementation of msg
+//
+// The application layer speaks of an actor's GetUser() and GetElevationToken(),
+// which protoc-gen-go already generates for the user and elevation_token fields.
+func genActor(f *jen.File, msg *protogen.Message, appPkg string) {
+	typ := msg.GoIdent.GoName
+
+	if appPkg != "" {
+		f.Comment("compile time assertions")
+		f.Var().Defs(
+			jen.Id("_").Qual(appPkg, authorizable).Op("=").Parens(jen.Op("*").Id(typ)).Call(jen.Nil()),
+		)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v2"
+
+	"github.com/xoe-labs/ddd-gen/cmd/protoc-gen-ddd/jsonschema"
+)
+
+const asyncAPIVersion = "2.0.0"
+
+// comment returns the leading comment of a proto element, without the
+// space protoc keeps after the comment markers
+func comment(c protogen.Comments) string {
+	lines := strings.Split(strings.TrimSpace(string(c)), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// enumSchema adds the schema of enum to schemas
+func enumSchema(enum *protogen.Enum, schemas map[string]jsonschema.Obj) {
+	name := string(enum.Desc.FullName())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	var values []string
+	for _, v := range enum.Values {
+		values = append(values, string(v.Desc.Name()))
+	}
+	schemas[name] = jsonschema.Described(comment(enum.Comments.Leading), jsonschema.Obj{{Key: "type", Value: "string"}, {Key: "enum", Value: values}})
+}
+
+// messageSchema adds the schema of msg, and of all types it refers to, to schemas
+func messageSchema(msg *protogen.Message, schemas map[string]jsonschema.Obj) {
+	name := string(msg.Desc.FullName())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	schemas[name] = nil // break cycles
+	var props jsonschema.Obj
+	for _, fld := range msg.Fields {
+		props = append(props, jsonschema.KV{Key: fld.Desc.JSONName(), Value: jsonschema.Described(comment(fld.Comments.Leading), fieldSchema(fld, schemas))})
+	}
+	s := jsonschema.Obj{{Key: "type", Value: "object"}}
+	if len(props) > 0 {
+		s = append(s, jsonschema.KV{Key: "properties", Value: props})
+	}
+	schemas[name] = jsonschema.Described(comment(msg.Comments.Leading), s)
+}
+
+// fieldSchema returns the schema of a field, messages and enums are referenced
+func fieldSchema(fld *protogen.Field, schemas map[string]jsonschema.Obj) jsonschema.Obj {
+	if fld.Desc.IsMap() {
+		// map keys are always encoded as json object keys
+		return jsonschema.Obj{
+			{Key: "type", Value: "object"},
+			{Key: "additionalProperties", Value: fieldSchema(fld.Message.Fields[1], schemas)},
+		}
+	}
+	var s jsonschema.Obj
+	switch fld.Desc.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if wk, ok := jsonschema.WellKnown(fld.Message.Desc.FullName()); ok {
+			s = wk
+		} else {
+			messageSchema(fld.Message, schemas)
+			s = jsonschema.Ref(string(fld.Message.Desc.FullName()))
+		}
+	case protoreflect.EnumKind:
+		enumSchema(fld.Enum, schemas)
+		s = jsonschema.Ref(string(fld.Enum.Desc.FullName()))
+	default:
+		s = jsonschema.Scalar(fld.Desc.Kind())
+	}
+	if fld.Desc.IsList() {
+		return jsonschema.Obj{{Key: "type", Value: "array"}, {Key: "items", Value: s}}
+	}
+	return s
+}
+
+// factMessage returns the AsyncAPI message of a fact of entity
+func factMessage(msg *protogen.Message, entity string) jsonschema.Obj {
+	m := jsonschema.Obj{
+		{Key: "name", Value: string(msg.Desc.FullName())},
+		{Key: "title", Value: msg.GoIdent.GoName},
+	}
+	if doc := comment(msg.Comments.Leading); doc != "" {
+		lines := strings.SplitN(doc, "\n", 2)
+		m = append(m, jsonschema.KV{Key: "summary", Value: lines[0]})
+		if len(lines) > 1 {
+			m = append(m, jsonschema.KV{Key: "description", Value: doc})
+		}
+	}
+	return append(m,
+		jsonschema.KV{Key: "tags", Value: []jsonschema.Obj{{{Key: "name", Value: entity}}}},
+		jsonschema.KV{Key: "payload", Value: jsonschema.Ref(string(msg.Desc.FullName()))},
+		jsonschema.KV{Key: "x-entity", Value: entity},
+		jsonschema.KV{Key: "x-version", Value: factVersion(msg)},
+	)
+}
+
+// genAsyncAPI generates the AsyncAPI document which catalogues the facts of a file
+// the output is deterministic: channels are sorted by topic, the facts of a
+// topic follow the file, components are sorted by name
+func genAsyncAPI(file *protogen.File, entity, title, version string) ([]byte, error) {
+	schemas := make(map[string]jsonschema.Obj)
+	topics := make(map[string][]string)
+	var messages jsonschema.Obj
+	for _, msg := range file.Messages {
+		name := string(msg.Desc.FullName())
+		topic := factTopic(msg, entity)
+		topics[topic] = append(topics[topic], name)
+		messageSchema(msg, schemas)
+		messages = append(messages, jsonschema.KV{Key: name, Value: factMessage(msg, entity)})
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("%s: no facts", file.Desc.Path())
+	}
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Key.(string) < messages[j].Key.(string) })
+
+	var names []string
+	for topic := range topics {
+		names = append(names, topic)
+	}
+	sort.Strings(names)
+	var channels jsonschema.Obj
+	for _, topic := range names {
+		var refs []jsonschema.Obj
+		for _, name := range topics[topic] {
+			refs = append(refs, jsonschema.Obj{{Key: "$ref", Value: "#/components/messages/" + name}})
+		}
+		var message jsonschema.Obj
+		if len(refs) == 1 {
+			message = refs[0]
+		} else {
+			message = jsonschema.Obj{{Key: "oneOf", Value: refs}}
+		}
+		channels = append(channels, jsonschema.KV{Key: topic, Value: jsonschema.Obj{
+			{Key: "description", Value: fmt.Sprintf("facts of %s", entity)},
+			{Key: "subscribe", Value: jsonschema.Obj{{Key: "message", Value: message}}},
+		}})
+	}
+
+	names = names[:0]
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var ss jsonschema.Obj
+	for _, name := range names {
+		ss = append(ss, jsonschema.KV{Key: name, Value: schemas[name]})
+	}
+
+	doc := jsonschema.Obj{
+		{Key: "asyncapi", Value: asyncAPIVersion},
+		{Key: "info", Value: jsonschema.Obj{{Key: "title", Value: title}, {Key: "version", Value: version}}},
+		{Key: "defaultContentType", Value: "application/json"},
+		{Key: "channels", Value: channels},
+		{Key: "components", Value: jsonschema.Obj{
+			{Key: "messages", Value: messages},
+			{Key: "schemas", Value: ss},
+		}},
+	}
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "# Code generated by protoc-gen-ddd. DO NOT EDIT.")
+	fmt.Fprintf(&buf, "# source: %s\n", file.Desc.Path())
+	buf.Write(b)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// genEntityModel generates a domain model for entity from the facts of a file
+func genEntityModel(f *jen.File, genFile *protogen.GeneratedFile, file *protogen.File, entity string) {
+	var visited = make(map[string]bool)
+
+	f.Commentf("%s is a domain model", entity)
+	f.Type().Id(entity).StructFunc(func(g *jen.Group) {
+		for _, msg := range file.Messages {
+			for _, fld := range msg.Fields {
+				if _, ok := visited[fld.GoName]; !ok {
+					goType, pointer := fieldGoType(genFile, fld)
+					if pointer {
+						g.Id(lowerFirst(fld.GoName)).Op("*").Id(goType)
+					} else {
+						g.Id(lowerFirst(fld.GoName)).Id(goType)
+					}
+					visited[fld.GoName] = true
+				}
+			}
+		}
+	})
+
+	var New = "New"
+
+	f.Commentf("%s%s constructs an empty %s", New, entity, entity)
+	f.Func().Id(New + entity).Params().Op("*").Id(entity).Block(
+		jen.Return().Op("&").Id(entity).Values(),
+	)
+
+	// var String = "String"
+
+	// f.Commentf("%s implements fmt.Stringer for %s", String, Entity)
+	// f.Func().Id(String).Params().String().Block(
+	// 	jen.Return().Lit(""),
+	// )
+
+	var Apply = "Apply"
+
+	f.Commentf("%s implements app.??? for %s", Apply, entity)
+	f.Func().Params(
+		jen.Id(firstLower(entity)).Op("*").Id(entity),
+	).Id(Apply).Params(
+		jen.Id("fact").Interface(),
+	).Params(
+		jen.Id("success").Bool(),
+	).Block(
+		jen.Switch(
+			jen.Id("f").Op(":=").Id("fact").Assert(jen.Type()),
+		).BlockFunc(func(g *jen.Group) {
+			for _, msg := range file.Messages {
+				g.Case(
+					jen.Op("*").Id(msg.GoIdent.GoName),
+				).BlockFunc(func(g *jen.Group) {
+					for _, fld := range msg.Fields {
+						g.Id(
+							firstLower(entity),
+						).Dot(
+							lowerFirst(fld.GoName),
+						).Op("=").Id("f").Dot(
+							fld.GoName,
+						)
+					}
+					g.Return().True()
+				})
+			}
+			g.Default().Block(
+				jen.Return().False(),
+			)
+		}),
+	)
+}
@@ -4,5 +4,7 @@ go 1.15
 
 require (
 	github.com/dave/jennifer v1.4.1
+	github.com/golang/protobuf v1.4.1
 	google.golang.org/protobuf v1.25.0
+	gopkg.in/yaml.v2 v2.2.8
 )
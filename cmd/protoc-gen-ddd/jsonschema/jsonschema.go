@@ -0,0 +1,86 @@
+// Package jsonschema maps protobuf types to the schemas of their protojson
+// encoding, as shared by the OpenAPI 3.0 and AsyncAPI 2.0 documents
+//
+// it is vendored from github.com/xoe-labs/ddd-gen/pkg/jsonschema, so that the
+// plugin module does not depend on the generator module, keep both in sync
+package jsonschema
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v2"
+)
+
+// KV is a key of a schema, keys are kept in order of insertion
+type KV = yaml.MapItem
+
+// Obj is a schema, or any other object of a document
+type Obj = yaml.MapSlice
+
+// Ref returns the reference to the component schema name
+func Ref(name string) Obj {
+	return Obj{{Key: "$ref", Value: "#/components/schemas/" + name}}
+}
+
+// wellKnown are the schemas of well-known types
+var wellKnown = map[protoreflect.FullName]Obj{
+	"google.protobuf.Timestamp":   {{Key: "type", Value: "string"}, {Key: "format", Value: "date-time"}},
+	"google.protobuf.Duration":    {{Key: "type", Value: "string"}, {Key: "pattern", Value: `^-?[0-9]+(\.[0-9]{1,9})?s$`}},
+	"google.protobuf.FieldMask":   {{Key: "type", Value: "string"}},
+	"google.protobuf.Empty":       {{Key: "type", Value: "object"}},
+	"google.protobuf.Struct":      {{Key: "type", Value: "object"}},
+	"google.protobuf.Any":         {{Key: "type", Value: "object"}},
+	"google.protobuf.Value":       {},
+	"google.protobuf.ListValue":   {{Key: "type", Value: "array"}, {Key: "items", Value: Obj{}}},
+	"google.protobuf.StringValue": Scalar(protoreflect.StringKind),
+	"google.protobuf.BytesValue":  Scalar(protoreflect.BytesKind),
+	"google.protobuf.BoolValue":   Scalar(protoreflect.BoolKind),
+	"google.protobuf.DoubleValue": Scalar(protoreflect.DoubleKind),
+	"google.protobuf.FloatValue":  Scalar(protoreflect.FloatKind),
+	"google.protobuf.Int32Value":  Scalar(protoreflect.Int32Kind),
+	"google.protobuf.UInt32Value": Scalar(protoreflect.Uint32Kind),
+	"google.protobuf.Int64Value":  Scalar(protoreflect.Int64Kind),
+	"google.protobuf.UInt64Value": Scalar(protoreflect.Uint64Kind),
+}
+
+// WellKnown returns the schema of the well-known type name
+func WellKnown(name protoreflect.FullName) (Obj, bool) {
+	s, ok := wellKnown[name]
+	return s, ok
+}
+
+// Scalar returns the schema of a scalar kind (64 bit integers are encoded as strings)
+// enums, messages and groups are not scalars, they map to a plain string
+func Scalar(kind protoreflect.Kind) Obj {
+	switch kind {
+	case protoreflect.BoolKind:
+		return Obj{{Key: "type", Value: "boolean"}}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return Obj{{Key: "type", Value: "integer"}, {Key: "format", Value: "int32"}}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return Obj{{Key: "type", Value: "integer"}, {Key: "format", Value: "int64"}, {Key: "minimum", Value: 0}}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return Obj{{Key: "type", Value: "string"}, {Key: "format", Value: "int64"}}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return Obj{{Key: "type", Value: "string"}, {Key: "format", Value: "uint64"}}
+	case protoreflect.FloatKind:
+		return Obj{{Key: "type", Value: "number"}, {Key: "format", Value: "float"}}
+	case protoreflect.DoubleKind:
+		return Obj{{Key: "type", Value: "number"}, {Key: "format", Value: "double"}}
+	case protoreflect.BytesKind:
+		return Obj{{Key: "type", Value: "string"}, {Key: "format", Value: "byte"}}
+	default:
+		return Obj{{Key: "type", Value: "string"}}
+	}
+}
+
+// Described adds the description doc to s
+// references can't carry siblings in OpenAPI 3.0 nor AsyncAPI 2.0, so they are wrapped
+func Described(doc string, s Obj) Obj {
+	if doc == "" {
+		return s
+	}
+	if len(s) > 0 && s[0].Key == "$ref" {
+		return Obj{{Key: "allOf", Value: []Obj{s}}, {Key: "description", Value: doc}}
+	}
+	return append(append(Obj{}, s...), KV{Key: "description", Value: doc})
+}
@@ -2,10 +2,11 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"io/ioutil"
-	"flag"
 	"os"
+	"strings"
 	"unicode"
 
 	"google.golang.org/protobuf/compiler/protogen"
@@ -71,17 +72,26 @@ func main() {
 	proto.Unmarshal(input, &req)
 
 	var flags flag.FlagSet
-	Entity := flags.String("entity", "", "")
+	Entity := flags.String("entity", "", "generate a domain model from the facts (eg. --ddd_out=entity=Account:.)")
+	Target := flags.String("target", "", "generate the distinguishable implementation of a message (eg. --ddd_out=target=Target:.)")
+	Actor := flags.String("actor", "", "generate the authorizable implementation of a message (eg. --ddd_out=actor=Actor:.)")
+	App := flags.String("app", "", "import path of the application layer to generate compile time assertions against")
+	Sep := flags.String("sep", "-", "separator of the composite target identifier")
+	AsyncAPI := flags.String("asyncapi", "", "generate an AsyncAPI catalog of the facts of an entity with this title (eg. --ddd_out=entity=Account,asyncapi=Accounts:.)")
+	AsyncAPIVersion := flags.String("asyncapi_version", "0.1.0", "version of the AsyncAPI catalog")
 	opts := &protogen.Options{
-	  ParamFunc: flags.Set,
-	}
-	if Entity == nil {
-		panic("necessary to define 'entity' option (eg. --ddd_out=entity=Account:.)")
+		ParamFunc: flags.Set,
 	}
 	plugin, err := opts.New(&req)
 	if err != nil {
 		panic(err)
 	}
+	if *Entity == "" && *Target == "" && *Actor == "" {
+		panic("necessary to define one of 'entity', 'target' or 'actor' options (eg. --ddd_out=entity=Account:.)")
+	}
+	if *AsyncAPI != "" && *Entity == "" {
+		panic("necessary to define the 'entity' of the facts to catalog (eg. --ddd_out=entity=Account,asyncapi=Accounts:.)")
+	}
 
 	// Protoc passes a slice of File structs for us to process
 	for _, file := range plugin.Files {
@@ -89,93 +99,56 @@ func main() {
 			continue
 		}
 
-		// Specify the output filename
-		filename := file.GeneratedFilenamePrefix + ".model.go"
-		genFile := plugin.NewGeneratedFile(filename, file.GoImportPath)
-
-		// 1. Initialise a buffer to hold the generated code
-		buf := &bytes.Buffer{}
-
-		// 2. Write the package name
-		f := jen.NewFilePathName(string(file.GoImportPath), string(file.GoPackageName))
-
-		var visited = make(map[string]bool)
-
-		f.Commentf("%s is a domain model", *Entity)
-		f.Type().Id(*Entity).StructFunc(func(g *jen.Group) {
-			for _, msg := range file.Messages {
-				for _, fld := range msg.Fields {
-					if _, ok := visited[fld.GoName]; !ok {
-						goType, pointer := fieldGoType(genFile, fld)
-						if pointer {
-							g.Id(lowerFirst(fld.GoName)).Op("*").Id(goType)
-						} else {
-							g.Id(lowerFirst(fld.GoName)).Id(goType)
-						}
-						visited[fld.GoName] = true
+		if *Entity != "" {
+			// Specify the output filename
+			filename := file.GeneratedFilenamePrefix + ".model.go"
+			genFile := plugin.NewGeneratedFile(filename, file.GoImportPath)
+
+			// Write the package name
+			f := jen.NewFilePathName(string(file.GoImportPath), string(file.GoPackageName))
+			genEntityModel(f, genFile, file, *Entity)
+			render(f, genFile)
+
+			// fact registry with schema versions and upcasting
+			filename = file.GeneratedFilenamePrefix + ".registry.go"
+			genFile = plugin.NewGeneratedFile(filename, file.GoImportPath)
+			f = newGeneratedFile(file)
+			genFactRegistry(f, file, *Entity)
+			render(f, genFile)
+
+			// catalog of the facts for their consumers
+			if *AsyncAPI != "" {
+				// a file without facts fails the run, but doesn't hold up the other outputs
+				if b, err := genAsyncAPI(file, *Entity, *AsyncAPI, *AsyncAPIVersion); err != nil {
+					plugin.Error(err)
+				} else {
+					filename = file.GeneratedFilenamePrefix + ".asyncapi.yaml"
+					genFile = plugin.NewGeneratedFile(filename, file.GoImportPath)
+					if _, err := genFile.Write(b); err != nil {
+						panic(err)
 					}
 				}
 			}
-		})
-
-		var New = "New"
-
-		f.Commentf("%s%s constructs an empty %s", New, *Entity, *Entity)
-		f.Func().Id(New + *Entity).Params().Op("*").Id(*Entity).Block(
-			jen.Return().Op("&").Id(*Entity).Values(),
-		)
-
-		// var String = "String"
-
-		// f.Commentf("%s implements fmt.Stringer for %s", String, Entity)
-		// f.Func().Id(String).Params().String().Block(
-		// 	jen.Return().Lit(""),
-		// )
-
-		var Apply = "Apply"
-
-		f.Commentf("%s implements app.??? for %s", Apply, *Entity)
-		f.Func().Params(
-			jen.Id(firstLower(*Entity)).Op("*").Id(*Entity),
-		).Id(Apply).Params(
-			jen.Id("fact").Interface(),
-		).Params(
-			jen.Id("success").Bool(),
-		).Block(
-			jen.Switch(
-				jen.Id("f").Op(":=").Id("fact").Assert(jen.Type()),
-			).BlockFunc(func(g *jen.Group) {
-				for _, msg := range file.Messages {
-					g.Case(
-						jen.Op("*").Id(msg.GoIdent.GoName),
-					).BlockFunc(func(g *jen.Group) {
-						for _, fld := range msg.Fields {
-							g.Id(
-								firstLower(*Entity),
-							).Dot(
-								lowerFirst(fld.GoName),
-							).Op("=").Id("f").Dot(
-								fld.GoName,
-							)
-						}
-						g.Return().True()
-					})
-				}
-				g.Default().Block(
-					jen.Return().False(),
-				)
-			}),
-		)
-
-		err := f.Render(buf)
-		if err != nil {
-			panic(err)
 		}
 
-		// 5. Pass the data from our buffer to the plugin file struct
-		_, err = genFile.Write(buf.Bytes())
-		if err != nil {
-			panic(err)
+		for _, msg := range file.Messages {
+			switch msg.GoIdent.GoName {
+			case *Target:
+				f := newGeneratedFile(file)
+				if err := genTarget(f, msg, *Sep, *App); err != nil {
+					plugin.Error(err)
+					break
+				}
+				filename := file.GeneratedFilenamePrefix + ".target.go"
+				genFile := plugin.NewGeneratedFile(filename, file.GoImportPath)
+				render(f, genFile)
+			case *Actor:
+				filename := file.GeneratedFilenamePrefix + ".actor.go"
+				genFile := plugin.NewGeneratedFile(filename, file.GoImportPath)
+				f := newGeneratedFile(file)
+				genActor(f, msg, *App)
+				render(f, genFile)
+			}
 		}
 	}
 
@@ -187,7 +160,32 @@ func main() {
 	}
 
 	// Write the response to stdout, to be picked up by protoc
-	fmt.Fprintf(os.Stdout, string(out))
+	os.Stdout.Write(out)
+}
+
+// newGeneratedFile returns a jennifer file for the package of file
+// flagged as generated code
+func newGeneratedFile(file *protogen.File) *jen.File {
+	f := jen.NewFilePathName(string(file.GoImportPath), string(file.GoPackageName))
+	f.HeaderComment("Code generated by protoc-gen-ddd. DO NOT EDIT.")
+	f.HeaderComment(fmt.Sprintf("source: %s", file.Desc.Path()))
+	return f
+}
+
+// render passes the code of f to the plugin file genFile
+func render(f *jen.File, genFile *protogen.GeneratedFile) {
+	// Initialise a buffer to hold the generated code
+	buf := &bytes.Buffer{}
+	err := f.Render(buf)
+	if err != nil {
+		panic(err)
+	}
+
+	// Pass the data from our buffer to the plugin file struct
+	_, err = genFile.Write(buf.Bytes())
+	if err != nil {
+		panic(err)
+	}
 }
 
 func lowerFirst(str string) string {
@@ -197,6 +195,21 @@ func lowerFirst(str string) string {
 	return ""
 }
 
+// kebab converts a CamelCase identifier into kebab-case
+func kebab(str string) string {
+	var b strings.Builder
+	for i, v := range str {
+		if unicode.IsUpper(v) {
+			if i > 0 {
+				b.WriteRune('-')
+			}
+			v = unicode.ToLower(v)
+		}
+		b.WriteRune(v)
+	}
+	return b.String()
+}
+
 func firstLower(str string) string {
 	return "r"
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Field numbers of the extensions declared in ddd/options.proto
+//
+// The plugin does not link the generated extension types, protoc passes
+// them as unknown fields of the descriptor options instead.
+const (
+	requiredFieldOption protowire.Number = 52001
+	keyFieldOption      protowire.Number = 52002
+
+	versionMessageOption protowire.Number = 52101
+	topicMessageOption   protowire.Number = 52102
+)
+
+// fieldOptionVarint returns the value of a varint encoded field option
+func fieldOptionVarint(field *protogen.Field, num protowire.Number) (v uint64, ok bool) {
+	opts, isFieldOpts := field.Desc.Options().(*descriptorpb.FieldOptions)
+	if !isFieldOpts || opts == nil {
+		return 0, false
+	}
+	return optionVarint(opts.ProtoReflect().GetUnknown(), num)
+}
+
+// messageOptionVarint returns the value of a varint encoded message option
+func messageOptionVarint(msg *protogen.Message, num protowire.Number) (v uint64, ok bool) {
+	opts, isMsgOpts := msg.Desc.Options().(*descriptorpb.MessageOptions)
+	if !isMsgOpts || opts == nil {
+		return 0, false
+	}
+	return optionVarint(opts.ProtoReflect().GetUnknown(), num)
+}
+
+// messageOptionString returns the value of a string option
+func messageOptionString(msg *protogen.Message, num protowire.Number) (v string, ok bool) {
+	opts, isMsgOpts := msg.Desc.Options().(*descriptorpb.MessageOptions)
+	if !isMsgOpts || opts == nil {
+		return "", false
+	}
+	return optionString(opts.ProtoReflect().GetUnknown(), num)
+}
+
+// optionVarint returns the value of a varint encoded option from the
+// unknown fields b of an options message
+func optionVarint(b []byte, num protowire.Number) (v uint64, ok bool) {
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return 0, false
+		}
+		b = b[l:]
+		if n == num && typ == protowire.VarintType {
+			// the last occurence wins, as for any other proto field
+			val, l := protowire.ConsumeVarint(b)
+			if l < 0 {
+				return 0, false
+			}
+			v, ok = val, true
+			b = b[l:]
+			continue
+		}
+		l = protowire.ConsumeFieldValue(n, typ, b)
+		if l < 0 {
+			return 0, false
+		}
+		b = b[l:]
+	}
+	return v, ok
+}
+
+// optionString returns the value of a length delimited string option from the
+// unknown fields b of an options message
+func optionString(b []byte, num protowire.Number) (v string, ok bool) {
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return "", false
+		}
+		b = b[l:]
+		if n == num && typ == protowire.BytesType {
+			// the last occurence wins, as for any other proto field
+			val, l := protowire.ConsumeBytes(b)
+			if l < 0 {
+				return "", false
+			}
+			v, ok = string(val), true
+			b = b[l:]
+			continue
+		}
+		l = protowire.ConsumeFieldValue(n, typ, b)
+		if l < 0 {
+			return "", false
+		}
+		b = b[l:]
+	}
+	return v, ok
+}
+
+// isRequiredField answers whether a field is marked with (ddd.required)
+func isRequiredField(field *protogen.Field) bool {
+	v, ok := fieldOptionVarint(field, requiredFieldOption)
+	return ok && v != 0
+}
+
+// fieldKey returns the position of a field within a composite key
+func fieldKey(field *protogen.Field) (pos uint64, ok bool) {
+	return fieldOptionVarint(field, keyFieldOption)
+}
+
+// factVersion returns the schema version of a fact, marked with (ddd.version)
+// facts without version are at their first version
+func factVersion(msg *protogen.Message) uint32 {
+	if v, ok := messageOptionVarint(msg, versionMessageOption); ok && v > 0 {
+		return uint32(v)
+	}
+	return 1
+}
+
+// factTopic returns the topic a fact is published on, marked with (ddd.topic)
+// facts without topic are published on the topic of their entity
+func factTopic(msg *protogen.Message, entity string) string {
+	if v, ok := messageOptionString(msg, topicMessageOption); ok && v != "" {
+		return v
+	}
+	return kebab(entity)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const protoPkg = "google.golang.org/protobuf/proto"
+
+// genFactRegistry generates the registry of the facts of a file with their
+// schema versions and the upcasting of stored facts to those versions
+func genFactRegistry(f *jen.File, file *protogen.File, entity string) {
+	schema := entity + "FactSchema"
+	registry := entity + "FactRegistry"
+	upcaster := entity + "Upcaster"
+	chain := entity + "UpcasterChain"
+	decode := "Decode" + entity + "Fact"
+	encode := "Encode" + entity + "Fact"
+
+	f.Commentf("%s describes the current schema of a fact of %s", schema, entity)
+	f.Type().Id(schema).Struct(
+		jen.Id("Name").String().Comment("full proto name of the fact"),
+		jen.Id("Version").Uint32().Comment("current schema version, see (ddd.version)"),
+		jen.Id("New").Func().Params().Qual(protoPkg, "Message").Comment("returns an empty fact of the current schema"),
+	)
+
+	f.Commentf("%s registers the facts of %s by their full proto name", registry, entity)
+	f.Var().Id(registry).Op("=").Map(jen.String()).Id(schema).Values(jen.DictFunc(func(d jen.Dict) {
+		for _, msg := range file.Messages {
+			name := string(msg.Desc.FullName())
+			d[jen.Lit(name)] = jen.Values(jen.Dict{
+				jen.Id("Name"):    jen.Lit(name),
+				jen.Id("Version"): jen.Lit(int(factVersion(msg))),
+				jen.Id("New"): jen.Func().Params().Qual(protoPkg, "Message").Block(
+					jen.Return(jen.Op("&").Id(msg.GoIdent.GoName).Values()),
+				),
+			})
+		}
+	}))
+
+	f.Commentf("%s knows how to upcast stored facts of %s to their next schema version", upcaster, entity)
+	f.Type().Id(upcaster).Interface(
+		jen.Comment("Upcast knows how to convert the payload of fact name from schema version from to version from+1"),
+		jen.Id("Upcast").Params(
+			jen.Id("name").String(),
+			jen.Id("from").Uint32(),
+			jen.Id("payload").Index().Byte(),
+		).Params(
+			jen.Index().Byte(),
+			jen.Error(),
+		),
+	)
+
+	f.Commentf("%s is an %s composed of single upcast steps by fact name and version", chain, upcaster)
+	f.Type().Id(chain).Map(jen.String()).Map(jen.Uint32()).Func().Params(
+		jen.Id("payload").Index().Byte(),
+	).Params(
+		jen.Index().Byte(),
+		jen.Error(),
+	)
+
+	f.Commentf("Upcast implements %s", upcaster)
+	f.Func().Params(
+		jen.Id("c").Id(chain),
+	).Id("Upcast").Params(
+		jen.Id("name").String(),
+		jen.Id("from").Uint32(),
+		jen.Id("payload").Index().Byte(),
+	).Params(
+		jen.Index().Byte(),
+		jen.Error(),
+	).Block(
+		jen.List(jen.Id("step"), jen.Id("ok")).Op(":=").Id("c").Index(jen.Id("name")).Index(jen.Id("from")),
+		jen.If(jen.Op("!").Id("ok")).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(
+				jen.Lit("no upcaster for %s from version %d"),
+				jen.Id("name"),
+				jen.Id("from"),
+			)),
+		),
+		jen.Return(jen.Id("step").Call(jen.Id("payload"))),
+	)
+
+	f.Commentf("%s decodes a stored fact of %s, upcasting its payload step by step to the current schema", decode, entity)
+	f.Comment("up may be nil if no fact is stored in an older version")
+	f.Func().Id(decode).Params(
+		jen.Id("name").String(),
+		jen.Id("version").Uint32(),
+		jen.Id("payload").Index().Byte(),
+		jen.Id("up").Id(upcaster),
+	).Params(
+		jen.Qual(protoPkg, "Message"),
+		jen.Error(),
+	).Block(
+		jen.List(jen.Id("s"), jen.Id("ok")).Op(":=").Id(registry).Index(jen.Id("name")),
+		jen.If(jen.Op("!").Id("ok")).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(
+				jen.Lit("unknown fact %s"),
+				jen.Id("name"),
+			)),
+		),
+		jen.If(jen.Id("version").Op(">").Id("s").Dot("Version")).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(
+				jen.Lit("fact %s has version %d, newer than the known version %d"),
+				jen.Id("name"),
+				jen.Id("version"),
+				jen.Id("s").Dot("Version"),
+			)),
+		),
+		jen.For(jen.Id("v").Op(":=").Id("version"), jen.Id("v").Op("<").Id("s").Dot("Version"), jen.Id("v").Op("++")).Block(
+			jen.If(jen.Id("up").Op("==").Nil()).Block(
+				jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(
+					jen.Lit("no upcaster for %s from version %d"),
+					jen.Id("name"),
+					jen.Id("v"),
+				)),
+			),
+			jen.Var().Id("err").Error(),
+			jen.If(
+				jen.List(jen.Id("payload"), jen.Id("err")).Op("=").Id("up").Dot("Upcast").Call(jen.Id("name"), jen.Id("v"), jen.Id("payload")),
+				jen.Id("err").Op("!=").Nil(),
+			).Block(
+				jen.Return(jen.Nil(), jen.Id("err")),
+			),
+		),
+		jen.Id("fact").Op(":=").Id("s").Dot("New").Call(),
+		jen.If(
+			jen.Id("err").Op(":=").Qual(protoPkg, "Unmarshal").Call(jen.Id("payload"), jen.Id("fact")),
+			jen.Id("err").Op("!=").Nil(),
+		).Block(
+			jen.Return(jen.Nil(), jen.Id("err")),
+		),
+		jen.Return(jen.Id("fact"), jen.Nil()),
+	)
+
+	f.Commentf("%s returns full name, current schema version and payload of a fact of %s for storage", encode, entity)
+	f.Func().Id(encode).Params(
+		jen.Id("fact").Qual(protoPkg, "Message"),
+	).Params(
+		jen.Id("name").String(),
+		jen.Id("version").Uint32(),
+		jen.Id("payload").Index().Byte(),
+		jen.Id("err").Error(),
+	).Block(
+		jen.Id("name").Op("=").String().Call(jen.Id("fact").Dot("ProtoReflect").Call().Dot("Descriptor").Call().Dot("FullName").Call()),
+		jen.List(jen.Id("s"), jen.Id("ok")).Op(":=").Id(registry).Index(jen.Id("name")),
+		jen.If(jen.Op("!").Id("ok")).Block(
+			jen.Return(jen.Lit(""), jen.Lit(0), jen.Nil(), jen.Qual("fmt", "Errorf").Call(
+				jen.Lit("unknown fact %s"),
+				jen.Id("name"),
+			)),
+		),
+		jen.List(jen.Id("payload"), jen.Id("err")).Op("=").Qual(protoPkg, "Marshal").Call(jen.Id("fact")),
+		jen.Return(jen.Id("name"), jen.Id("s").Dot("Version"), jen.Id("payload"), jen.Id("err")),
+	)
+
+	f.Commentf("ApplyStored upcasts a stored fact to its current schema before it is applied to %s", entity)
+	f.Func().Params(
+		jen.Id(firstLower(entity)).Op("*").Id(entity),
+	).Id("ApplyStored").Params(
+		jen.Id("name").String(),
+		jen.Id("version").Uint32(),
+		jen.Id("payload").Index().Byte(),
+		jen.Id("up").Id(upcaster),
+	).Error().Block(
+		jen.List(jen.Id("fact"), jen.Id("err")).Op(":=").Id(decode).Call(
+			jen.Id("name"),
+			jen.Id("version"),
+			jen.Id("payload"),
+			jen.Id("up"),
+		),
+		jen.If(jen.Id("err").Op("!=").Nil()).Block(
+			jen.Return(jen.Id("err")),
+		),
+		jen.If(jen.Op("!").Id(firstLower(entity)).Dot("Apply").Call(jen.Id("fact"))).Block(
+			jen.Return(jen.Qual("fmt", "Errorf").Call(
+				jen.Lit(entity+" cannot apply fact %s"),
+				jen.Id("name"),
+			)),
+		),
+		jen.Return(jen.Nil()),
+	)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// Interfaces of the application layer which generated objects implement
+const (
+	distinguishable         = "OffersDistinguishable"
+	distinguishableAsserter = "RequiresDistinguishableAsserter"
+	authorizable            = "OffersAuthorizable"
+)
+
+// targetEscape escapes the separator, and itself, within the components of a target identifier
+const targetEscape = `\`
+
+// keyFields returns the fields which compose the identifier of a target
+func keyFields(msg *protogen.Message) (flds []*protogen.Field, err error) {
+	var keyed []*protogen.Field
+	for _, fld := range msg.Fields {
+		if _, ok := fieldKey(fld); ok {
+			keyed = append(keyed, fld)
+		}
+	}
+	if len(keyed) == 0 {
+		keyed = append(keyed, msg.Fields...)
+	} else {
+		sort.SliceStable(keyed, func(i, j int) bool {
+			pi, _ := fieldKey(keyed[i])
+			pj, _ := fieldKey(keyed[j])
+			return pi < pj
+		})
+	}
+	for _, fld := range keyed {
+		if fld.Desc.IsList() || fld.Desc.IsMap() {
+			return nil, fmt.Errorf("%s.%s: repeated fields cannot be part of the identifier", msg.GoIdent.GoName, fld.GoName)
+		}
+		switch fld.Desc.Kind() {
+		case protoreflect.StringKind, protoreflect.BoolKind,
+			protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+			protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+			protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+			protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		default:
+			return nil, fmt.Errorf("%s.%s: %s fields cannot be part of the identifier", msg.GoIdent.GoName, fld.GoName, fld.Desc.Kind())
+		}
+	}
+	return keyed, nil
+}
+
+// formatField returns code which formats a key field as string
+func formatField(recv string, fld *protogen.Field) jen.Code {
+	get := jen.Id(recv).Dot("Get" + fld.GoName).Call()
+	switch fld.Desc.Kind() {
+	case protoreflect.BoolKind:
+		return jen.Qual("strconv", "FormatBool").Call(get)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return jen.Qual("strconv", "FormatInt").Call(jen.Int64().Call(get), jen.Lit(10))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return jen.Qual("strconv", "FormatUint").Call(jen.Uint64().Call(get), jen.Lit(10))
+	default:
+		return get
+	}
+}
+
+// parseField returns code which parses part into a key field
+func parseField(g *jen.Group, recv string, fld *protogen.Field, part jen.Code, errMsg string) {
+	var (
+		parse  jen.Code
+		goType string
+	)
+	switch fld.Desc.Kind() {
+	case protoreflect.BoolKind:
+		parse = jen.Qual("strconv", "ParseBool").Call(part)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		parse, goType = jen.Qual("strconv", "ParseInt").Call(part, jen.Lit(10), jen.Lit(32)), "int32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		parse, goType = jen.Qual("strconv", "ParseInt").Call(part, jen.Lit(10), jen.Lit(64)), "int64"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		parse, goType = jen.Qual("strconv", "ParseUint").Call(part, jen.Lit(10), jen.Lit(32)), "uint32"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		parse, goType = jen.Qual("strconv", "ParseUint").Call(part, jen.Lit(10), jen.Lit(64)), "uint64"
+	default:
+		g.Id(recv).Dot(fld.GoName).Op("=").Add(part)
+		return
+	}
+	value := jen.Id("v")
+	if goType != "" {
+		value = jen.Id(goType).Call(jen.Id("v"))
+	}
+	g.BlockFunc(func(g *jen.Group) {
+		g.List(jen.Id("v"), jen.Id("err")).Op(":=").Add(parse)
+		g.If(jen.Id("err").Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(jen.Lit(errMsg+" %s: %v"), jen.Id("s"), jen.Lit(string(fld.Desc.Name())), jen.Id("err"))),
+		)
+		g.Id(recv).Dot(fld.GoName).Op("=").Add(value)
+	})
+}
+
+// genTarget generates the distinguishable implementation of msg
+func genTarget(f *jen.File, msg *protogen.Message, sep, appPkg string) error {
+	typ := msg.GoIdent.GoName
+	recv := lowerFirst(typ)[:1]
+
+	if sep == "" || strings.Contains(sep, targetEscape) {
+		return fmt.Errorf("%s: the separator must be neither empty nor contain %q", typ, targetEscape)
+	}
+	keyed, err := keyFields(msg)
+	if err != nil {
+		return err
+	}
+	var required []*protogen.Field
+	for _, fld := range msg.Fields {
+		if isRequiredField(fld) {
+			required = append(required, fld)
+		}
+	}
+	if len(required) == 0 {
+		required = keyed
+	}
+
+	sepIdent := typ + "Separator"
+	f.Commentf("%s separates the components of the %s identifier", sepIdent, typ)
+	f.Const().Id(sepIdent).Op("=").Lit(sep)
+
+	escIdent := typ + "Escape"
+	f.Commentf("%s escapes each character of %s, and itself, within the components of the %s identifier", escIdent, sepIdent, typ)
+	f.Comment("so that distinct targets never share an identifier")
+	f.Const().Id(escIdent).Op("=").Lit(targetEscape)
+
+	escaper := lowerFirst(typ) + "Escaper"
+	f.Commentf("%s escapes a component of the %s identifier", escaper, typ)
+	f.Var().Id(escaper).Op("=").Qual("strings", "NewReplacer").CallFunc(func(g *jen.Group) {
+		g.Id(escIdent)
+		g.Id(escIdent).Op("+").Id(escIdent)
+		seen := make(map[rune]bool)
+		for _, r := range sep {
+			if !seen[r] {
+				seen[r] = true
+				g.Lit(string(r))
+				g.Id(escIdent).Op("+").Lit(string(r))
+			}
+		}
+	})
+
+	f.Commentf("Identifier implements the %s interface", distinguishable)
+	f.Func().Params(
+		jen.Id(recv).Op("*").Id(typ),
+	).Id("Identifier").Params().String().Block(
+		jen.Return(
+			jen.Qual("strings", "Join").Call(
+				jen.Index().String().ValuesFunc(func(g *jen.Group) {
+					for _, fld := range keyed {
+						g.Id(escaper).Dot("Replace").Call(formatField(recv, fld))
+					}
+				}),
+				jen.Id(sepIdent),
+			),
+		),
+	)
+
+	split := "split" + typ
+	f.Commentf("%s splits a %s identifier into its unescaped components", split, typ)
+	f.Comment("escapes which Identifier does not produce are rejected, so that each target has exactly one identifier")
+	f.Func().Id(split).Params(
+		jen.Id("s").String(),
+	).Params(
+		jen.Index().String(),
+		jen.Error(),
+	).Block(
+		jen.Var().Defs(
+			jen.Id("parts").Index().String(),
+			jen.Id("part").Qual("strings", "Builder"),
+		),
+		jen.For(jen.Id("i").Op(":=").Lit(0), jen.Id("i").Op("<").Len(jen.Id("s")), jen.Empty()).Block(
+			jen.Id("rest").Op(":=").Id("s").Index(jen.Id("i"), jen.Empty()),
+			jen.Switch().Block(
+				jen.Case(jen.Qual("strings", "HasPrefix").Call(jen.Id("rest"), jen.Id(escIdent))).Block(
+					jen.List(jen.Id("r"), jen.Id("n")).Op(":=").Qual("unicode/utf8", "DecodeRuneInString").Call(jen.Id("rest").Index(jen.Len(jen.Id(escIdent)), jen.Empty())),
+					jen.If(jen.Id("n").Op("==").Lit(0).Op("||").Op("!").Qual("strings", "ContainsRune").Call(jen.Id(escIdent).Op("+").Id(sepIdent), jen.Id("r"))).Block(
+						jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(
+							jen.Lit(fmt.Sprintf("%s identifier %%q: invalid escape at %%d", lowerFirst(typ))),
+							jen.Id("s"),
+							jen.Id("i"),
+						)),
+					),
+					jen.Id("part").Dot("WriteRune").Call(jen.Id("r")),
+					jen.Id("i").Op("+=").Len(jen.Id(escIdent)).Op("+").Id("n"),
+				),
+				jen.Case(jen.Qual("strings", "HasPrefix").Call(jen.Id("rest"), jen.Id(sepIdent))).Block(
+					jen.Id("parts").Op("=").Append(jen.Id("parts"), jen.Id("part").Dot("String").Call()),
+					jen.Id("part").Dot("Reset").Call(),
+					jen.Id("i").Op("+=").Len(jen.Id(sepIdent)),
+				),
+				jen.Default().Block(
+					jen.List(jen.Id("r"), jen.Id("n")).Op(":=").Qual("unicode/utf8", "DecodeRuneInString").Call(jen.Id("rest")),
+					jen.If(jen.Qual("strings", "ContainsRune").Call(jen.Id(sepIdent), jen.Id("r"))).Block(
+						jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(
+							jen.Lit(fmt.Sprintf("%s identifier %%q: unescaped %%q at %%d", lowerFirst(typ))),
+							jen.Id("s"),
+							jen.Id("r"),
+							jen.Id("i"),
+						)),
+					),
+					jen.Id("part").Dot("WriteString").Call(jen.Id("rest").Index(jen.Empty(), jen.Id("n"))),
+					jen.Id("i").Op("+=").Id("n"),
+				),
+			),
+		),
+		jen.Return(jen.Append(jen.Id("parts"), jen.Id("part").Dot("String").Call()), jen.Nil()),
+	)
+
+	parse := "Parse" + typ
+	f.Commentf("%s parses a %s from its identifier", parse, typ)
+	f.Func().Id(parse).Params(
+		jen.Id("s").String(),
+	).Params(
+		jen.Op("*").Id(typ),
+		jen.Error(),
+	).BlockFunc(func(g *jen.Group) {
+		g.List(jen.Id("parts"), jen.Err()).Op(":=").Id(split).Call(jen.Id("s"))
+		g.If(jen.Err().Op("!=").Nil()).Block(
+			jen.Return(jen.Nil(), jen.Err()),
+		)
+		g.If(jen.Len(jen.Id("parts")).Op("!=").Lit(len(keyed))).Block(
+			jen.Return(jen.Nil(), jen.Qual("fmt", "Errorf").Call(
+				jen.Lit(fmt.Sprintf("%s identifier %%q: expected %d components", lowerFirst(typ), len(keyed))),
+				jen.Id("s"),
+			)),
+		)
+		g.Id(recv).Op(":=").Op("&").Id(typ).Values()
+		for i, fld := range keyed {
+			parseField(g, recv, fld, jen.Id("parts").Index(jen.Lit(i)), fmt.Sprintf("%s identifier %%q: invalid", lowerFirst(typ)))
+		}
+		g.Return(jen.Id(recv), jen.Nil())
+	})
+
+	f.Commentf("IsDistinguishable implements the %s interface used by the", distinguishableAsserter)
+	f.Comment("application layer to assert valid targets")
+	f.Func().Params(
+		jen.Id(recv).Op("*").Id(typ),
+	).Id("IsDistinguishable").Params().Bool().Block(
+		jen.ReturnFunc(func(g *jen.Group) {
+			var cond *jen.Statement
+			for _, fld := range required {
+				c := jen.Add(isSetField(recv, fld))
+				if cond == nil {
+					cond = c
+				} else {
+					cond = cond.Op("&&").Add(c)
+				}
+			}
+			if cond == nil {
+				// without fields, no target is distinguishable
+				g.False()
+				return
+			}
+			g.Add(cond)
+		}),
+	)
+
+	if appPkg != "" {
+		f.Comment("compile time assertions")
+		f.Var().Defs(
+			jen.Id("_").Qual(appPkg, distinguishableAsserter).Op("=").Parens(jen.Op("*").Id(typ)).Call(jen.Nil()),
+			jen.Id("_").Qual(appPkg, distinguishable).Op("=").Parens(jen.Op("*").Id(typ)).Call(jen.Nil()),
+		)
+	}
+	return nil
+}
+
+// isSetField returns a condition which holds when fld is not the zero value
+func isSetField(recv string, fld *protogen.Field) jen.Code {
+	get := jen.Id(recv).Dot("Get" + fld.GoName).Call()
+	switch {
+	case fld.Desc.IsList() || fld.Desc.IsMap():
+		return jen.Len(get).Op("!=").Lit(0)
+	}
+	switch fld.Desc.Kind() {
+	case protoreflect.StringKind:
+		return get.Op("!=").Lit("")
+	case protoreflect.BytesKind:
+		return jen.Len(get).Op("!=").Lit(0)
+	case protoreflect.BoolKind:
+		return get
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return get.Op("!=").Nil()
+	default:
+		return get.Op("!=").Lit(0)
+	}
+}
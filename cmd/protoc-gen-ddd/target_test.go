@@ -0,0 +1,214 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// TestGenTarget generates the targets of targetTestProto into a temporary module and
+// runs targetTest against them, the messages are stood in for by targetTestTypes
+func TestGenTarget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a temporary module")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+	plugin, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"place.proto"},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{targetTestProto},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	seps := map[string]string{"Place": "-", "Route": "::"}
+	for _, msg := range plugin.Files[0].Messages {
+		f := newGeneratedFile(plugin.Files[0])
+		if err := genTarget(f, msg, seps[msg.GoIdent.GoName], ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Save(filepath.Join(dir, lowerFirst(msg.GoIdent.GoName)+".target.go")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"go.mod":         "module example.com/place\n\ngo 1.14\n",
+		"place.go":       targetTestTypes,
+		"target_test.go": targetTest,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "test", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
+
+func TestGenTargetSeparator(t *testing.T) {
+	plugin, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"place.proto"},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{targetTestProto},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sep := range []string{"", `\`, `-\`} {
+		f := newGeneratedFile(plugin.Files[0])
+		if err := genTarget(f, plugin.Files[0].Messages[0], sep, ""); err == nil {
+			t.Errorf("separator %q accepted", sep)
+		}
+	}
+}
+
+func targetTestField(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   typ.Enum(),
+	}
+}
+
+var targetTestProto = &descriptorpb.FileDescriptorProto{
+	Name:    proto.String("place.proto"),
+	Package: proto.String("place"),
+	Syntax:  proto.String("proto3"),
+	Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/place")},
+	MessageType: []*descriptorpb.DescriptorProto{
+		{
+			Name: proto.String("Place"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				targetTestField("zone", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				targetTestField("office", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				targetTestField("floor", 3, descriptorpb.FieldDescriptorProto_TYPE_INT64),
+			},
+		},
+		{
+			Name: proto.String("Route"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				targetTestField("from", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				targetTestField("to", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			},
+		},
+	},
+}
+
+const targetTestTypes = `package place
+
+type Place struct {
+	Zone   string
+	Office string
+	Floor  int64
+}
+
+func (p *Place) GetZone() string   { return p.Zone }
+func (p *Place) GetOffice() string { return p.Office }
+func (p *Place) GetFloor() int64   { return p.Floor }
+
+type Route struct {
+	From string
+	To   string
+}
+
+func (r *Route) GetFrom() string { return r.From }
+func (r *Route) GetTo() string   { return r.To }
+`
+
+const targetTest = `package place
+
+import "testing"
+
+func TestPlaceRoundTrip(t *testing.T) {
+	ids := make(map[string]Place)
+	for _, p := range []Place{
+		{Zone: "eu-west", Office: "1"},
+		{Zone: "eu", Office: "west-1"},
+		{Zone: "eu", Office: "west", Floor: -1},
+		{Zone: ` + "`a\\`" + `, Office: "-b"},
+		{Zone: ` + "`a\\-`" + `, Office: "b"},
+		{Zone: ` + "`\\\\`" + `, Office: ` + "`-\\`" + `},
+		{},
+	} {
+		id := p.Identifier()
+		if other, ok := ids[id]; ok {
+			t.Errorf("%+v and %+v share the identifier %q", p, other, id)
+		}
+		ids[id] = p
+		got, err := ParsePlace(id)
+		if err != nil {
+			t.Errorf("%+v: %v", p, err)
+			continue
+		}
+		if *got != p {
+			t.Errorf("%q parses into %+v, want %+v", id, *got, p)
+		}
+	}
+}
+
+func TestRouteRoundTrip(t *testing.T) {
+	ids := make(map[string]Route)
+	for _, r := range []Route{
+		{From: "a::b", To: "c"},
+		{From: "a", To: "b::c"},
+		{From: "a:", To: ":b"},
+		{From: "a", To: ":::b"},
+	} {
+		id := r.Identifier()
+		if other, ok := ids[id]; ok {
+			t.Errorf("%+v and %+v share the identifier %q", r, other, id)
+		}
+		ids[id] = r
+		got, err := ParseRoute(id)
+		if err != nil {
+			t.Errorf("%+v: %v", r, err)
+			continue
+		}
+		if *got != r {
+			t.Errorf("%q parses into %+v, want %+v", id, *got, r)
+		}
+	}
+}
+
+func TestParseRouteInvalid(t *testing.T) {
+	for _, id := range []string{"a:b::c", "a::b:", ":a::b"} {
+		if r, err := ParseRoute(id); err == nil {
+			t.Errorf("%q parses into %+v", id, *r)
+		}
+	}
+}
+
+func TestParsePlaceInvalid(t *testing.T) {
+	for _, id := range []string{
+		"eu-west",
+		"eu-west-1-2",
+		` + "`eu\\x-west-1`" + `,
+		` + "`eu-west-1\\`" + `,
+		"eu-west-one",
+	} {
+		if p, err := ParsePlace(id); err == nil {
+			t.Errorf("%q parses into %+v", id, *p)
+		}
+	}
+}
+`
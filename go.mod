@@ -13,4 +13,5 @@ require (
 	github.com/spf13/viper v1.7.1
 	golang.org/x/tools v0.0.0-20191112195655-aa38f8e97acc
 	google.golang.org/protobuf v1.25.0
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -2,7 +2,7 @@ package app
 
 // OffersAuthorizable is an actor that can be policed
 // application implements OffersAuthorizable and thereby offers policy adapter and external consumers a common language to reason about a authorizable actor
-// TODO: implement OffersAuthorizable
+// TODO: implement OffersAuthorizable or generate it with protoc-gen-ddd (--ddd_out=actor=<Message>)
 type OffersAuthorizable interface {
 	// TODO: adapt to your needs
 
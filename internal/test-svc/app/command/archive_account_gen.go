@@ -29,18 +29,18 @@ var (
 // ArchiveAccountHandlerWrapper knows how to perform ArchiveAccount
 type ArchiveAccountHandlerWrapper struct {
 	rw app.RequiresStorageWriterReader
-	p  app.RequiresPolicer
+	tp app.RequiresTargetPolicer
 }
 
 // NewArchiveAccountHandlerWrapper returns ArchiveAccountHandlerWrapper
-func NewArchiveAccountHandlerWrapper(rw app.RequiresStorageWriterReader, p app.RequiresPolicer) *ArchiveAccountHandlerWrapper {
+func NewArchiveAccountHandlerWrapper(rw app.RequiresStorageWriterReader, tp app.RequiresTargetPolicer) *ArchiveAccountHandlerWrapper {
 	if reflect.ValueOf(rw).IsZero() {
 		panic("no 'rw' provided!")
 	}
-	if reflect.ValueOf(p).IsZero() {
-		panic("no 'p' provided!")
+	if reflect.ValueOf(tp).IsZero() {
+		panic("no 'tp' provided!")
 	}
-	return &ArchiveAccountHandlerWrapper{rw: rw, p: p}
+	return &ArchiveAccountHandlerWrapper{rw: rw, tp: tp}
 }
 
 // Handle generically performs ArchiveAccount
@@ -49,16 +49,16 @@ func (h ArchiveAccountHandlerWrapper) Handle(ctx context.Context, aa domain.Arch
 	if !target.IsDistinguishable() {
 		return ErrArchiveAccountHasNoTarget
 	}
+	// assert authorization on the target via policy interface, before paying for a load
+	if ok := h.tp.CanOnTarget(ctx, actor, "ArchiveAccount", target); !ok {
+		// return opaque error: handle potentially sensitive policy errors out-of-band!
+		return ErrNotAuthorizedToArchiveAccount
+	}
 	// load entity from store; handle + wrap error
 	a, loadErr := h.rw.Load(ctx, target)
 	if loadErr != nil {
 		return errwrap.Wrap(ErrArchiveAccountLoadingFailed, loadErr)
 	}
-	// assert authorization via policy interface
-	if ok := h.p.Can(ctx, actor, "ArchiveAccount", a); !ok {
-		// return opaque error: handle potentially sensitive policy errors out-of-band!
-		return ErrNotAuthorizedToArchiveAccount
-	}
 	// assert correct command handling by the domain
 	if ok := aa.Handle(ctx, a); !ok {
 		var domErr error
@@ -4,7 +4,7 @@ package command
 type Commands struct {
 	MakeNewAccount       MakeNewAccountHandlerWrapper     ``
 	MakeNewAccountQuick  MakeNewAccountQuckHandlerWrapper `command:"topic,account"`
-	ArchiveAccount       ArchiveAccountHandlerWrapper     ``
+	ArchiveAccount       ArchiveAccountHandlerWrapper     `command:"authorize-before-load"`
 	BlockAccount         BlockAccountHandlerWrapper       ``
 	ValidateHolder       BlockAccountHandlerWrapper       `command:"w/o policy"`
 	ModifyBalance        ModifyBalanceHandlerWrapper      ``
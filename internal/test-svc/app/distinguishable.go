@@ -2,10 +2,9 @@ package app
 
 // OffersDistinguishable can be identified
 // application implements OffersDistinguishable and thereby offers storage adapter and external consumers a common language to reason about identity
-// TODO: implement OffersDistinguishable
+// TODO: implement OffersDistinguishable or generate it with protoc-gen-ddd (--ddd_out=target=<Message>)
 type OffersDistinguishable interface {
 	RequiresDistinguishableAsserter
 	// Identifier knows how to identify OffersDistinguishable
-	// TODO: adapt return type to your needs
 	Identifier() string
 }
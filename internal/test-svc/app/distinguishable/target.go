@@ -1,30 +0,0 @@
-package distinguishable
-
-import (
-	"fmt"
-
-	"github.com/satori/go.uuid"
-)
-
-// Target represents a target entity    --- this message component probably should be autogenerated, eg. by protobuf
-// type Target struct {
-// 	Continent string
-// 	Zone      string
-// 	Office    string
-// 	Id        uuid.UUID
-// }
-
-// Identifier implements the Distinguishable interface
-func (t *Target) Identifier() string {
-	return fmt.Sprintf("%s-%s-%s-%s", t.GetContinent(), t.GetZone(), t.GetOffice(), t.GetId())
-}
-
-// IsDistinguishable implements the DistinguishableAsserter interface used by the
-// application layer to assert valid targets
-func (t *Target) IsDistinguishable() bool {
-	// in this example:
-	//   - continent and zone are not required for a target to be distinguishable
-	//   - for example, it might be used optionally for sharding or routing purposes
-	return t.GetOffice() != "" && t.GetId() != uuid.Nil
-}
-
@@ -0,0 +1,4 @@
+// Package errors implements application layer errors
+package errors
+
+//go:generate go run ../../../../main.go --config ../../ddd-config.yaml app errors --type Error
@@ -0,0 +1,196 @@
+// Code generated by 'ddd-gen app errors': DO NOT EDIT.
+
+package errors
+
+import (
+	"fmt"
+	errwrap "github.com/hashicorp/errwrap"
+	"sort"
+	"strings"
+)
+
+// Kind classifies application errors
+type Kind int
+
+const (
+	// KindUnknown classifies errors not raised by the application layer
+	KindUnknown Kind = iota
+	// KindAuthorization classifies errors signaling that the caller is not authorized
+	KindAuthorization
+	// KindTargetIdentification classifies errors signaling that the target could not be identified
+	KindTargetIdentification
+	// KindStorageLoading classifies errors signaling that the storage failed to load the entity
+	KindStorageLoading
+	// KindStorageSaving classifies errors signaling that the storage failed to save the entity
+	KindStorageSaving
+	// KindDomain classifies errors signaling that the domain failed to handle the command
+	KindDomain
+)
+
+// String implements the fmt.Stringer interface
+func (k Kind) String() string {
+	switch k {
+	case KindAuthorization:
+		return "Authorization"
+	case KindTargetIdentification:
+		return "TargetIdentification"
+	case KindStorageLoading:
+		return "StorageLoading"
+	case KindStorageSaving:
+		return "StorageSaving"
+	case KindDomain:
+		return "Domain"
+	default:
+		return "Unknown"
+	}
+}
+
+// Error is an application error of a distinct Kind
+// its code identifies the error, structured fields may carry additional context
+type Error struct {
+	kind   Kind
+	code   string
+	fields map[string]interface{}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if len(e.fields) == 0 {
+		return e.code
+	}
+	keys := make([]string, 0, len(e.fields))
+	for k := range e.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(e.code)
+	for i, k := range keys {
+		if i == 0 {
+			b.WriteString(" (")
+		} else {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s=%v", k, e.fields[k])
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// Kind returns the kind of the error
+func (e *Error) Kind() Kind {
+	return e.kind
+}
+
+// Code returns the code which identifies the error
+func (e *Error) Code() string {
+	return e.code
+}
+
+// Is reports whether target is a Error of the same kind and code
+// a target without code (e.g. ErrAuthorization) matches any error of its kind
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.kind == e.kind && (t.code == "" || t.code == e.code)
+}
+
+// With returns a copy of e which carries the structured field key
+func (e *Error) With(key string, value interface{}) *Error {
+	fields := make(map[string]interface{}, len(e.fields)+1)
+	for k, v := range e.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+	return &Error{
+		code:   e.code,
+		fields: fields,
+		kind:   e.kind,
+	}
+}
+
+// Fields returns a copy of the structured fields of the error
+func (e *Error) Fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(e.fields))
+	for k, v := range e.fields {
+		fields[k] = v
+	}
+	return fields
+}
+
+var (
+	// ErrAuthorization matches any error of KindAuthorization
+	ErrAuthorization = &Error{kind: KindAuthorization}
+	// ErrTargetIdentification matches any error of KindTargetIdentification
+	ErrTargetIdentification = &Error{kind: KindTargetIdentification}
+	// ErrStorageLoading matches any error of KindStorageLoading
+	ErrStorageLoading = &Error{kind: KindStorageLoading}
+	// ErrStorageSaving matches any error of KindStorageSaving
+	ErrStorageSaving = &Error{kind: KindStorageSaving}
+	// ErrDomain matches any error of KindDomain
+	ErrDomain = &Error{kind: KindDomain}
+)
+
+// NewAuthorizationError returns a Error of KindAuthorization identified by code
+func NewAuthorizationError(code string) *Error {
+	return &Error{
+		code: code,
+		kind: KindAuthorization,
+	}
+}
+
+// NewTargetIdentificationError returns a Error of KindTargetIdentification identified by code
+func NewTargetIdentificationError(code string) *Error {
+	return &Error{
+		code: code,
+		kind: KindTargetIdentification,
+	}
+}
+
+// NewStorageLoadingError returns a Error of KindStorageLoading identified by code
+func NewStorageLoadingError(code string) *Error {
+	return &Error{
+		code: code,
+		kind: KindStorageLoading,
+	}
+}
+
+// NewStorageSavingError returns a Error of KindStorageSaving identified by code
+func NewStorageSavingError(code string) *Error {
+	return &Error{
+		code: code,
+		kind: KindStorageSaving,
+	}
+}
+
+// NewDomainError returns a Error of KindDomain identified by code
+func NewDomainError(code string) *Error {
+	return &Error{
+		code: code,
+		kind: KindDomain,
+	}
+}
+
+// KindOf returns the kind of the first application error found in err
+// it understands both errwrap wrapping and Unwrap() chains
+func KindOf(err error) Kind {
+	kind := KindUnknown
+	errwrap.Walk(err, func(err error) {
+		for kind == KindUnknown && err != nil {
+			if e, ok := err.(*Error); ok {
+				kind = e.kind
+				return
+			}
+			u, ok := err.(interface {
+				Unwrap() error
+			})
+			if !ok {
+				return
+			}
+			err = u.Unwrap()
+		}
+	})
+	return kind
+}
@@ -10,3 +10,10 @@ import (
 type RequiresPolicer interface {
 	Can(ctx context.Context, p OffersAuthorizable, action string, a *account.Account) bool
 }
+
+// RequiresTargetPolicer knows to make decisions on access policy before Account entity is loaded
+// application requires policy adapter to implement this interface for commands tagged 'authorize-before-load'.
+type RequiresTargetPolicer interface {
+	// CanOnTarget knows whether p may perform action on target, judging only by actor and target
+	CanOnTarget(ctx context.Context, p OffersAuthorizable, action string, target OffersDistinguishable) bool
+}
@@ -0,0 +1,18 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter/generator"
+)
+
+// GenAuditLog generates the JSON lines audit log adapter into the current working directory
+func GenAuditLog(typ string, conf *Config) error {
+	cwd, goPackage, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	gf := generator.GenAuditLog(goPackage, typ, conf.App)
+	return save(gf, genPath(cwd, "auditlog_gen.go"))
+}
@@ -0,0 +1,56 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter/generator"
+)
+
+type Config struct {
+	App    string           // import path of the application interfaces
+	Entity generator.QualId // entity, only required by storage adapters
+}
+
+func NewConfig(
+	app,
+	entity string,
+) (*Config, error) {
+	if app == "" || strings.HasSuffix(app, "/") {
+		return nil, fmt.Errorf("'%s' is not a valid app import path", app)
+	}
+	conf := &Config{
+		App: app,
+	}
+	if entity != "" {
+		if !isValidQualId(entity) {
+			return nil, fmt.Errorf("'%s' is not a valid full qualifier entity", entity)
+		}
+		conf.Entity = splitQual(entity)
+	}
+	return conf, nil
+}
+
+func isValidQualId(s string) bool {
+	idx := strings.LastIndex(s, ".")
+	if idx != -1 {
+		id := s[strings.LastIndex(s, ".")+1:]         // suggested identifier
+		if strings.Index(id, "/") == -1 && id != "" { // no '/' in suggested identifier
+			return unicode.IsUpper(rune(id[0])) // starts with upper case (is exported)
+		}
+	}
+	return false
+}
+
+func splitQual(s string) generator.QualId {
+	imp := s[:strings.LastIndex(s, ".")]
+	id := s[strings.LastIndex(s, ".")+1:]
+	return generator.QualId{
+		Qual: imp,
+		Id:   id,
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"fmt"
+	"go/types"
+	"log"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter/generator"
+	domaingen "github.com/xoe-labs/ddd-gen/pkg/gen_domain/generator"
+)
+
+// storeColumns maps the parameters of the entity's UnmarshalFromStore to columns
+func storeColumns(entity generator.QualId) ([]generator.Column, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedTypes}, entity.Qual)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 || pkgs[0].Types == nil {
+		return nil, fmt.Errorf("cannot load entity package %s", entity.Qual)
+	}
+	obj := pkgs[0].Types.Scope().Lookup(domaingen.UnmarshalFromStore)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return nil, fmt.Errorf("%s not found in %s, run 'ddd-gen domain entity' first", domaingen.UnmarshalFromStore, entity.Qual)
+	}
+	params := fn.Type().(*types.Signature).Params()
+	var cols []generator.Column
+	for i := 0; i < params.Len(); i++ {
+		p := params.At(i)
+		typ, err := domaingen.QualifiedType(p.Type())
+		if err != nil {
+			return nil, fmt.Errorf("%s: field '%s': %w", entity.Id, p.Name(), err)
+		}
+		col := generator.Column{
+			Field: p.Name(),
+			Name:  generator.ToSnakeCase(p.Name()),
+			Typ:   typ,
+			Type:  p.Type(),
+		}
+		col.Kind = generator.KindOf(p.Type())
+		log.Printf("%s: field '%s'\n", entity.Id, col.Field)
+		cols = append(cols, col)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("%s of %s has no fields", domaingen.UnmarshalFromStore, entity.Id)
+	}
+	return cols, nil
+}
@@ -0,0 +1,23 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"fmt"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter/generator"
+)
+
+// GenEventStore generates the event store adapter into the current working directory
+func GenEventStore(typ string, useSnapshots bool, conf *Config) error {
+	if conf.Entity.Id == "" {
+		return fmt.Errorf("'entity' is not configured")
+	}
+	cwd, goPackage, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	gf := generator.GenEventStore(goPackage, typ, conf.App, conf.Entity, useSnapshots)
+	return save(gf, genPath(cwd, "eventstore_gen.go"))
+}
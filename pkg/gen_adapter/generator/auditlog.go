@@ -0,0 +1,128 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+var cmdGenAuditLog string = "ddd-gen adapter auditlog"
+
+func auditLogLine(typ string) string {
+	return lowerFirst(typ) + "Line"
+}
+
+func addAuditLogType(f *File, typ string) {
+	f.Commentf("%s writes the audit trail as JSON lines to an io.Writer", typ)
+	f.Commentf("it implements the %s interface and is safe for concurrent use", appgen.Auditor)
+	f.Type().Id(typ).Struct(
+		Id("mu").Qual("sync", "Mutex"),
+		Id("w").Qual("io", "Writer"),
+		Id("err").Error(),
+	)
+
+	ident := "New" + typ
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns a %s writing JSON lines to w", ident, typ)
+	f.Func().Id(ident).Params(
+		Id("w").Qual("io", "Writer"),
+	).Op("*").Id(typ).Block(
+		If(Id("w").Op("==").Nil()).Block(
+			Id("panic").Call(Lit("no 'w' provided!")),
+		),
+		Return(Op("&").Id(typ).Values(Dict{
+			Id("w"): Id("w"),
+		})),
+	)
+}
+
+func addAuditLogLine(f *File, typ string) {
+	f.Commentf("%s is the JSON representation of an %s", auditLogLine(typ), appgen.AuditRecord)
+	f.Type().Id(auditLogLine(typ)).Struct(
+		Id("Time").Qual("time", "Time").Tag(map[string]string{"json": "time"}),
+		Id("User").String().Tag(map[string]string{"json": "user"}),
+		Id("Elevated").Bool().Tag(map[string]string{"json": "elevated"}),
+		Id("Target").String().Tag(map[string]string{"json": "target"}),
+		Id("Command").String().Tag(map[string]string{"json": "command"}),
+		Id("Topic").String().Tag(map[string]string{"json": "topic"}),
+		Id("Outcome").String().Tag(map[string]string{"json": "outcome"}),
+		Id("Error").String().Tag(map[string]string{"json": "error,omitempty"}),
+		Id("Facts").Index().Interface().Tag(map[string]string{"json": "facts,omitempty"}),
+		Id("FactsError").String().Tag(map[string]string{"json": "factsError,omitempty"}),
+	)
+}
+
+func addAuditLogAudit(f *File, typ, appPkg string) {
+	short := cmdShortForm(typ)
+	log.Printf("%s: generating '%s()'\n", typ, appgen.AuditMethod)
+	f.Commentf("%s implements the %s interface", appgen.AuditMethod, appgen.Auditor)
+	f.Comment("the elevation token of the actor is never written, only whether it was present")
+	f.Comment("facts which cannot be marshalled are dropped, so that the record itself is never lost")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.AuditMethod).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("r").Qual(appPkg, appgen.AuditRecord),
+	).Block(
+		Id("line").Op(":=").Id(auditLogLine(typ)).Values(Dict{
+			Id("Time"):    Id("r").Dot("Time").Dot("UTC").Call(),
+			Id("Target"):  Id("r").Dot("Target"),
+			Id("Command"): Id("r").Dot("Command"),
+			Id("Topic"):   Id("r").Dot("Topic"),
+			Id("Outcome"): String().Call(Id("r").Dot("Outcome")),
+			Id("Facts"):   Id("r").Dot("Facts"),
+		}),
+		If(Id("r").Dot("Actor").Op("!=").Nil()).Block(
+			Id("line").Dot("User").Op("=").Id("r").Dot("Actor").Dot(appgen.AuthorizableUserMethod).Call(),
+			Id("line").Dot("Elevated").Op("=").Id("r").Dot("Actor").Dot(appgen.AuthorizableElevationTokenMethod).Call().Op("!=").Lit(""),
+		),
+		If(Id("r").Dot("Err").Op("!=").Nil()).Block(
+			Id("line").Dot("Error").Op("=").Id("r").Dot("Err").Dot("Error").Call(),
+		),
+		List(Id("b"), Id("err")).Op(":=").Qual("encoding/json", "Marshal").Call(Id("line")),
+		If(Id("err").Op("!=").Nil()).Block(
+			Id("line").Dot("Facts").Op("=").Nil(),
+			Id("line").Dot("FactsError").Op("=").Id("err").Dot("Error").Call(),
+			List(Id("b"), Id("err")).Op("=").Qual("encoding/json", "Marshal").Call(Id("line")),
+		),
+		Id(short).Dot("mu").Dot("Lock").Call(),
+		Defer().Id(short).Dot("mu").Dot("Unlock").Call(),
+		If(Id("err").Op("==").Nil()).Block(
+			List(Id("_"), Id("err")).Op("=").Id(short).Dot("w").Dot("Write").Call(
+				Append(Id("b"), LitRune('\n')),
+			),
+		),
+		If(Id("err").Op("!=").Nil().Op("&&").Id(short).Dot("err").Op("==").Nil()).Block(
+			Id(short).Dot("err").Op("=").Id("err"),
+		),
+	)
+
+	f.Comment("Err returns the first error encountered while writing the audit trail")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Err").Params().Error().Block(
+		Id(short).Dot("mu").Dot("Lock").Call(),
+		Defer().Id(short).Dot("mu").Dot("Unlock").Call(),
+		Return(Id(short).Dot("err")),
+	)
+}
+
+// Composers ...
+
+func GenAuditLog(pkgName, typ, appPkg string) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenAuditLog))
+	ret.Line()
+	addAuditLogType(ret, typ)
+	addAuditLogLine(ret, typ)
+	addAuditLogAudit(ret, typ, appPkg)
+	ret.Comment("compile time assertions")
+	ret.Var().Id("_").Qual(appPkg, appgen.Auditor).Op("=").Parens(Op("*").Id(typ)).Call(Nil())
+	return ret
+}
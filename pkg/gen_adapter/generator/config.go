@@ -0,0 +1,50 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"go/types"
+
+	"github.com/dave/jennifer/jen"
+)
+
+type QualId struct{ Id, Qual string }
+
+// ColumnKind classifies how a field is stored
+type ColumnKind int
+
+const (
+	TextColumn ColumnKind = iota
+	BoolColumn
+	IntColumn
+	FloatColumn
+	BytesColumn
+	TimeColumn
+	JSONColumn // types without a column type of their own are stored JSON encoded
+)
+
+// Column maps an entity field, as enumerated by UnmarshalFromStore, to a table column
+type Column struct {
+	Field  string         // field name
+	Name   string         // column name
+	Typ    *jen.Statement // go type of the field
+	Type   types.Type     // type of the field, as loaded
+	Kind   ColumnKind
+	SQLTyp string // column type
+}
+
+// JSON reports whether the field is stored JSON encoded
+func (c Column) JSON() bool { return c.Kind == JSONColumn }
+
+// Null reports whether the column is nullable, only nil byte slices are stored as NULL
+func (c Column) Null() bool { return c.Kind == BytesColumn }
+
+// Policy is a compiled policy expression which decides on a command
+type Policy struct {
+	Command string // name of the command, the action
+	Source  string // expression as configured
+	Expr    string // type checked go expression over actor, entity and cmd
+	Entity  bool   // the expression refers to the entity
+	Cmd     bool   // the expression refers to the command payload
+}
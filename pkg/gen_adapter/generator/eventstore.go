@@ -0,0 +1,800 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+	domaingen "github.com/xoe-labs/ddd-gen/pkg/gen_domain/generator"
+)
+
+var cmdGenEventStore string = "ddd-gen adapter eventstore"
+
+const protoPkg = "google.golang.org/protobuf/proto"
+
+func eventStoreRecord(typ string) string        { return typ + "Record" }
+func eventStoreBackend(typ string) string       { return typ + "Backend" }
+func memoryEventStoreBackend(typ string) string { return "Memory" + typ + "Backend" }
+func fileEventStoreBackend(typ string) string   { return "File" + typ + "Backend" }
+func encodeEventStoreRecords(typ string) string { return "encode" + typ + "Records" }
+func decodeEventStoreRecords(typ string) string { return "decode" + typ + "Records" }
+func eventStoreSnapshot(typ string) string      { return lowerFirst(typ) + "Snapshot" }
+
+const versionConflictError = "VersionConflictError"
+
+func addEventStoreError(f *File) {
+	errTyp := versionConflictError
+	f.Commentf("%s signals that a stream has not the expected number of records, e.g. because", errTyp)
+	f.Comment("facts of a concurrent command were appended since the entity was loaded")
+	f.Type().Id(errTyp).Struct(
+		Id("Stream").String(),
+		Id("Expected").Uint64(),
+		Id("Actual").Uint64(),
+	)
+
+	f.Comment("Error implements the error interface")
+	f.Func().Params(
+		Id("e").Op("*").Id(errTyp),
+	).Id("Error").Params().String().Block(
+		Return(Qual("fmt", "Sprintf").Call(
+			Lit("stream '%s' has %d records, expected %d"),
+			Id("e").Dot("Stream"),
+			Id("e").Dot("Actual"),
+			Id("e").Dot("Expected"),
+		)),
+	)
+}
+
+func addEventStoreBackend(f *File, typ string, useSnapshots bool) {
+	rec := eventStoreRecord(typ)
+	f.Commentf("%s is a domain fact as it is stored in a stream", rec)
+	f.Type().Id(rec).Struct(
+		Id("Name").String().Comment("full proto name of the fact"),
+		Id("Version").Uint32().Comment("schema version of the fact"),
+		Id("Payload").Index().Byte().Comment("proto encoded fact"),
+	)
+
+	f.Commentf("%s knows how to append records to and read records from a stream", eventStoreBackend(typ))
+	if useSnapshots {
+		f.Comment("as well as how to keep the latest snapshot of a stream")
+	}
+	f.Type().Id(eventStoreBackend(typ)).InterfaceFunc(func(g *Group) {
+		g.Comment("Append knows how to atomically append records to stream and how many records stream has thereafter")
+		g.Commentf("if stream has other than expectedVersion records, it appends nothing and fails with a %s", versionConflictError)
+		g.Id("Append").Params(Id("stream").String(), Id("expectedVersion").Uint64(), Id("records").Index().Id(rec)).Params(Id("version").Uint64(), Err().Error())
+		g.Comment("Read knows how to read all records of stream in order")
+		g.Id("Read").Params(Id("stream").String()).Params(Index().Id(rec), Error())
+		if useSnapshots {
+			g.Comment("SaveSnapshot knows how to replace the snapshot of stream with data, which covers the first version records")
+			g.Id("SaveSnapshot").Params(Id("stream").String(), Id("version").Uint64(), Id("data").Index().Byte()).Error()
+			g.Comment("LoadSnapshot knows how to load the snapshot of stream; without snapshot, data is nil")
+			g.Id("LoadSnapshot").Params(Id("stream").String()).Params(Id("version").Uint64(), Id("data").Index().Byte(), Err().Error())
+		}
+	})
+}
+
+func addEventStoreType(f *File, typ string, entity QualId) {
+	upcaster := entity.Id + "Upcaster"
+	f.Commentf("%s stores the facts of %s entities per target identifier stream", typ, entity.Id)
+	f.Commentf("it relies on the fact registry generated by protoc-gen-ddd next to %s entity", entity.Id)
+	f.Type().Id(typ).Struct(
+		Id("b").Id(eventStoreBackend(typ)),
+		Id("up").Qual(entity.Qual, upcaster),
+	)
+
+	ident := "New" + typ
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns a new %s over backend b", ident, typ)
+	f.Commentf("up upcasts facts stored in older schema versions and may be nil")
+	f.Func().Id(ident).Params(
+		Id("b").Id(eventStoreBackend(typ)),
+		Id("up").Qual(entity.Qual, upcaster),
+	).Op("*").Id(typ).Block(
+		If(Id("b").Op("==").Nil()).Block(
+			Id("panic").Call(Lit("no 'b' provided!")),
+		),
+		Return(Op("&").Id(typ).Values(Dict{
+			Id("b"):  Id("b"),
+			Id("up"): Id("up"),
+		})),
+	)
+}
+
+func addEventStoreMethods(f *File, typ, appPkg string, entity QualId, useSnapshots bool) {
+	short := cmdShortForm(typ)
+	rec := eventStoreRecord(typ)
+
+	f.Comment("replay applies records onto e in order")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("replay").Params(
+		Id("e").Op("*").Qual(entity.Qual, entity.Id),
+		Id("records").Index().Id(rec),
+	).Error().Block(
+		For(List(Id("_"), Id("r")).Op(":=").Range().Id("records")).Block(
+			If(
+				Id("err").Op(":=").Id("e").Dot("ApplyStored").Call(
+					Id("r").Dot("Name"),
+					Id("r").Dot("Version"),
+					Id("r").Dot("Payload"),
+					Id(short).Dot("up"),
+				),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Id("err")),
+			),
+		),
+		Return(Nil()),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageLoadMethod)
+	f.Commentf("%s rehydrates %s entity by applying the facts of the target's stream in order", appgen.StorageLoadMethod, entity.Id)
+	f.Comment("the entity of an empty stream is returned in its zero state")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.StorageLoadMethod).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+	).Params(
+		Op("*").Qual(entity.Qual, entity.Id),
+		Error(),
+	).Block(
+		List(Id("records"), Id("err")).Op(":=").Id(short).Dot("b").Dot("Read").Call(
+			Id("target").Dot(appgen.DistinguishableMethod).Call(),
+		),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), Id("err")),
+		),
+		Id("e").Op(":=").Op("&").Qual(entity.Qual, entity.Id).Values(),
+		If(
+			Id("err").Op(":=").Id(short).Dot("replay").Call(Id("e"), Id("records")),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Id("err")),
+		),
+		Return(Id("e"), Nil()),
+	)
+
+	// without snapshots, SaveFacts only returns the error
+	fail := func(err Code) *Statement {
+		if useSnapshots {
+			return Return(Lit(0), err)
+		}
+		return Return(err)
+	}
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageSaveFactsMethod)
+	f.Commentf("%s appends domain facts to the target's stream", appgen.StorageSaveFactsMethod)
+	f.Commentf("unless it has other than expectedVersion facts, which fails with a %s", versionConflictError)
+	if useSnapshots {
+		f.Comment("version is the length of the stream thereafter")
+	}
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.StorageSaveFactsMethod).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+		Id("fk").Qual(appPkg, appgen.FactKeeper),
+		Id("expectedVersion").Uint64(),
+	).ParamsFunc(func(g *Group) {
+		if useSnapshots {
+			g.Id("version").Uint64()
+		}
+		g.Err().Error()
+	}).BlockFunc(func(g *Group) {
+		g.Id("facts").Op(":=").Id("fk").Dot(appgen.FactKeeperMethod).Call()
+		g.If(Len(Id("facts")).Op("==").Lit(0)).BlockFunc(func(g *Group) {
+			if !useSnapshots {
+				g.Return(Nil())
+				return
+			}
+			g.List(Id("records"), Id("err")).Op(":=").Id(short).Dot("b").Dot("Read").Call(
+				Id("target").Dot(appgen.DistinguishableMethod).Call(),
+			)
+			g.If(Id("err").Op("!=").Nil()).Block(
+				Return(Lit(0), Id("err")),
+			)
+			g.Return(Uint64().Call(Len(Id("records"))), Nil())
+		})
+		g.Id("records").Op(":=").Make(Index().Id(rec), Lit(0), Len(Id("facts")))
+		g.For(List(Id("_"), Id("fact")).Op(":=").Range().Id("facts")).Block(
+			List(Id("m"), Id("ok")).Op(":=").Id("fact").Assert(Qual(protoPkg, "Message")),
+			If(Op("!").Id("ok")).Block(
+				fail(Qual("fmt", "Errorf").Call(Lit("fact %T is not a proto message"), Id("fact"))),
+			),
+			List(Id("name"), Id("version"), Id("payload"), Id("err")).Op(":=").Qual(entity.Qual, "Encode"+entity.Id+"Fact").Call(Id("m")),
+			If(Id("err").Op("!=").Nil()).Block(
+				fail(Id("err")),
+			),
+			Id("records").Op("=").Append(Id("records"), Id(rec).Values(Dict{
+				Id("Name"):    Id("name"),
+				Id("Version"): Id("version"),
+				Id("Payload"): Id("payload"),
+			})),
+		)
+		appendRecords := Id(short).Dot("b").Dot("Append").Call(
+			Id("target").Dot(appgen.DistinguishableMethod).Call(),
+			Id("expectedVersion"),
+			Id("records"),
+		)
+		if useSnapshots {
+			g.Return(appendRecords)
+			return
+		}
+		g.List(Id("_"), Id("err")).Op("=").Add(appendRecords)
+		g.Return(Id("err"))
+	})
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageLoadSince)
+	f.Commentf("%s applies the facts of the target's stream after the first snapshotVersion onto snapshot", appgen.StorageLoadSince)
+	f.Comment("a snapshot ahead of the stream, e.g. after a torn tail was truncated, is ignored and all facts are replayed")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.StorageLoadSince).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+		Id("snapshot").Op("*").Qual(entity.Qual, entity.Id),
+		Id("snapshotVersion").Uint64(),
+	).Params(
+		Id("e").Op("*").Qual(entity.Qual, entity.Id),
+		Id("version").Uint64(),
+		Err().Error(),
+	).Block(
+		List(Id("records"), Id("err")).Op(":=").Id(short).Dot("b").Dot("Read").Call(
+			Id("target").Dot(appgen.DistinguishableMethod).Call(),
+		),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), Lit(0), Id("err")),
+		),
+		Id("e").Op("=").Id("snapshot"),
+		If(Id("e").Op("==").Nil().Op("||").Id("snapshotVersion").Op(">").Uint64().Call(Len(Id("records")))).Block(
+			List(Id("e"), Id("snapshotVersion")).Op("=").List(Op("&").Qual(entity.Qual, entity.Id).Values(), Lit(0)),
+		),
+		If(
+			Id("err").Op(":=").Id(short).Dot("replay").Call(Id("e"), Id("records").Index(Id("snapshotVersion"), Empty())),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Lit(0), Id("err")),
+		),
+		Return(Id("e"), Uint64().Call(Len(Id("records"))), Nil()),
+	)
+
+	if !useSnapshots {
+		return
+	}
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageLoadSnapshot)
+	f.Commentf("%s loads the latest snapshot of the target's stream through %s", appgen.StorageLoadSnapshot, domaingen.UnmarshalSnapshot)
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.StorageLoadSnapshot).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+	).Params(
+		Id("e").Op("*").Qual(entity.Qual, entity.Id),
+		Id("version").Uint64(),
+		Err().Error(),
+	).Block(
+		List(Id("version"), Id("data"), Id("err")).Op(":=").Id(short).Dot("b").Dot("LoadSnapshot").Call(
+			Id("target").Dot(appgen.DistinguishableMethod).Call(),
+		),
+		If(Id("err").Op("!=").Nil().Op("||").Id("data").Op("==").Nil()).Block(
+			Return(Nil(), Lit(0), Id("err")),
+		),
+		List(Id("e"), Id("err")).Op("=").Qual(entity.Qual, domaingen.UnmarshalSnapshot).Call(Id("data")),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), Lit(0), Id("err")),
+		),
+		Return(Id("e"), Id("version"), Nil()),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageSaveSnapshot)
+	f.Commentf("%s replaces the snapshot of the target's stream through %s", appgen.StorageSaveSnapshot, domaingen.MarshalSnapshot)
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.StorageSaveSnapshot).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+		Id("e").Op("*").Qual(entity.Qual, entity.Id),
+		Id("version").Uint64(),
+	).Error().Block(
+		List(Id("data"), Id("err")).Op(":=").Id("e").Dot(domaingen.MarshalSnapshot).Call(),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Id("err")),
+		),
+		Return(Id(short).Dot("b").Dot("SaveSnapshot").Call(
+			Id("target").Dot(appgen.DistinguishableMethod).Call(),
+			Id("version"),
+			Id("data"),
+		)),
+	)
+}
+
+func addMemoryEventStoreBackend(f *File, typ string, useSnapshots bool) {
+	rec := eventStoreRecord(typ)
+	backend := memoryEventStoreBackend(typ)
+
+	if useSnapshots {
+		f.Commentf("%s is a snapshot of a stream", eventStoreSnapshot(typ))
+		f.Type().Id(eventStoreSnapshot(typ)).Struct(
+			Id("version").Uint64(),
+			Id("data").Index().Byte(),
+		)
+	}
+
+	f.Commentf("%s keeps streams in memory, e.g. for tests", backend)
+	f.Comment("it is safe for concurrent use")
+	f.Type().Id(backend).StructFunc(func(g *Group) {
+		g.Id("mu").Qual("sync", "RWMutex")
+		g.Id("streams").Map(String()).Index().Id(rec)
+		if useSnapshots {
+			g.Id("snapshots").Map(String()).Id(eventStoreSnapshot(typ))
+		}
+	})
+
+	ident := "New" + backend
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns an empty %s", ident, backend)
+	f.Func().Id(ident).Params().Op("*").Id(backend).Block(
+		Return(Op("&").Id(backend).Values(DictFunc(func(d Dict) {
+			d[Id("streams")] = Make(Map(String()).Index().Id(rec))
+			if useSnapshots {
+				d[Id("snapshots")] = Make(Map(String()).Id(eventStoreSnapshot(typ)))
+			}
+		}))),
+	)
+
+	f.Commentf("Append implements %s", eventStoreBackend(typ))
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("Append").Params(
+		Id("stream").String(),
+		Id("expectedVersion").Uint64(),
+		Id("records").Index().Id(rec),
+	).Params(
+		Uint64(),
+		Error(),
+	).Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+		If(
+			Id("n").Op(":=").Uint64().Call(Len(Id("b").Dot("streams").Index(Id("stream")))),
+			Id("n").Op("!=").Id("expectedVersion"),
+		).Block(
+			Return(Lit(0), Op("&").Id(versionConflictError).Values(Dict{
+				Id("Stream"):   Id("stream"),
+				Id("Expected"): Id("expectedVersion"),
+				Id("Actual"):   Id("n"),
+			})),
+		),
+		For(List(Id("_"), Id("r")).Op(":=").Range().Id("records")).Block(
+			Id("r").Dot("Payload").Op("=").Append(Index().Byte().Call(Nil()), Id("r").Dot("Payload").Op("...")),
+			Id("b").Dot("streams").Index(Id("stream")).Op("=").Append(Id("b").Dot("streams").Index(Id("stream")), Id("r")),
+		),
+		Return(Uint64().Call(Len(Id("b").Dot("streams").Index(Id("stream")))), Nil()),
+	)
+
+	f.Commentf("Read implements %s", eventStoreBackend(typ))
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("Read").Params(
+		Id("stream").String(),
+	).Params(
+		Index().Id(rec),
+		Error(),
+	).Block(
+		Id("b").Dot("mu").Dot("RLock").Call(),
+		Defer().Id("b").Dot("mu").Dot("RUnlock").Call(),
+		Id("records").Op(":=").Make(Index().Id(rec), Len(Id("b").Dot("streams").Index(Id("stream")))),
+		Copy(Id("records"), Id("b").Dot("streams").Index(Id("stream"))),
+		Return(Id("records"), Nil()),
+	)
+
+	if !useSnapshots {
+		return
+	}
+
+	f.Commentf("SaveSnapshot implements %s", eventStoreBackend(typ))
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("SaveSnapshot").Params(
+		Id("stream").String(),
+		Id("version").Uint64(),
+		Id("data").Index().Byte(),
+	).Error().Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+		Id("b").Dot("snapshots").Index(Id("stream")).Op("=").Id(eventStoreSnapshot(typ)).Values(Dict{
+			Id("version"): Id("version"),
+			Id("data"):    Append(Index().Byte().Call(Nil()), Id("data").Op("...")),
+		}),
+		Return(Nil()),
+	)
+
+	f.Commentf("LoadSnapshot implements %s", eventStoreBackend(typ))
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("LoadSnapshot").Params(
+		Id("stream").String(),
+	).Params(
+		Uint64(),
+		Index().Byte(),
+		Error(),
+	).Block(
+		Id("b").Dot("mu").Dot("RLock").Call(),
+		Defer().Id("b").Dot("mu").Dot("RUnlock").Call(),
+		List(Id("s"), Id("ok")).Op(":=").Id("b").Dot("snapshots").Index(Id("stream")),
+		If(Op("!").Id("ok")).Block(
+			Return(Lit(0), Nil(), Nil()),
+		),
+		Return(Id("s").Dot("version"), Append(Index().Byte().Call(Nil()), Id("s").Dot("data").Op("...")), Nil()),
+	)
+}
+
+func addFileEventStoreBackend(f *File, typ string, useSnapshots bool) {
+	rec := eventStoreRecord(typ)
+	backend := fileEventStoreBackend(typ)
+	be := Qual("encoding/binary", "BigEndian")
+
+	f.Commentf("%s appends the records of each stream to its own file in a directory", backend)
+	f.Comment("records are length prefixed and checksummed and every append is synced to disk")
+	f.Comment("a torn or corrupt tail, e.g. after a crash during an append, is truncated on the next access of the stream")
+	if useSnapshots {
+		f.Comment("the latest snapshot of each stream is atomically replaced in a file next to it")
+	}
+	f.Comment("it is safe for concurrent use, but not for concurrent use of the same directory by several processes")
+	f.Type().Id(backend).Struct(
+		Id("mu").Qual("sync", "Mutex"),
+		Id("dir").String(),
+		Id("recovered").Map(String()).Bool(),
+		Id("lengths").Map(String()).Uint64().Comment("number of records of recovered files"),
+	)
+
+	ident := "New" + backend
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns a %s storing streams in dir, which is created if needed", ident, backend)
+	f.Func().Id(ident).Params(
+		Id("dir").String(),
+	).Params(
+		Op("*").Id(backend),
+		Error(),
+	).Block(
+		If(
+			Id("err").Op(":=").Qual("os", "MkdirAll").Call(Id("dir"), Id("0700")),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Id("err")),
+		),
+		Return(Op("&").Id(backend).Values(Dict{
+			Id("dir"):       Id("dir"),
+			Id("recovered"): Make(Map(String()).Bool()),
+			Id("lengths"):   Make(Map(String()).Uint64()),
+		}), Nil()),
+	)
+
+	f.Comment("path returns the file of stream, hex encoded as identifiers may contain path separators")
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("path").Params(
+		Id("stream").String(),
+	).String().Block(
+		Return(Qual("path/filepath", "Join").Call(
+			Id("b").Dot("dir"),
+			Qual("encoding/hex", "EncodeToString").Call(Index().Byte().Call(Id("stream"))).Op("+").Lit(".log"),
+		)),
+	)
+
+	if useSnapshots {
+		f.Comment("snapshotPath returns the snapshot file of stream")
+		f.Func().Params(
+			Id("b").Op("*").Id(backend),
+		).Id("snapshotPath").Params(
+			Id("stream").String(),
+		).String().Block(
+			Return(Qual("path/filepath", "Join").Call(
+				Id("b").Dot("dir"),
+				Qual("encoding/hex", "EncodeToString").Call(Index().Byte().Call(Id("stream"))).Op("+").Lit(".snapshot"),
+			)),
+		)
+	}
+
+	f.Comment("recover reads the records of the file at p and truncates a torn or corrupt tail")
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("recover").Params(
+		Id("p").String(),
+	).Params(
+		Index().Id(rec),
+		Error(),
+	).Block(
+		List(Id("data"), Id("err")).Op(":=").Qual("io/ioutil", "ReadFile").Call(Id("p")),
+		If(Qual("os", "IsNotExist").Call(Id("err"))).Block(
+			Id("b").Dot("recovered").Index(Id("p")).Op("=").True(),
+			Id("b").Dot("lengths").Index(Id("p")).Op("=").Lit(0),
+			Return(Nil(), Nil()),
+		),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), Id("err")),
+		),
+		List(Id("records"), Id("n")).Op(":=").Id(decodeEventStoreRecords(typ)).Call(Id("data")),
+		If(Id("n").Op("<").Len(Id("data"))).Block(
+			If(
+				Id("err").Op(":=").Qual("os", "Truncate").Call(Id("p"), Int64().Call(Id("n"))),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Nil(), Id("err")),
+			),
+		),
+		Id("b").Dot("recovered").Index(Id("p")).Op("=").True(),
+		Id("b").Dot("lengths").Index(Id("p")).Op("=").Uint64().Call(Len(Id("records"))),
+		Return(Id("records"), Nil()),
+	)
+
+	f.Commentf("Append implements %s", eventStoreBackend(typ))
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("Append").Params(
+		Id("stream").String(),
+		Id("expectedVersion").Uint64(),
+		Id("records").Index().Id(rec),
+	).Params(
+		Uint64(),
+		Error(),
+	).Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+		Id("p").Op(":=").Id("b").Dot("path").Call(Id("stream")),
+		If(Op("!").Id("b").Dot("recovered").Index(Id("p"))).Block(
+			If(
+				List(Id("_"), Id("err")).Op(":=").Id("b").Dot("recover").Call(Id("p")),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Lit(0), Id("err")),
+			),
+		),
+		If(
+			Id("n").Op(":=").Id("b").Dot("lengths").Index(Id("p")),
+			Id("n").Op("!=").Id("expectedVersion"),
+		).Block(
+			Return(Lit(0), Op("&").Id(versionConflictError).Values(Dict{
+				Id("Stream"):   Id("stream"),
+				Id("Expected"): Id("expectedVersion"),
+				Id("Actual"):   Id("n"),
+			})),
+		),
+		List(Id("_"), Id("statErr")).Op(":=").Qual("os", "Stat").Call(Id("p")),
+		List(Id("file"), Id("err")).Op(":=").Qual("os", "OpenFile").Call(
+			Id("p"),
+			Qual("os", "O_APPEND").Op("|").Qual("os", "O_CREATE").Op("|").Qual("os", "O_WRONLY"),
+			Id("0600"),
+		),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Lit(0), Id("err")),
+		),
+		Comment("a failed write may leave a torn tail, which is truncated on the next access"),
+		Id("b").Dot("recovered").Index(Id("p")).Op("=").False(),
+		If(
+			List(Id("_"), Id("err")).Op(":=").Id("file").Dot("Write").Call(Id(encodeEventStoreRecords(typ)).Call(Id("records"))),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Id("file").Dot("Close").Call(),
+			Return(Lit(0), Id("err")),
+		),
+		If(
+			Id("err").Op(":=").Id("file").Dot("Sync").Call(),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Id("file").Dot("Close").Call(),
+			Return(Lit(0), Id("err")),
+		),
+		If(
+			Id("err").Op(":=").Id("file").Dot("Close").Call(),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Lit(0), Id("err")),
+		),
+		Id("b").Dot("recovered").Index(Id("p")).Op("=").True(),
+		Id("b").Dot("lengths").Index(Id("p")).Op("+=").Uint64().Call(Len(Id("records"))),
+		If(Qual("os", "IsNotExist").Call(Id("statErr"))).Block(
+			Comment("persist the directory entry of a new stream"),
+			If(
+				Id("err").Op(":=").Id("b").Dot("syncDir").Call(),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Lit(0), Id("err")),
+			),
+		),
+		Return(Id("b").Dot("lengths").Index(Id("p")), Nil()),
+	)
+
+	f.Commentf("Read implements %s", eventStoreBackend(typ))
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("Read").Params(
+		Id("stream").String(),
+	).Params(
+		Index().Id(rec),
+		Error(),
+	).Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+		Return(Id("b").Dot("recover").Call(Id("b").Dot("path").Call(Id("stream")))),
+	)
+
+	if useSnapshots {
+		f.Commentf("SaveSnapshot implements %s", eventStoreBackend(typ))
+		f.Comment("the snapshot is encoded as [version][crc32][data], version is 8 and the checksum 4 byte big endian")
+		f.Func().Params(
+			Id("b").Op("*").Id(backend),
+		).Id("SaveSnapshot").Params(
+			Id("stream").String(),
+			Id("version").Uint64(),
+			Id("data").Index().Byte(),
+		).Error().Block(
+			Id("b").Dot("mu").Dot("Lock").Call(),
+			Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+			Id("buf").Op(":=").Make(Index().Byte(), Lit(12).Op("+").Len(Id("data"))),
+			be.Clone().Dot("PutUint64").Call(Id("buf").Index(Lit(0), Empty()), Id("version")),
+			be.Clone().Dot("PutUint32").Call(Id("buf").Index(Lit(8), Empty()), Qual("hash/crc32", "ChecksumIEEE").Call(Id("data"))),
+			Copy(Id("buf").Index(Lit(12), Empty()), Id("data")),
+			List(Id("tmp"), Id("err")).Op(":=").Qual("io/ioutil", "TempFile").Call(Id("b").Dot("dir"), Lit(".tmp-*")),
+			If(Id("err").Op("!=").Nil()).Block(
+				Return(Id("err")),
+			),
+			Defer().Qual("os", "Remove").Call(Id("tmp").Dot("Name").Call()),
+			If(
+				List(Id("_"), Id("err")).Op(":=").Id("tmp").Dot("Write").Call(Id("buf")),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Id("tmp").Dot("Close").Call(),
+				Return(Id("err")),
+			),
+			If(
+				Id("err").Op(":=").Id("tmp").Dot("Sync").Call(),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Id("tmp").Dot("Close").Call(),
+				Return(Id("err")),
+			),
+			If(
+				Id("err").Op(":=").Id("tmp").Dot("Close").Call(),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Id("err")),
+			),
+			If(
+				Id("err").Op(":=").Qual("os", "Rename").Call(Id("tmp").Dot("Name").Call(), Id("b").Dot("snapshotPath").Call(Id("stream"))),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Id("err")),
+			),
+			Return(Id("b").Dot("syncDir").Call()),
+		)
+
+		f.Commentf("LoadSnapshot implements %s", eventStoreBackend(typ))
+		f.Comment("a corrupt snapshot is treated as missing, so that all facts are replayed")
+		f.Func().Params(
+			Id("b").Op("*").Id(backend),
+		).Id("LoadSnapshot").Params(
+			Id("stream").String(),
+		).Params(
+			Uint64(),
+			Index().Byte(),
+			Error(),
+		).Block(
+			Id("b").Dot("mu").Dot("Lock").Call(),
+			Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+			List(Id("buf"), Id("err")).Op(":=").Qual("io/ioutil", "ReadFile").Call(Id("b").Dot("snapshotPath").Call(Id("stream"))),
+			If(Qual("os", "IsNotExist").Call(Id("err"))).Block(
+				Return(Lit(0), Nil(), Nil()),
+			),
+			If(Id("err").Op("!=").Nil()).Block(
+				Return(Lit(0), Nil(), Id("err")),
+			),
+			If(
+				Len(Id("buf")).Op("<").Lit(12).Op("||").
+					Qual("hash/crc32", "ChecksumIEEE").Call(Id("buf").Index(Lit(12), Empty())).Op("!=").Add(be.Clone()).Dot("Uint32").Call(Id("buf").Index(Lit(8), Empty())),
+			).Block(
+				Return(Lit(0), Nil(), Nil()),
+			),
+			Return(be.Clone().Dot("Uint64").Call(Id("buf")), Id("buf").Index(Lit(12), Empty()), Nil()),
+		)
+	}
+
+	f.Comment("syncDir syncs the directory to disk")
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("syncDir").Params().Error().Block(
+		List(Id("d"), Id("err")).Op(":=").Qual("os", "Open").Call(Id("b").Dot("dir")),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Id("err")),
+		),
+		If(
+			Id("err").Op(":=").Id("d").Dot("Sync").Call(),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Id("d").Dot("Close").Call(),
+			Return(Id("err")),
+		),
+		Return(Id("d").Dot("Close").Call()),
+	)
+
+	f.Commentf("%s encodes records as [size][crc32][name length][version][name][payload]", encodeEventStoreRecords(typ))
+	f.Comment("all integers are 4 byte big endian, size and checksum cover the rest of the record")
+	f.Func().Id(encodeEventStoreRecords(typ)).Params(
+		Id("records").Index().Id(rec),
+	).Index().Byte().Block(
+		Var().Id("buf").Index().Byte(),
+		For(List(Id("_"), Id("r")).Op(":=").Range().Id("records")).Block(
+			Id("body").Op(":=").Make(Index().Byte(), Lit(8).Op("+").Len(Id("r").Dot("Name")).Op("+").Len(Id("r").Dot("Payload"))),
+			be.Clone().Dot("PutUint32").Call(Id("body").Index(Lit(0), Empty()), Uint32().Call(Len(Id("r").Dot("Name")))),
+			be.Clone().Dot("PutUint32").Call(Id("body").Index(Lit(4), Empty()), Id("r").Dot("Version")),
+			Copy(Id("body").Index(Lit(8), Empty()), Id("r").Dot("Name")),
+			Copy(Id("body").Index(Lit(8).Op("+").Len(Id("r").Dot("Name")), Empty()), Id("r").Dot("Payload")),
+			Var().Id("head").Index(Lit(8)).Byte(),
+			be.Clone().Dot("PutUint32").Call(Id("head").Index(Lit(0), Empty()), Uint32().Call(Len(Id("body")))),
+			be.Clone().Dot("PutUint32").Call(Id("head").Index(Lit(4), Empty()), Qual("hash/crc32", "ChecksumIEEE").Call(Id("body"))),
+			Id("buf").Op("=").Append(Id("buf"), Id("head").Index(Empty(), Empty()).Op("...")),
+			Id("buf").Op("=").Append(Id("buf"), Id("body").Op("...")),
+		),
+		Return(Id("buf")),
+	)
+
+	f.Commentf("%s decodes records up to the first torn or corrupt one", decodeEventStoreRecords(typ))
+	f.Comment("n is the length of the valid data")
+	f.Func().Id(decodeEventStoreRecords(typ)).Params(
+		Id("data").Index().Byte(),
+	).Params(
+		Id("records").Index().Id(rec),
+		Id("n").Int(),
+	).Block(
+		For(Len(Id("data")).Op("-").Id("n").Op(">=").Lit(8)).Block(
+			Id("size").Op(":=").Int().Call(be.Clone().Dot("Uint32").Call(Id("data").Index(Id("n"), Empty()))),
+			Id("sum").Op(":=").Add(be.Clone()).Dot("Uint32").Call(Id("data").Index(Id("n").Op("+").Lit(4), Empty())),
+			If(Id("size").Op("<").Lit(8).Op("||").Len(Id("data")).Op("-").Id("n").Op("-").Lit(8).Op("<").Id("size")).Block(
+				Break(),
+			),
+			Id("body").Op(":=").Id("data").Index(Id("n").Op("+").Lit(8), Id("n").Op("+").Lit(8).Op("+").Id("size")),
+			If(Qual("hash/crc32", "ChecksumIEEE").Call(Id("body")).Op("!=").Id("sum")).Block(
+				Break(),
+			),
+			Id("nameLen").Op(":=").Int().Call(be.Clone().Dot("Uint32").Call(Id("body"))),
+			If(Id("nameLen").Op(">").Id("size").Op("-").Lit(8)).Block(
+				Break(),
+			),
+			Id("records").Op("=").Append(Id("records"), Id(rec).Values(Dict{
+				Id("Name"):    String().Call(Id("body").Index(Lit(8), Lit(8).Op("+").Id("nameLen"))),
+				Id("Version"): be.Clone().Dot("Uint32").Call(Id("body").Index(Lit(4), Empty())),
+				Id("Payload"): Id("body").Index(Lit(8).Op("+").Id("nameLen"), Empty()),
+			})),
+			Id("n").Op("+=").Lit(8).Op("+").Id("size"),
+		),
+		Return(Id("records"), Id("n")),
+	)
+}
+
+// Composers ...
+
+func GenEventStore(pkgName, typ, appPkg string, entity QualId, useSnapshots bool) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenEventStore))
+	ret.Line()
+	addEventStoreError(ret)
+	addEventStoreBackend(ret, typ, useSnapshots)
+	addEventStoreType(ret, typ, entity)
+	addEventStoreMethods(ret, typ, appPkg, entity, useSnapshots)
+	addMemoryEventStoreBackend(ret, typ, useSnapshots)
+	addFileEventStoreBackend(ret, typ, useSnapshots)
+	ret.Comment("compile time assertions")
+	ret.Var().Defs(
+		Id("_").Qual(appPkg, appgen.StorageWriterReader).Op("=").Parens(Op("*").Id(typ)).Call(Nil()),
+		Id("_").Id(eventStoreBackend(typ)).Op("=").Parens(Op("*").Id(memoryEventStoreBackend(typ))).Call(Nil()),
+		Id("_").Id(eventStoreBackend(typ)).Op("=").Parens(Op("*").Id(fileEventStoreBackend(typ))).Call(Nil()),
+	)
+	return ret
+}
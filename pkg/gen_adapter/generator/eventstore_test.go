@@ -0,0 +1,274 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+// TestGenEventStore generates the event store into a temporary module and runs
+// eventStoreTest against it, the entity is stood in for by eventStoreTestEntity
+func TestGenEventStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a temporary module")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+	sum, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "eventstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const mod = "example.com/eventstore"
+	entity := QualId{Id: "Account", Qual: mod + "/account"}
+	for _, d := range []string{"app", "account", "store"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	storage, _, _ := appgen.GenStorageIface(appgen.QualId(entity), true, appgen.Variants{}, "app")
+	if err := storage.Save(filepath.Join(dir, "app", "storage.go")); err != nil {
+		t.Fatal(err)
+	}
+	store := GenEventStore("store", "EventStore", mod+"/app", entity, false)
+	if err := store.Save(filepath.Join(dir, "store", "eventstore_gen.go")); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":                   eventStoreTestMod,
+		"go.sum":                   string(sum),
+		"app/app.go":               eventStoreTestApp,
+		"account/account.go":       eventStoreTestEntity,
+		"store/eventstore_test.go": eventStoreTest,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "test", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
+
+const eventStoreTestMod = `module example.com/eventstore
+
+go 1.14
+
+require google.golang.org/protobuf v1.25.0
+`
+
+const eventStoreTestApp = `package app
+
+type OffersDistinguishable interface {
+	Identifier() string
+}
+
+type OffersFactKeeper interface {
+	Facts() []interface{}
+}
+`
+
+const eventStoreTestEntity = `package account
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Account records the values of the facts applied to it
+type Account struct {
+	Applied []string
+}
+
+type AccountUpcaster interface{}
+
+func EncodeAccountFact(fact proto.Message) (name string, version uint32, payload []byte, err error) {
+	payload, err = proto.Marshal(fact)
+	return string(fact.ProtoReflect().Descriptor().FullName()), 1, payload, err
+}
+
+func (a *Account) ApplyStored(name string, version uint32, payload []byte, up AccountUpcaster) error {
+	fact := &wrapperspb.StringValue{}
+	if name != string(fact.ProtoReflect().Descriptor().FullName()) {
+		return fmt.Errorf("unknown fact %s", name)
+	}
+	if err := proto.Unmarshal(payload, fact); err != nil {
+		return err
+	}
+	a.Applied = append(a.Applied, fact.GetValue())
+	return nil
+}
+`
+
+const eventStoreTest = `package store
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type target string
+
+func (t target) Identifier() string { return string(t) }
+
+type facts []interface{}
+
+func (f facts) Facts() []interface{} { return f }
+
+func records(payloads ...string) []EventStoreRecord {
+	var rs []EventStoreRecord
+	for _, p := range payloads {
+		rs = append(rs, EventStoreRecord{Name: "fact", Version: 1, Payload: []byte(p)})
+	}
+	return rs
+}
+
+func newFileBackend(t *testing.T, dir string) *FileEventStoreBackend {
+	b, err := NewFileEventStoreBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "streams")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFileBackendRoundTrip(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	b := newFileBackend(t, dir)
+	if v, err := b.Append("eu/1", 0, records("a", "bb")); err != nil || v != 2 {
+		t.Fatalf("got version %d, %v", v, err)
+	}
+	if v, err := b.Append("eu/1", 2, records("")); err != nil || v != 3 {
+		t.Fatalf("got version %d, %v", v, err)
+	}
+	var conflict *VersionConflictError
+	if _, err := b.Append("eu/1", 2, records("c")); !errors.As(err, &conflict) || conflict.Actual != 3 {
+		t.Fatalf("appended to a stream of an unexpected version: %v", err)
+	}
+	want := records("a", "bb", "")
+	for _, b := range []*FileEventStoreBackend{b, newFileBackend(t, dir)} {
+		got, err := b.Read("eu/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got %d records, want %d", len(got), len(want))
+		}
+		for i := range want {
+			if got[i].Name != want[i].Name || got[i].Version != want[i].Version || string(got[i].Payload) != string(want[i].Payload) {
+				t.Errorf("record %d is %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestFileBackendRecovery(t *testing.T) {
+	valid := len(encodeEventStoreRecords(records("a", "bb")))
+	for name, damage := range map[string]func([]byte) []byte{
+		"truncated size":    func(data []byte) []byte { return data[:valid+3] },
+		"truncated payload": func(data []byte) []byte { return data[:len(data)-1] },
+		"corrupt payload":   func(data []byte) []byte { data[len(data)-1] ^= 1; return data },
+		"corrupt size":      func(data []byte) []byte { data[valid] = 0xff; return data },
+		"garbage":           func(data []byte) []byte { return append(data[:valid], 0, 0, 0, 9, 1, 2, 3, 4, 5, 6, 7, 8, 9) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			if _, err := newFileBackend(t, dir).Append("s", 0, records("a", "bb", "ccc")); err != nil {
+				t.Fatal(err)
+			}
+			p := newFileBackend(t, dir).path("s")
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(p, damage(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			b := newFileBackend(t, dir)
+			got, err := b.Read("s")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("recovered %d records, want 2", len(got))
+			}
+			if fi, err := os.Stat(p); err != nil || fi.Size() != int64(valid) {
+				t.Fatalf("tail is not cut back to the last valid record: %v, %v", fi.Size(), err)
+			}
+			if v, err := b.Append("s", 2, records("d")); err != nil || v != 3 {
+				t.Fatalf("got version %d, %v", v, err)
+			}
+			got, err = newFileBackend(t, dir).Read("s")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 3 || string(got[2].Payload) != "d" {
+				t.Fatalf("got %+v after appending to a recovered stream", got)
+			}
+		})
+	}
+}
+
+func TestSaveFactsConflict(t *testing.T) {
+	ctx := context.Background()
+	s := NewEventStore(NewMemoryEventStoreBackend(), nil)
+	fact := func(v string) facts { return facts{wrapperspb.String(v)} }
+	if err := s.SaveFacts(ctx, target("t"), fact("a"), 0); err != nil {
+		t.Fatal(err)
+	}
+	var conflict *VersionConflictError
+	if err := s.SaveFacts(ctx, target("t"), fact("b"), 0); !errors.As(err, &conflict) {
+		t.Fatalf("saved facts onto a stream changed since loading: %v", err)
+	}
+	e, version, err := s.LoadSince(ctx, target("t"), nil, 0)
+	if err != nil || version != 1 {
+		t.Fatalf("got version %d, %v", version, err)
+	}
+	if err := s.SaveFacts(ctx, target("t"), fact("c"), version); err != nil {
+		t.Fatal(err)
+	}
+	if e, err = s.Load(ctx, target("t")); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "c"}; !reflect.DeepEqual(e.Applied, want) {
+		t.Fatalf("loaded %v, want %v", e.Applied, want)
+	}
+}
+`
@@ -0,0 +1,411 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+	domaingen "github.com/xoe-labs/ddd-gen/pkg/gen_domain/generator"
+)
+
+var cmdGenKVStore string = "ddd-gen adapter kv"
+
+func kvStoreBackend(typ string) string     { return typ + "Backend" }
+func fileKVStoreBackend(typ string) string { return "File" + typ + "Backend" }
+func storedEntity(entity string) string    { return "stored" + entity }
+func encodeEntity(entity string) string    { return "encode" + entity }
+func decodeEntity(entity string) string    { return "decode" + entity }
+
+func kvStoreVar(field string) string {
+	return fieldVar(field, "e")
+}
+
+func addKVStoreBackend(f *File, typ string) {
+	f.Commentf("%s knows how to store values by key", kvStoreBackend(typ))
+	f.Type().Id(kvStoreBackend(typ)).Interface(
+		Comment("Get knows how to get the value of key, ok is false if key is not present"),
+		Id("Get").Params(Id("key").String()).Params(Id("value").Index().Byte(), Id("ok").Bool(), Id("err").Error()),
+		Comment("Put knows how to atomically set the value of key"),
+		Id("Put").Params(Id("key").String(), Id("value").Index().Byte()).Error(),
+		Comment("Delete knows how to remove key, it is not an error if key is not present"),
+		Id("Delete").Params(Id("key").String()).Error(),
+		Comment("Iterate knows how to call fn for each key with prefix in ascending order, until fn returns an error"),
+		Id("Iterate").Params(
+			Id("prefix").String(),
+			Id("fn").Func().Params(Id("key").String(), Id("value").Index().Byte()).Error(),
+		).Error(),
+	)
+}
+
+func addKVStoreCodec(f *File, entity QualId, cols []Column) {
+	stored := storedEntity(entity.Id)
+	f.Commentf("%s is the stored form of %s entity, including private fields", stored, entity.Id)
+	f.Type().Id(stored).StructFunc(func(g *Group) {
+		for _, col := range cols {
+			g.Id(strings.Title(col.Field)).Add(col.Typ.Clone()).Tag(map[string]string{"json": col.Field})
+		}
+	})
+
+	log.Printf("%s: generating '%s()'\n", entity.Id, encodeEntity(entity.Id))
+	f.Commentf("%s encodes the full state of %s entity through %s", encodeEntity(entity.Id), entity.Id, domaingen.MarshalToStore)
+	f.Func().Id(encodeEntity(entity.Id)).Params(
+		Id("e").Op("*").Qual(entity.Qual, entity.Id),
+	).Params(
+		Index().Byte(),
+		Error(),
+	).Block(
+		ListFunc(func(g *Group) {
+			for _, col := range cols {
+				g.Id(kvStoreVar(col.Field))
+			}
+		}).Op(":=").Id("e").Dot(domaingen.MarshalToStore).Call(),
+		Return(Qual("encoding/json", "Marshal").Call(
+			Op("&").Id(stored).Values(DictFunc(func(d Dict) {
+				for _, col := range cols {
+					d[Id(strings.Title(col.Field))] = Id(kvStoreVar(col.Field))
+				}
+			})),
+		)),
+	)
+
+	log.Printf("%s: generating '%s()'\n", entity.Id, decodeEntity(entity.Id))
+	f.Commentf("%s decodes %s entity encoded by %s through %s", decodeEntity(entity.Id), entity.Id, encodeEntity(entity.Id), domaingen.UnmarshalFromStore)
+	f.Func().Id(decodeEntity(entity.Id)).Params(
+		Id("data").Index().Byte(),
+	).Params(
+		Op("*").Qual(entity.Qual, entity.Id),
+		Error(),
+	).Block(
+		Var().Id("s").Id(stored),
+		If(
+			Id("err").Op(":=").Qual("encoding/json", "Unmarshal").Call(Id("data"), Op("&").Id("s")),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Id("err")),
+		),
+		Return(
+			Qual(entity.Qual, domaingen.UnmarshalFromStore).CallFunc(func(g *Group) {
+				for _, col := range cols {
+					g.Id("s").Dot(strings.Title(col.Field))
+				}
+			}),
+			Nil(),
+		),
+	)
+}
+
+func addKVStoreType(f *File, typ string, entity QualId) {
+	f.Commentf("%s stores %s entities in a key-value backend, one key per target identifier", typ, entity.Id)
+	f.Commentf("keys are prefixed with \"%s/\", so that several stores may share a backend", ToSnakeCase(entity.Id))
+	f.Type().Id(typ).Struct(
+		Id("kv").Id(kvStoreBackend(typ)),
+	)
+
+	ident := "New" + typ
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns a new %s over backend kv", ident, typ)
+	f.Func().Id(ident).Params(
+		Id("kv").Id(kvStoreBackend(typ)),
+	).Op("*").Id(typ).Block(
+		If(Id("kv").Op("==").Nil()).Block(
+			Id("panic").Call(Lit("no 'kv' provided!")),
+		),
+		Return(Op("&").Id(typ).Values(Dict{
+			Id("kv"): Id("kv"),
+		})),
+	)
+}
+
+func addKVStoreMethods(f *File, typ, appPkg string, entity QualId) {
+	short := cmdShortForm(typ)
+	entityShort := cmdShortForm(entity.Id)
+	prefix := ToSnakeCase(entity.Id) + "/"
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageLoadMethod)
+	f.Commentf("%s loads %s entity of the target's key", appgen.StorageLoadMethod, entity.Id)
+	f.Comment("the entity of an unknown target is returned in its zero state")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.StorageLoadMethod).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+	).Params(
+		Op("*").Qual(entity.Qual, entity.Id),
+		Error(),
+	).Block(
+		List(Id("data"), Id("ok"), Id("err")).Op(":=").Id(short).Dot("kv").Dot("Get").Call(
+			Lit(prefix).Op("+").Id("target").Dot(appgen.DistinguishableMethod).Call(),
+		),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), Id("err")),
+		),
+		If(Op("!").Id("ok")).Block(
+			Return(Op("&").Qual(entity.Qual, entity.Id).Values(), Nil()),
+		),
+		Return(Id(decodeEntity(entity.Id)).Call(Id("data"))),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageSaveMethod)
+	f.Commentf("%s persists %s entity under the target's key", appgen.StorageSaveMethod, entity.Id)
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.StorageSaveMethod).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+		Id(entityShort).Op("*").Qual(entity.Qual, entity.Id),
+	).Error().Block(
+		List(Id("data"), Id("err")).Op(":=").Id(encodeEntity(entity.Id)).Call(Id(entityShort)),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Id("err")),
+		),
+		Return(Id(short).Dot("kv").Dot("Put").Call(
+			Lit(prefix).Op("+").Id("target").Dot(appgen.DistinguishableMethod).Call(),
+			Id("data"),
+		)),
+	)
+
+	f.Commentf("Range calls fn for each stored %s entity in ascending order of the target identifiers, until fn returns an error", entity.Id)
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Range").Params(
+		Id("fn").Func().Params(Id("id").String(), Id(entityShort).Op("*").Qual(entity.Qual, entity.Id)).Error(),
+	).Error().Block(
+		Return(Id(short).Dot("kv").Dot("Iterate").Call(
+			Lit(prefix),
+			Func().Params(Id("key").String(), Id("value").Index().Byte()).Error().Block(
+				List(Id(entityShort), Id("err")).Op(":=").Id(decodeEntity(entity.Id)).Call(Id("value")),
+				If(Id("err").Op("!=").Nil()).Block(
+					Return(Id("err")),
+				),
+				Return(Id("fn").Call(
+					Qual("strings", "TrimPrefix").Call(Id("key"), Lit(prefix)),
+					Id(entityShort),
+				)),
+			),
+		)),
+	)
+}
+
+func addFileKVStoreBackend(f *File, typ string) {
+	backend := fileKVStoreBackend(typ)
+
+	f.Commentf("%s stores each key in its own file in a directory", backend)
+	f.Comment("values are written to a temporary file, synced to disk and renamed over the previous value,")
+	f.Comment("so that a crash leaves either the previous or the new value, but never a partial one")
+	f.Comment("it is safe for concurrent use, but not for concurrent use of the same directory by several processes")
+	f.Type().Id(backend).Struct(
+		Id("mu").Qual("sync", "RWMutex"),
+		Id("dir").String(),
+	)
+
+	ident := "New" + backend
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns a %s storing keys in dir, which is created if needed", ident, backend)
+	f.Comment("temporary files left behind by a crash are removed")
+	f.Func().Id(ident).Params(
+		Id("dir").String(),
+	).Params(
+		Op("*").Id(backend),
+		Error(),
+	).Block(
+		If(
+			Id("err").Op(":=").Qual("os", "MkdirAll").Call(Id("dir"), Id("0700")),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Id("err")),
+		),
+		List(Id("tmps"), Id("err")).Op(":=").Qual("path/filepath", "Glob").Call(
+			Qual("path/filepath", "Join").Call(Id("dir"), Lit(".tmp-*")),
+		),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), Id("err")),
+		),
+		For(List(Id("_"), Id("tmp")).Op(":=").Range().Id("tmps")).Block(
+			If(
+				Id("err").Op(":=").Qual("os", "Remove").Call(Id("tmp")),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Nil(), Id("err")),
+			),
+		),
+		Return(Op("&").Id(backend).Values(Dict{
+			Id("dir"): Id("dir"),
+		}), Nil()),
+	)
+
+	f.Comment("path returns the file of key, hex encoded to preserve the order of keys and as keys may contain path separators")
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("path").Params(
+		Id("key").String(),
+	).String().Block(
+		Return(Qual("path/filepath", "Join").Call(
+			Id("b").Dot("dir"),
+			Qual("encoding/hex", "EncodeToString").Call(Index().Byte().Call(Id("key"))),
+		)),
+	)
+
+	f.Commentf("Get implements %s", kvStoreBackend(typ))
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("Get").Params(
+		Id("key").String(),
+	).Params(
+		Index().Byte(),
+		Bool(),
+		Error(),
+	).Block(
+		Id("b").Dot("mu").Dot("RLock").Call(),
+		Defer().Id("b").Dot("mu").Dot("RUnlock").Call(),
+		List(Id("value"), Id("err")).Op(":=").Qual("io/ioutil", "ReadFile").Call(Id("b").Dot("path").Call(Id("key"))),
+		If(Qual("os", "IsNotExist").Call(Id("err"))).Block(
+			Return(Nil(), False(), Nil()),
+		),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), False(), Id("err")),
+		),
+		Return(Id("value"), True(), Nil()),
+	)
+
+	f.Commentf("Put implements %s", kvStoreBackend(typ))
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("Put").Params(
+		Id("key").String(),
+		Id("value").Index().Byte(),
+	).Error().Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+		List(Id("tmp"), Id("err")).Op(":=").Qual("io/ioutil", "TempFile").Call(Id("b").Dot("dir"), Lit(".tmp-*")),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Id("err")),
+		),
+		Defer().Qual("os", "Remove").Call(Id("tmp").Dot("Name").Call()),
+		If(
+			List(Id("_"), Id("err")).Op(":=").Id("tmp").Dot("Write").Call(Id("value")),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Id("tmp").Dot("Close").Call(),
+			Return(Id("err")),
+		),
+		If(
+			Id("err").Op(":=").Id("tmp").Dot("Sync").Call(),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Id("tmp").Dot("Close").Call(),
+			Return(Id("err")),
+		),
+		If(
+			Id("err").Op(":=").Id("tmp").Dot("Close").Call(),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Id("err")),
+		),
+		If(
+			Id("err").Op(":=").Qual("os", "Rename").Call(Id("tmp").Dot("Name").Call(), Id("b").Dot("path").Call(Id("key"))),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Id("err")),
+		),
+		Return(Id("b").Dot("syncDir").Call()),
+	)
+
+	f.Commentf("Delete implements %s", kvStoreBackend(typ))
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("Delete").Params(
+		Id("key").String(),
+	).Error().Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+		Id("err").Op(":=").Qual("os", "Remove").Call(Id("b").Dot("path").Call(Id("key"))),
+		If(Qual("os", "IsNotExist").Call(Id("err"))).Block(
+			Return(Nil()),
+		),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Id("err")),
+		),
+		Return(Id("b").Dot("syncDir").Call()),
+	)
+
+	f.Commentf("Iterate implements %s", kvStoreBackend(typ))
+	f.Comment("fn must not call other methods of the backend")
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("Iterate").Params(
+		Id("prefix").String(),
+		Id("fn").Func().Params(Id("key").String(), Id("value").Index().Byte()).Error(),
+	).Error().Block(
+		Id("b").Dot("mu").Dot("RLock").Call(),
+		Defer().Id("b").Dot("mu").Dot("RUnlock").Call(),
+		Comment("the files are sorted by name, which preserves the order of the keys"),
+		List(Id("infos"), Id("err")).Op(":=").Qual("io/ioutil", "ReadDir").Call(Id("b").Dot("dir")),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Id("err")),
+		),
+		For(List(Id("_"), Id("info")).Op(":=").Range().Id("infos")).Block(
+			List(Id("k"), Id("err")).Op(":=").Qual("encoding/hex", "DecodeString").Call(Id("info").Dot("Name").Call()),
+			If(Id("err").Op("!=").Nil().Op("||").Id("info").Dot("IsDir").Call()).Block(
+				Continue().Comment("not a key, e.g. a temporary file"),
+			),
+			Id("key").Op(":=").String().Call(Id("k")),
+			If(Op("!").Qual("strings", "HasPrefix").Call(Id("key"), Id("prefix"))).Block(
+				Continue(),
+			),
+			List(Id("value"), Id("err")).Op(":=").Qual("io/ioutil", "ReadFile").Call(Id("b").Dot("path").Call(Id("key"))),
+			If(Id("err").Op("!=").Nil()).Block(
+				Return(Id("err")),
+			),
+			If(
+				Id("err").Op(":=").Id("fn").Call(Id("key"), Id("value")),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Id("err")),
+			),
+		),
+		Return(Nil()),
+	)
+
+	f.Comment("syncDir syncs the directory to disk")
+	f.Func().Params(
+		Id("b").Op("*").Id(backend),
+	).Id("syncDir").Params().Error().Block(
+		List(Id("d"), Id("err")).Op(":=").Qual("os", "Open").Call(Id("b").Dot("dir")),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Id("err")),
+		),
+		If(
+			Id("err").Op(":=").Id("d").Dot("Sync").Call(),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Id("d").Dot("Close").Call(),
+			Return(Id("err")),
+		),
+		Return(Id("d").Dot("Close").Call()),
+	)
+}
+
+// Composers ...
+
+func GenKVStore(pkgName, typ, appPkg string, entity QualId, cols []Column) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenKVStore))
+	ret.Line()
+	addKVStoreBackend(ret, typ)
+	addKVStoreCodec(ret, entity, cols)
+	addKVStoreType(ret, typ, entity)
+	addKVStoreMethods(ret, typ, appPkg, entity)
+	addFileKVStoreBackend(ret, typ)
+	ret.Comment("compile time assertions")
+	ret.Var().Defs(
+		Id("_").Qual(appPkg, appgen.StorageWriterReader).Op("=").Parens(Op("*").Id(typ)).Call(Nil()),
+		Id("_").Id(kvStoreBackend(typ)).Op("=").Parens(Op("*").Id(fileKVStoreBackend(typ))).Call(Nil()),
+	)
+	return ret
+}
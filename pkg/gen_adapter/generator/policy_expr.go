@@ -0,0 +1,113 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+var cmdGenPolicyExpr string = "ddd-gen adapter policy-expr"
+
+// policyExprCases adds a case per policy to the action switch
+// the command payload is asserted to the domain command, if the expression refers to it
+func policyExprCases(g *Group, policies []Policy, domainPkg string) {
+	for _, p := range policies {
+		p := p
+		g.Case(Lit(p.Command)).BlockFunc(func(g *Group) {
+			g.Comment(p.Source)
+			if p.Cmd {
+				g.List(Id("cmd"), Id("ok")).Op(":=").Id("payload").Assert(Qual(domainPkg, p.Command))
+				g.If(Op("!").Id("ok")).Block(
+					Return(False()),
+				)
+			}
+			g.Return(Op(p.Expr))
+		})
+	}
+}
+
+func addPolicyExpr(f *File, typ, appPkg, domainPkg string, entity QualId, policies []Policy, payload bool) {
+	short := cmdShortForm(typ)
+
+	f.Commentf("%s decides on commands by the policy expressions of the config file, compiled at generation time", typ)
+	f.Comment("it denies by default: actors or entities which are nil and commands without a policy expression")
+	f.Commentf("it implements the %s and %s interfaces and is safe for concurrent use", appgen.Policer, appgen.TargetPolicer)
+	f.Type().Id(typ).Struct()
+
+	ident := "New" + typ
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns a %s", ident, typ)
+	f.Func().Id(ident).Params().Op("*").Id(typ).Block(
+		Return(Op("&").Id(typ).Values()),
+	)
+
+	params := func(g *Group, last Code) {
+		g.Id("_").Qual("context", "Context")
+		g.Id("actor").Qual(appPkg, appgen.Authorizable)
+		g.Id("action").String()
+		if payload {
+			g.Id("payload").Interface()
+		}
+		g.Add(last)
+	}
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.PolicerMethod)
+	f.Commentf("%s implements %s", appgen.PolicerMethod, appgen.Policer)
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.PolicerMethod).ParamsFunc(func(g *Group) {
+		params(g, Id("entity").Op("*").Qual(entity.Qual, entity.Id))
+	}).Bool().Block(
+		If(Id("actor").Op("==").Nil().Op("||").Id("entity").Op("==").Nil()).Block(
+			Return(False()),
+		),
+		Switch(Id("action")).BlockFunc(func(g *Group) {
+			policyExprCases(g, policies, domainPkg)
+		}),
+		Return(False()),
+	)
+
+	var targeted []Policy
+	for _, p := range policies {
+		if !p.Entity {
+			targeted = append(targeted, p)
+		}
+	}
+	log.Printf("%s: generating '%s()'\n", typ, appgen.TargetPolicerMethod)
+	f.Commentf("%s implements %s", appgen.TargetPolicerMethod, appgen.TargetPolicer)
+	f.Comment("the entity is not loaded yet: commands whose policy expression refers to the entity are denied")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.TargetPolicerMethod).ParamsFunc(func(g *Group) {
+		params(g, Id("_").Qual(appPkg, appgen.Distinguishable))
+	}).Bool().Block(
+		If(Id("actor").Op("==").Nil()).Block(
+			Return(False()),
+		),
+		Switch(Id("action")).BlockFunc(func(g *Group) {
+			policyExprCases(g, targeted, domainPkg)
+		}),
+		Return(False()),
+	)
+}
+
+// Composers ...
+
+func GenPolicyExpr(pkgName, typ, appPkg, domainPkg string, entity QualId, policies []Policy, payload bool) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenPolicyExpr))
+	ret.Line()
+	addPolicyExpr(ret, typ, appPkg, domainPkg, entity, policies, payload)
+	ret.Comment("compile time assertions")
+	ret.Var().Defs(
+		Id("_").Qual(appPkg, appgen.Policer).Op("=").Parens(Op("*").Id(typ)).Call(Nil()),
+		Id("_").Qual(appPkg, appgen.TargetPolicer).Op("=").Parens(Op("*").Id(typ)).Call(Nil()),
+	)
+	return ret
+}
@@ -0,0 +1,394 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+var cmdGenPolicyRBAC string = "ddd-gen adapter policy-rbac"
+
+const yamlPkg = "gopkg.in/yaml.v2"
+
+// RBACWildcard grants all commands to a role
+const RBACWildcard = "*"
+
+func policyFile(typ string) string { return typ + "File" }
+func policyTest(typ string) string { return typ + "Test" }
+
+func addPolicyRBACFile(f *File, typ string, tenancy bool) {
+	file := policyFile(typ)
+	short := cmdShortForm(file)
+
+	f.Commentf("%s is the yaml policy file of %s: roles grant commands, users are bound to roles", file, typ)
+	f.Type().Id(file).StructFunc(func(g *Group) {
+		g.Commentf("Roles maps roles onto the names of the commands they grant, %q grants all commands", RBACWildcard)
+		g.Id("Roles").Map(String()).Index().String().Tag(map[string]string{"yaml": "roles"})
+		if tenancy {
+			g.Comment("Tenants maps tenants onto their users and those onto their roles")
+			g.Comment("a user is only bound to roles within the tenant of the actor")
+			g.Id("Tenants").Map(String()).Map(String()).Index().String().Tag(map[string]string{"yaml": "tenants"})
+		} else {
+			g.Comment("Users maps users onto their roles")
+			g.Id("Users").Map(String()).Index().String().Tag(map[string]string{"yaml": "users"})
+		}
+	})
+
+	undefined := func(role Code) *Statement {
+		return If(List(Id("_"), Id("ok")).Op(":=").Id(short).Dot("Roles").Index(role), Op("!").Id("ok"))
+	}
+	checkBindings := For(List(Id("user"), Id("roles")).Op(":=").Range().Id(short).Dot("Users")).Block(
+		For(List(Id("_"), Id("role")).Op(":=").Range().Id("roles")).Block(
+			undefined(Id("role")).Block(
+				Return(Nil(), Qual("fmt", "Errorf").Call(Lit("user %s: undefined role '%s'"), Id("user"), Id("role"))),
+			),
+		),
+	)
+	if tenancy {
+		checkBindings = For(List(Id("tenant"), Id("users")).Op(":=").Range().Id(short).Dot("Tenants")).Block(
+			For(List(Id("user"), Id("roles")).Op(":=").Range().Id("users")).Block(
+				For(List(Id("_"), Id("role")).Op(":=").Range().Id("roles")).Block(
+					undefined(Id("role")).Block(
+						Return(Nil(), Qual("fmt", "Errorf").Call(Lit("tenant %s: user %s: undefined role '%s'"), Id("tenant"), Id("user"), Id("role"))),
+					),
+				),
+			),
+		)
+	}
+
+	ident := "Parse" + file
+	log.Printf("%s: generating '%s()'\n", file, ident)
+	f.Commentf("%s parses a %s, unknown keys and users bound to undefined roles are rejected", ident, file)
+	f.Func().Id(ident).Params(
+		Id("b").Index().Byte(),
+	).Params(Op("*").Id(file), Error()).Block(
+		Id(short).Op(":=").Op("&").Id(file).Values(),
+		If(Err().Op(":=").Qual(yamlPkg, "UnmarshalStrict").Call(Id("b"), Id(short)), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Err()),
+		),
+		checkBindings,
+		Return(Id(short), Nil()),
+	)
+
+	f.Comment("grants reports whether any of roles grants action")
+	f.Func().Params(
+		Id(short).Op("*").Id(file),
+	).Id("grants").Params(
+		Id("roles").Index().String(),
+		Id("action").String(),
+	).Bool().Block(
+		For(List(Id("_"), Id("role")).Op(":=").Range().Id("roles")).Block(
+			For(List(Id("_"), Id("granted")).Op(":=").Range().Id(short).Dot("Roles").Index(Id("role"))).Block(
+				If(Id("granted").Op("==").Id("action").Op("||").Id("granted").Op("==").Lit(RBACWildcard)).Block(
+					Return(True()),
+				),
+			),
+		),
+		Return(False()),
+	)
+}
+
+func addPolicyRBAC(f *File, typ string, tenancy bool) {
+	file := policyFile(typ)
+	short := cmdShortForm(typ)
+
+	f.Comment("RoleHolder is an actor which brings its own roles, e.g. mapped from a token claim")
+	f.Comment("its roles are trusted as-is: they must stem from a verified source, such as the claims of a token")
+	f.Comment("verified by 'ddd-gen ports actorauth', never from an actor which a client can assert unverified,")
+	f.Comment("such as one read by default from the X-Actor header of the http port")
+	f.Type().Id("RoleHolder").Interface(
+		Id("Roles").Params().Index().String(),
+	)
+
+	f.Commentf("%s grants commands by the roles of the actor, as declared in a %s", typ, file)
+	f.Comment("it denies by default: actors without roles, unknown roles and commands not granted to a role")
+	f.Commentf("it implements the %s and %s interfaces and is safe for concurrent use", appgen.Policer, appgen.TargetPolicer)
+	f.Type().Id(typ).Struct(
+		Id("file").String(),
+		Id("mu").Qual("sync", "RWMutex"),
+		Id("policy").Op("*").Id(file),
+		Id("modTime").Qual("time", "Time"),
+		Id("size").Int64(),
+	)
+
+	ident := "New" + typ
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns a %s which loads the %s file", ident, typ, file)
+	f.Func().Id(ident).Params(
+		Id("file").String(),
+	).Params(Op("*").Id(typ), Error()).Block(
+		If(Id("file").Op("==").Lit("")).Block(
+			Id("panic").Call(Lit("no 'file' provided!")),
+		),
+		Id(short).Op(":=").Op("&").Id(typ).Values(Dict{
+			Id("file"): Id("file"),
+		}),
+		If(List(Id("_"), Err()).Op(":=").Id(short).Dot("Reload").Call(), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Err()),
+		),
+		Return(Id(short), Nil()),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, "Reload")
+	f.Comment("Reload loads the policy file if it was modified since it was last loaded")
+	f.Comment("an invalid policy file is not loaded, the last valid policy stays in force")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Reload").Params().Params(Id("reloaded").Bool(), Err().Error()).Block(
+		List(Id("info"), Err()).Op(":=").Qual("os", "Stat").Call(Id(short).Dot("file")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(False(), Err()),
+		),
+		Id(short).Dot("mu").Dot("RLock").Call(),
+		Id("unmodified").Op(":=").Id(short).Dot("policy").Op("!=").Nil().Op("&&").
+			Id(short).Dot("modTime").Dot("Equal").Call(Id("info").Dot("ModTime").Call()).Op("&&").
+			Id(short).Dot("size").Op("==").Id("info").Dot("Size").Call(),
+		Id(short).Dot("mu").Dot("RUnlock").Call(),
+		If(Id("unmodified")).Block(
+			Return(False(), Nil()),
+		),
+		List(Id("b"), Err()).Op(":=").Qual("io/ioutil", "ReadFile").Call(Id(short).Dot("file")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(False(), Err()),
+		),
+		List(Id("policy"), Err()).Op(":=").Id("Parse"+file).Call(Id("b")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(False(), Qual("fmt", "Errorf").Call(Lit("%s: %w"), Id(short).Dot("file"), Err())),
+		),
+		Id(short).Dot("mu").Dot("Lock").Call(),
+		Id(short).Dot("policy").Op("=").Id("policy"),
+		Id(short).Dot("modTime").Op("=").Id("info").Dot("ModTime").Call(),
+		Id(short).Dot("size").Op("=").Id("info").Dot("Size").Call(),
+		Id(short).Dot("mu").Dot("Unlock").Call(),
+		Return(True(), Nil()),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, "Watch")
+	f.Comment("Watch reloads the policy file every interval until ctx is done, errors are passed to onErr")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Watch").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("interval").Qual("time", "Duration"),
+		Id("onErr").Func().Params(Error()),
+	).Block(
+		Id("t").Op(":=").Qual("time", "NewTicker").Call(Id("interval")),
+		Defer().Id("t").Dot("Stop").Call(),
+		For().Block(
+			Select().Block(
+				Case(Op("<-").Id("ctx").Dot("Done").Call()).Block(
+					Return(),
+				),
+				Case(Op("<-").Id("t").Dot("C")).Block(
+					If(
+						List(Id("_"), Err()).Op(":=").Id(short).Dot("Reload").Call(),
+						Err().Op("!=").Nil().Op("&&").Id("onErr").Op("!=").Nil(),
+					).Block(
+						Id("onErr").Call(Err()),
+					),
+				),
+			),
+		),
+	)
+
+	bound := Id(short).Dot("policy").Dot("Users").Index(Id("user"))
+	if tenancy {
+		f.Comment("decide reports whether user of tenant, with its own roles, may perform action")
+		bound = Id(short).Dot("policy").Dot("Tenants").Index(Id("tenant")).Index(Id("user"))
+	} else {
+		f.Comment("decide reports whether user, with its own roles, may perform action")
+	}
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("decide").ParamsFunc(func(g *Group) {
+		if tenancy {
+			g.Id("tenant").String()
+		}
+		g.Id("user").String()
+		g.Id("roles").Index().String()
+		g.Id("action").String()
+	}).Bool().Block(
+		Id(short).Dot("mu").Dot("RLock").Call(),
+		Defer().Id(short).Dot("mu").Dot("RUnlock").Call(),
+		If(Id(short).Dot("policy").Op("==").Nil()).Block(
+			Return(False()),
+		),
+		Id("roles").Op("=").Append(
+			Id("roles").Index(Empty(), Len(Id("roles")).Op(":").Len(Id("roles"))),
+			bound.Op("..."),
+		),
+		Return(Id(short).Dot("policy").Dot("grants").Call(Id("roles"), Id("action"))),
+	)
+}
+
+func addPolicyRBACPolicer(f *File, typ, appPkg string, entity QualId, payload, tenancy bool) {
+	short := cmdShortForm(typ)
+
+	f.Comment("allowed resolves the roles of actor and reports whether they grant action")
+	if tenancy {
+		f.Comment("actors which are RoleHolder bring their own roles, roles bound to the user of the actor within its tenant are added")
+	} else {
+		f.Comment("actors which are RoleHolder bring their own roles, roles bound to the user of the actor are added")
+	}
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("allowed").Params(
+		Id("actor").Qual(appPkg, appgen.Authorizable),
+		Id("action").String(),
+	).Bool().Block(
+		If(Id("actor").Op("==").Nil()).Block(
+			Return(False()),
+		),
+		Var().Id("roles").Index().String(),
+		If(List(Id("rh"), Id("ok")).Op(":=").Id("actor").Assert(Id("RoleHolder")), Id("ok")).Block(
+			Id("roles").Op("=").Id("rh").Dot("Roles").Call(),
+		),
+		Return(Id(short).Dot("decide").CallFunc(func(g *Group) {
+			if tenancy {
+				g.Id("actor").Dot(appgen.TenantMethod).Call()
+			}
+			g.Id("actor").Dot(appgen.AuthorizableUserMethod).Call()
+			g.Id("roles")
+			g.Id("action")
+		})),
+	)
+
+	params := func(g *Group, last Code) {
+		g.Id("_").Qual("context", "Context")
+		g.Id("actor").Qual(appPkg, appgen.Authorizable)
+		g.Id("action").String()
+		if payload {
+			g.Id("_").Interface()
+		}
+		g.Id("_").Add(last)
+	}
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.PolicerMethod)
+	f.Commentf("%s implements %s", appgen.PolicerMethod, appgen.Policer)
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.PolicerMethod).ParamsFunc(func(g *Group) {
+		params(g, Op("*").Qual(entity.Qual, entity.Id))
+	}).Bool().Block(
+		Return(Id(short).Dot("allowed").Call(Id("actor"), Id("action"))),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.TargetPolicerMethod)
+	f.Commentf("%s implements %s, roles do not depend on the target", appgen.TargetPolicerMethod, appgen.TargetPolicer)
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.TargetPolicerMethod).ParamsFunc(func(g *Group) {
+		params(g, Qual(appPkg, appgen.Distinguishable))
+	}).Bool().Block(
+		Return(Id(short).Dot("allowed").Call(Id("actor"), Id("action"))),
+	)
+}
+
+func addPolicyRBACHarness(f *File, typ string, tenancy bool) {
+	test := policyTest(typ)
+	short := cmdShortForm(typ)
+
+	if tenancy {
+		f.Commentf("%s is a case of the policy test harness: whether a user of tenant with roles may perform action", test)
+	} else {
+		f.Commentf("%s is a case of the policy test harness: whether a user with roles may perform action", test)
+	}
+	f.Type().Id(test).StructFunc(func(g *Group) {
+		g.Id("Name").String().Tag(map[string]string{"yaml": "name"})
+		if tenancy {
+			g.Id("Tenant").String().Tag(map[string]string{"yaml": "tenant"})
+		}
+		g.Id("User").String().Tag(map[string]string{"yaml": "user"})
+		g.Comment("Roles are the roles the actor brings itself (see RoleHolder)")
+		g.Id("Roles").Index().String().Tag(map[string]string{"yaml": "roles"})
+		g.Id("Action").String().Tag(map[string]string{"yaml": "action"})
+		g.Id("Allow").Bool().Tag(map[string]string{"yaml": "allow"})
+	})
+
+	ident := "Load" + test + "s"
+	log.Printf("%s: generating '%s()'\n", test, ident)
+	f.Commentf("%s reads the cases of the policy test harness from a yaml file", ident)
+	f.Func().Id(ident).Params(
+		Id("file").String(),
+	).Params(Index().Id(test), Error()).Block(
+		List(Id("b"), Err()).Op(":=").Qual("io/ioutil", "ReadFile").Call(Id("file")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), Err()),
+		),
+		Var().Id("tests").Index().Id(test),
+		If(Err().Op(":=").Qual(yamlPkg, "UnmarshalStrict").Call(Id("b"), Op("&").Id("tests")), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Qual("fmt", "Errorf").Call(Lit("%s: %w"), Id("file"), Err())),
+		),
+		Return(Id("tests"), Nil()),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, "Test")
+	f.Comment("Test runs the cases of the policy test harness against the policy in force")
+	f.Comment("it returns an error per failing case, e.g. to be reported with testing.T.Error")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Test").Params(
+		Id("tests").Index().Id(test),
+	).Index().Error().Block(
+		Var().Id("errs").Index().Error(),
+		For(List(Id("i"), Id("t")).Op(":=").Range().Id("tests")).Block(
+			Id("name").Op(":=").Id("t").Dot("Name"),
+			If(Id("name").Op("==").Lit("")).Block(
+				Id("name").Op("=").Qual("fmt", "Sprintf").Call(Lit("#%d"), Id("i")),
+			),
+			If(
+				Id("got").Op(":=").Id(short).Dot("decide").CallFunc(func(g *Group) {
+					if tenancy {
+						g.Id("t").Dot("Tenant")
+					}
+					g.Id("t").Dot("User")
+					g.Id("t").Dot("Roles")
+					g.Id("t").Dot("Action")
+				}),
+				Id("got").Op("!=").Id("t").Dot("Allow"),
+			).Block(
+				Id("errs").Op("=").Append(Id("errs"), Qual("fmt", "Errorf").CallFunc(func(g *Group) {
+					if tenancy {
+						g.Lit("%s: user '%s' of tenant '%s' with roles %v performing %s: allowed %t, want %t")
+						g.Id("name")
+						g.Id("t").Dot("User")
+						g.Id("t").Dot("Tenant")
+					} else {
+						g.Lit("%s: user '%s' with roles %v performing %s: allowed %t, want %t")
+						g.Id("name")
+						g.Id("t").Dot("User")
+					}
+					g.Id("t").Dot("Roles")
+					g.Id("t").Dot("Action")
+					g.Id("got")
+					g.Id("t").Dot("Allow")
+				})),
+			),
+		),
+		Return(Id("errs")),
+	)
+}
+
+// Composers ...
+
+func GenPolicyRBAC(pkgName, typ, appPkg string, entity QualId, payload, tenancy bool) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenPolicyRBAC))
+	ret.Line()
+	addPolicyRBACFile(ret, typ, tenancy)
+	addPolicyRBAC(ret, typ, tenancy)
+	addPolicyRBACPolicer(ret, typ, appPkg, entity, payload, tenancy)
+	addPolicyRBACHarness(ret, typ, tenancy)
+	ret.Comment("compile time assertions")
+	ret.Var().Defs(
+		Id("_").Qual(appPkg, appgen.Policer).Op("=").Parens(Op("*").Id(typ)).Call(Nil()),
+		Id("_").Qual(appPkg, appgen.TargetPolicer).Op("=").Parens(Op("*").Id(typ)).Call(Nil()),
+	)
+	return ret
+}
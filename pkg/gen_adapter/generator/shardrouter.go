@@ -0,0 +1,281 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+var cmdGenShardRouter string = "ddd-gen adapter shardrouter"
+
+const unknownShardError = "UnknownShardError"
+
+func addShardRouterError(f *File, typ string) {
+	errTyp := unknownShardError
+	f.Commentf("%s signals that no storage is configured for the shard of a target", errTyp)
+	f.Type().Id(errTyp).Struct(
+		Id("Shard").String(),
+		Id("Target").String(),
+	)
+
+	f.Comment("Error implements the error interface")
+	f.Func().Params(
+		Id("e").Op("*").Id(errTyp),
+	).Id("Error").Params().String().Block(
+		Return(Qual("fmt", "Sprintf").Call(
+			Lit("unknown shard '%s' for target '%s'"),
+			Id("e").Dot("Shard"),
+			Id("e").Dot("Target"),
+		)),
+	)
+}
+
+func addShardRouterType(f *File, typ, appPkg string) {
+	f.Commentf("%s routes storage calls to the storage of the target's shard", typ)
+	f.Comment("the shard of a target is determined by the configured key function")
+	f.Type().Id(typ).Struct(
+		Id("key").Func().Params(Qual(appPkg, appgen.Distinguishable)).String(),
+		Id("shards").Map(String()).Qual(appPkg, appgen.StorageWriterReader),
+	)
+
+	ident := "New" + typ
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns a %s over shards, selected by key(target)", ident, typ)
+	f.Func().Id(ident).Params(
+		Id("key").Func().Params(Qual(appPkg, appgen.Distinguishable)).String(),
+		Id("shards").Map(String()).Qual(appPkg, appgen.StorageWriterReader),
+	).Op("*").Id(typ).Block(
+		If(Id("key").Op("==").Nil()).Block(
+			Id("panic").Call(Lit("no 'key' provided!")),
+		),
+		If(Len(Id("shards")).Op("==").Lit(0)).Block(
+			Id("panic").Call(Lit("no 'shards' provided!")),
+		),
+		Id("s").Op(":=").Make(Map(String()).Qual(appPkg, appgen.StorageWriterReader), Len(Id("shards"))),
+		For(List(Id("k"), Id("v")).Op(":=").Range().Id("shards")).Block(
+			If(Id("v").Op("==").Nil()).Block(
+				Id("panic").Call(Lit("no storage provided for shard '").Op("+").Id("k").Op("+").Lit("'!")),
+			),
+			Id("s").Index(Id("k")).Op("=").Id("v"),
+		),
+		Return(Op("&").Id(typ).Values(Dict{
+			Id("key"):    Id("key"),
+			Id("shards"): Id("s"),
+		})),
+	)
+
+	keyIdent := typ + "KeyFromIdentifier"
+	log.Printf("%s: generating '%s()'\n", typ, keyIdent)
+	f.Commentf("%s returns a key function which cuts the target identifier before its n-th separator", keyIdent)
+	f.Comment("separators escaped within the components of the identifier (e.g. by protoc-gen-ddd) are skipped")
+	f.Comment("e.g. with sep \"-\" and n 2, \"eu-west-office1-42\" is routed to shard \"eu-west\"")
+	f.Func().Id(keyIdent).Params(
+		List(Id("sep"), Id("esc")).String(),
+		Id("n").Int(),
+	).Func().Params(Qual(appPkg, appgen.Distinguishable)).String().Block(
+		Return(Func().Params(
+			Id("target").Qual(appPkg, appgen.Distinguishable),
+		).String().Block(
+			Id("id").Op(":=").Id("target").Dot(appgen.DistinguishableMethod).Call(),
+			Id("left").Op(":=").Id("n"),
+			For(Id("i").Op(":=").Lit(0), Id("i").Op("<").Len(Id("id")), Empty()).Block(
+				Switch().Block(
+					Case(Id("esc").Op("!=").Lit("").Op("&&").Qual("strings", "HasPrefix").Call(Id("id").Index(Id("i"), Empty()), Id("esc"))).Block(
+						Comment("skip the escaped character"),
+						Id("i").Op("+=").Len(Id("esc")),
+						List(Id("_"), Id("size")).Op(":=").Qual("unicode/utf8", "DecodeRuneInString").Call(Id("id").Index(Id("i"), Empty())),
+						Id("i").Op("+=").Id("size"),
+					),
+					Case(Qual("strings", "HasPrefix").Call(Id("id").Index(Id("i"), Empty()), Id("sep"))).Block(
+						If(Id("left").Op("--"), Id("left").Op("==").Lit(0)).Block(
+							Return(Id("id").Index(Empty(), Id("i"))),
+						),
+						Id("i").Op("+=").Len(Id("sep")),
+					),
+					Default().Block(
+						Id("i").Op("++"),
+					),
+				),
+			),
+			Return(Id("id")),
+		)),
+	)
+}
+
+func addShardRouterMethods(f *File, typ, appPkg string, entity QualId, useFactStorage, useSnapshots bool) {
+	short := cmdShortForm(typ)
+	entityShort := cmdShortForm(entity.Id)
+
+	f.Comment("shard returns the storage of the target's shard")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("shard").Params(
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+	).Params(
+		Qual(appPkg, appgen.StorageWriterReader),
+		Error(),
+	).Block(
+		Id("k").Op(":=").Id(short).Dot("key").Call(Id("target")),
+		List(Id("s"), Id("ok")).Op(":=").Id(short).Dot("shards").Index(Id("k")),
+		If(Op("!").Id("ok")).Block(
+			Return(Nil(), Op("&").Id(unknownShardError).Values(Dict{
+				Id("Shard"):  Id("k"),
+				Id("Target"): Id("target").Dot(appgen.DistinguishableMethod).Call(),
+			})),
+		),
+		Return(Id("s"), Nil()),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageLoadMethod)
+	f.Commentf("%s loads %s entity from the storage of the target's shard", appgen.StorageLoadMethod, entity.Id)
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id(appgen.StorageLoadMethod).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+	).Params(
+		Op("*").Qual(entity.Qual, entity.Id),
+		Error(),
+	).Block(
+		List(Id("s"), Id("err")).Op(":=").Id(short).Dot("shard").Call(Id("target")),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), Id("err")),
+		),
+		Return(Id("s").Dot(appgen.StorageLoadMethod).Call(Id("ctx"), Id("target"))),
+	)
+
+	if useFactStorage {
+		log.Printf("%s: generating '%s()'\n", typ, appgen.StorageLoadSince)
+		f.Commentf("%s replays the facts after a snapshot of %s entity from the storage of the target's shard", appgen.StorageLoadSince, entity.Id)
+		f.Func().Params(
+			Id(short).Op("*").Id(typ),
+		).Id(appgen.StorageLoadSince).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("target").Qual(appPkg, appgen.Distinguishable),
+			Id("snapshot").Op("*").Qual(entity.Qual, entity.Id),
+			Id("snapshotVersion").Uint64(),
+		).Params(
+			Op("*").Qual(entity.Qual, entity.Id),
+			Uint64(),
+			Error(),
+		).Block(
+			List(Id("s"), Id("err")).Op(":=").Id(short).Dot("shard").Call(Id("target")),
+			If(Id("err").Op("!=").Nil()).Block(
+				Return(Nil(), Lit(0), Id("err")),
+			),
+			Return(Id("s").Dot(appgen.StorageLoadSince).Call(Id("ctx"), Id("target"), Id("snapshot"), Id("snapshotVersion"))),
+		)
+
+		log.Printf("%s: generating '%s()'\n", typ, appgen.StorageSaveFactsMethod)
+		f.Commentf("%s persists domain facts to the storage of the target's shard", appgen.StorageSaveFactsMethod)
+		f.Func().Params(
+			Id(short).Op("*").Id(typ),
+		).Id(appgen.StorageSaveFactsMethod).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("target").Qual(appPkg, appgen.Distinguishable),
+			Id("fk").Qual(appPkg, appgen.FactKeeper),
+			Id("expectedVersion").Uint64(),
+		).ParamsFunc(func(g *Group) {
+			if useSnapshots {
+				g.Uint64()
+			}
+			g.Error()
+		}).Block(
+			List(Id("s"), Id("err")).Op(":=").Id(short).Dot("shard").Call(Id("target")),
+			If(Id("err").Op("!=").Nil()).BlockFunc(func(g *Group) {
+				if useSnapshots {
+					g.Return(Lit(0), Id("err"))
+				} else {
+					g.Return(Id("err"))
+				}
+			}),
+			Return(Id("s").Dot(appgen.StorageSaveFactsMethod).Call(Id("ctx"), Id("target"), Id("fk"), Id("expectedVersion"))),
+		)
+	} else {
+		log.Printf("%s: generating '%s()'\n", typ, appgen.StorageSaveMethod)
+		f.Commentf("%s persists %s entity to the storage of the target's shard", appgen.StorageSaveMethod, entity.Id)
+		f.Func().Params(
+			Id(short).Op("*").Id(typ),
+		).Id(appgen.StorageSaveMethod).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("target").Qual(appPkg, appgen.Distinguishable),
+			Id(entityShort).Op("*").Qual(entity.Qual, entity.Id),
+		).Error().Block(
+			List(Id("s"), Id("err")).Op(":=").Id(short).Dot("shard").Call(Id("target")),
+			If(Id("err").Op("!=").Nil()).Block(
+				Return(Id("err")),
+			),
+			Return(Id("s").Dot(appgen.StorageSaveMethod).Call(Id("ctx"), Id("target"), Id(entityShort))),
+		)
+	}
+
+	if useSnapshots {
+		log.Printf("%s: generating '%s()'\n", typ, appgen.StorageLoadSnapshot)
+		f.Commentf("%s loads the latest snapshot of %s entity from the storage of the target's shard", appgen.StorageLoadSnapshot, entity.Id)
+		f.Func().Params(
+			Id(short).Op("*").Id(typ),
+		).Id(appgen.StorageLoadSnapshot).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("target").Qual(appPkg, appgen.Distinguishable),
+		).Params(
+			Op("*").Qual(entity.Qual, entity.Id),
+			Uint64(),
+			Error(),
+		).Block(
+			List(Id("s"), Id("err")).Op(":=").Id(short).Dot("shard").Call(Id("target")),
+			If(Id("err").Op("!=").Nil()).Block(
+				Return(Nil(), Lit(0), Id("err")),
+			),
+			Return(Id("s").Dot(appgen.StorageLoadSnapshot).Call(Id("ctx"), Id("target"))),
+		)
+
+		log.Printf("%s: generating '%s()'\n", typ, appgen.StorageSaveSnapshot)
+		f.Commentf("%s persists a snapshot of %s entity to the storage of the target's shard", appgen.StorageSaveSnapshot, entity.Id)
+		f.Func().Params(
+			Id(short).Op("*").Id(typ),
+		).Id(appgen.StorageSaveSnapshot).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("target").Qual(appPkg, appgen.Distinguishable),
+			Id(entityShort).Op("*").Qual(entity.Qual, entity.Id),
+			Id("version").Uint64(),
+		).Error().Block(
+			List(Id("s"), Id("err")).Op(":=").Id(short).Dot("shard").Call(Id("target")),
+			If(Id("err").Op("!=").Nil()).Block(
+				Return(Id("err")),
+			),
+			Return(Id("s").Dot(appgen.StorageSaveSnapshot).Call(Id("ctx"), Id("target"), Id(entityShort), Id("version"))),
+		)
+	}
+
+	f.Comment("Shards returns the sorted names of the configured shards")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Shards").Params().Index().String().Block(
+		Id("names").Op(":=").Make(Index().String(), Lit(0), Len(Id(short).Dot("shards"))),
+		For(Id("k").Op(":=").Range().Id(short).Dot("shards")).Block(
+			Id("names").Op("=").Append(Id("names"), Id("k")),
+		),
+		Qual("sort", "Strings").Call(Id("names")),
+		Return(Id("names")),
+	)
+}
+
+// Composers ...
+
+func GenShardRouter(pkgName, typ, appPkg string, entity QualId, useFactStorage, useSnapshots bool) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenShardRouter))
+	ret.Line()
+	addShardRouterError(ret, typ)
+	addShardRouterType(ret, typ, appPkg)
+	addShardRouterMethods(ret, typ, appPkg, entity, useFactStorage, useSnapshots)
+	ret.Comment("compile time assertions")
+	ret.Var().Id("_").Qual(appPkg, appgen.StorageWriterReader).Op("=").Parens(Op("*").Id(typ)).Call(Nil())
+	return ret
+}
@@ -0,0 +1,487 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"go/types"
+	"log"
+	"strings"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+	domaingen "github.com/xoe-labs/ddd-gen/pkg/gen_domain/generator"
+)
+
+var cmdGenSQLStore string = "ddd-gen adapter sql"
+
+// SQLDialect describes the column types, placeholders and identifier quoting of a database
+type SQLDialect struct {
+	Text, Bool, Int, Float, Bytes, Time, JSON string
+	Placeholder                               func(n int) string
+	Quote                                     func(ident string) string
+}
+
+// SQLDialects are the supported databases
+// both support upserts with 'ON CONFLICT ... DO UPDATE' (sqlite since 3.24)
+var SQLDialects = map[string]SQLDialect{
+	"postgres": {
+		Text: "TEXT", Bool: "BOOLEAN", Int: "BIGINT", Float: "DOUBLE PRECISION",
+		Bytes: "BYTEA", Time: "TIMESTAMPTZ", JSON: "JSONB",
+		Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		Quote:       quoteSQLIdent,
+	},
+	"sqlite": {
+		Text: "TEXT", Bool: "BOOLEAN", Int: "INTEGER", Float: "REAL",
+		Bytes: "BLOB", Time: "TIMESTAMP", JSON: "TEXT",
+		Placeholder: func(n int) string { return "?" },
+		Quote:       quoteSQLIdent,
+	},
+}
+
+// quoteSQLIdent quotes an identifier the standard way, so that reserved words like user or order can be used
+func quoteSQLIdent(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+// QuoteName quotes each part of a possibly schema qualified name, e.g. public.user
+func (d SQLDialect) QuoteName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = d.Quote(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// KindOf classifies a field of type t
+func KindOf(t types.Type) ColumnKind {
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != nil {
+		if n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Time" {
+			return TimeColumn
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch info := u.Info(); {
+		case info&types.IsString != 0:
+			return TextColumn
+		case info&types.IsBoolean != 0:
+			return BoolColumn
+		case info&types.IsInteger != 0:
+			return IntColumn
+		case info&types.IsFloat != 0:
+			return FloatColumn
+		}
+	case *types.Slice:
+		if b, ok := u.Elem().(*types.Basic); ok && b.Kind() == types.Byte {
+			return BytesColumn
+		}
+	}
+	return JSONColumn
+}
+
+// ColumnType returns the column type of a column kind
+func (d SQLDialect) ColumnType(k ColumnKind) string {
+	return map[ColumnKind]string{
+		TextColumn:  d.Text,
+		BoolColumn:  d.Bool,
+		IntColumn:   d.Int,
+		FloatColumn: d.Float,
+		BytesColumn: d.Bytes,
+		TimeColumn:  d.Time,
+		JSONColumn:  d.JSON,
+	}[k]
+}
+
+// columnSample returns a non-zero value of a column kind for the integration test
+func columnSample(col Column) *Statement {
+	switch col.Kind {
+	case TextColumn:
+		return Lit("test")
+	case BoolColumn:
+		return True()
+	case IntColumn:
+		return Lit(1)
+	case FloatColumn:
+		return Lit(1.5)
+	case BytesColumn:
+		return Index().Byte().Call(Lit("test"))
+	case TimeColumn:
+		return Qual("time", "Unix").Call(Lit(1600000000), Lit(0)).Dot("UTC").Call()
+	}
+	return Op("*").New(col.Typ.Clone())
+}
+
+const sqlStoreKeyColumn = "target_id"
+
+// sqliteDriver registers the sqlite3 driver, which needs cgo
+const sqliteDriver = "github.com/mattn/go-sqlite3"
+
+func sqlStoreDDL(typ string) string    { return typ + "DDL" }
+func sqlStoreSelect(typ string) string { return "select" + typ }
+func sqlStoreUpsert(typ string) string { return "upsert" + typ }
+
+func sqlStoreVar(field string) string {
+	return fieldVar(field, "ctx", "target", "err", "s", "raw")
+}
+
+func addSQLStoreQueries(f *File, typ, entity, table string, d SQLDialect, cols []Column) {
+	var ddl, names, values, updates []string
+	key := d.Quote(sqlStoreKeyColumn)
+	table = d.QuoteName(table)
+	ddl = append(ddl, fmt.Sprintf("\t%s TEXT PRIMARY KEY", key))
+	names = append(names, key)
+	values = append(values, d.Placeholder(1))
+	for i, col := range cols {
+		notNull := " NOT NULL"
+		if col.Null() {
+			notNull = ""
+		}
+		name := d.Quote(col.Name)
+		ddl = append(ddl, fmt.Sprintf("\t%s %s%s", name, col.SQLTyp, notNull))
+		names = append(names, name)
+		values = append(values, d.Placeholder(i+2))
+		updates = append(updates, fmt.Sprintf("%s = excluded.%s", name, name))
+	}
+
+	f.Commentf("%s creates the table of %s entities, if it does not exist", sqlStoreDDL(typ), entity)
+	f.Const().Id(sqlStoreDDL(typ)).Op("=").Id(fmt.Sprintf(
+		"`CREATE TABLE IF NOT EXISTS %s (\n%s\n)`",
+		table,
+		strings.Join(ddl, ",\n"),
+	))
+
+	f.Const().Defs(
+		Id(sqlStoreSelect(typ)).Op("=").Id(fmt.Sprintf(
+			"`SELECT %s FROM %s WHERE %s = %s`",
+			strings.Join(names[1:], ", "),
+			table,
+			key,
+			d.Placeholder(1),
+		)),
+		Id(sqlStoreUpsert(typ)).Op("=").Id(fmt.Sprintf(
+			"`INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s`",
+			table,
+			strings.Join(names, ", "),
+			strings.Join(values, ", "),
+			key,
+			strings.Join(updates, ", "),
+		)),
+	)
+}
+
+func addSQLStoreType(f *File, typ string, entity QualId) {
+	f.Commentf("%s stores %s entities in a table, one row per target identifier", typ, entity.Id)
+	f.Type().Id(typ).Struct(
+		Id("db").Op("*").Qual("database/sql", "DB"),
+	)
+
+	ident := "New" + typ
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s returns a new %s over db", ident, typ)
+	f.Func().Id(ident).Params(
+		Id("db").Op("*").Qual("database/sql", "DB"),
+	).Op("*").Id(typ).Block(
+		If(Id("db").Op("==").Nil()).Block(
+			Id("panic").Call(Lit("no 'db' provided!")),
+		),
+		Return(Op("&").Id(typ).Values(Dict{
+			Id("db"): Id("db"),
+		})),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, "Migrate")
+	f.Commentf("Migrate creates the table of %s, if it does not exist", typ)
+	f.Func().Params(
+		Id("s").Op("*").Id(typ),
+	).Id("Migrate").Params(
+		Id("ctx").Qual("context", "Context"),
+	).Error().Block(
+		List(Id("_"), Id("err")).Op(":=").Id("s").Dot("db").Dot("ExecContext").Call(Id("ctx"), Id(sqlStoreDDL(typ))),
+		Return(Id("err")),
+	)
+}
+
+func addSQLStoreMethods(f *File, typ, appPkg string, entity QualId, cols []Column) {
+	entityShort := cmdShortForm(entity.Id)
+	hasJSON := false
+	for _, col := range cols {
+		hasJSON = hasJSON || col.JSON()
+	}
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageLoadMethod)
+	f.Commentf("%s loads %s entity of the target's row through %s", appgen.StorageLoadMethod, entity.Id, domaingen.UnmarshalFromStore)
+	f.Comment("the entity of an unknown target is returned in its zero state")
+	f.Func().Params(
+		Id("s").Op("*").Id(typ),
+	).Id(appgen.StorageLoadMethod).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+	).Params(
+		Op("*").Qual(entity.Qual, entity.Id),
+		Error(),
+	).BlockFunc(func(g *Group) {
+		g.Var().DefsFunc(func(g *Group) {
+			for _, col := range cols {
+				g.Id(sqlStoreVar(col.Field)).Add(col.Typ.Clone())
+				if col.JSON() {
+					g.Id(sqlStoreVar(col.Field) + "JSON").Index().Byte()
+				}
+			}
+		})
+		g.Id("err").Op(":=").Id("s").Dot("db").Dot("QueryRowContext").Call(
+			Id("ctx"),
+			Id(sqlStoreSelect(typ)),
+			Id("target").Dot(appgen.DistinguishableMethod).Call(),
+		).Dot("Scan").CallFunc(func(g *Group) {
+			for _, col := range cols {
+				if col.JSON() {
+					g.Op("&").Id(sqlStoreVar(col.Field) + "JSON")
+				} else {
+					g.Op("&").Id(sqlStoreVar(col.Field))
+				}
+			}
+		})
+		g.If(Id("err").Op("==").Qual("database/sql", "ErrNoRows")).Block(
+			Return(Op("&").Qual(entity.Qual, entity.Id).Values(), Nil()),
+		)
+		g.If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), Id("err")),
+		)
+		for _, col := range cols {
+			if !col.JSON() {
+				continue
+			}
+			g.If(
+				Id("err").Op(":=").Qual("encoding/json", "Unmarshal").Call(
+					Id(sqlStoreVar(col.Field)+"JSON"),
+					Op("&").Id(sqlStoreVar(col.Field)),
+				),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Nil(), Qual("fmt", "Errorf").Call(Lit(col.Name+": %w"), Id("err"))),
+			)
+		}
+		g.Return(
+			Qual(entity.Qual, domaingen.UnmarshalFromStore).CallFunc(func(g *Group) {
+				for _, col := range cols {
+					g.Id(sqlStoreVar(col.Field))
+				}
+			}),
+			Nil(),
+		)
+	})
+
+	log.Printf("%s: generating '%s()'\n", typ, appgen.StorageSaveMethod)
+	f.Commentf("%s upserts %s entity into the target's row through %s", appgen.StorageSaveMethod, entity.Id, domaingen.MarshalToStore)
+	f.Func().Params(
+		Id("s").Op("*").Id(typ),
+	).Id(appgen.StorageSaveMethod).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("target").Qual(appPkg, appgen.Distinguishable),
+		Id(entityShort).Op("*").Qual(entity.Qual, entity.Id),
+	).Error().BlockFunc(func(g *Group) {
+		g.ListFunc(func(g *Group) {
+			for _, col := range cols {
+				g.Id(sqlStoreVar(col.Field))
+			}
+		}).Op(":=").Id(entityShort).Dot(domaingen.MarshalToStore).Call()
+		for _, col := range cols {
+			if !col.JSON() {
+				continue
+			}
+			g.List(Id(sqlStoreVar(col.Field)+"JSON"), Id("err")).Op(":=").Qual("encoding/json", "Marshal").Call(Id(sqlStoreVar(col.Field)))
+			g.If(Id("err").Op("!=").Nil()).Block(
+				Return(Qual("fmt", "Errorf").Call(Lit(col.Name+": %w"), Id("err"))),
+			)
+		}
+		assign := ":="
+		if hasJSON {
+			assign = "="
+		}
+		g.List(Id("_"), Id("err")).Op(assign).Id("s").Dot("db").Dot("ExecContext").CallFunc(func(g *Group) {
+			g.Id("ctx")
+			g.Id(sqlStoreUpsert(typ))
+			g.Id("target").Dot(appgen.DistinguishableMethod).Call()
+			for _, col := range cols {
+				if col.JSON() {
+					g.String().Call(Id(sqlStoreVar(col.Field) + "JSON"))
+				} else {
+					g.Id(sqlStoreVar(col.Field))
+				}
+			}
+		})
+		g.Return(Id("err"))
+	})
+}
+
+func sqlStoreTestDriver(typ string) string { return "test" + typ + "Driver" }
+func sqlStoreTestDSN(typ string) string    { return "test" + typ + "DSN" }
+func sqlStoreTestEnv(typ string) string    { return strings.ToUpper(ToSnakeCase(typ)) }
+
+func addSQLStoreTest(f *File, typ string, entity QualId, cols []Column) {
+	target := "test" + typ + "Target"
+	fields := "test" + typ + "Fields"
+	sample := "test" + typ + "Entity"
+	hasTime := false
+	for _, col := range cols {
+		hasTime = hasTime || col.Kind == TimeColumn
+	}
+	env := sqlStoreTestEnv(typ)
+
+	f.Commentf("%s and %s name the database of the integration test", sqlStoreTestDriver(typ), sqlStoreTestDSN(typ))
+	f.Var().List(Id(sqlStoreTestDriver(typ)), Id(sqlStoreTestDSN(typ))).Op("=").List(
+		Qual("os", "Getenv").Call(Lit(env+"_DRIVER")),
+		Qual("os", "Getenv").Call(Lit(env+"_DSN")),
+	)
+
+	f.Commentf("%s returns the %s entity the integration test round-trips", sample, entity.Id)
+	f.Commentf("it is built from sample values through %s, which panics if they do not validate", domaingen.UnmarshalFromStore)
+	f.Comment("a test file of this package may replace it, e.g. in an init function")
+	f.Var().Id(sample).Op("=").Func().Params().Op("*").Qual(entity.Qual, entity.Id).Block(
+		Return(Qual(entity.Qual, domaingen.UnmarshalFromStore).CallFunc(func(g *Group) {
+			for _, col := range cols {
+				g.Add(columnSample(col))
+			}
+		})),
+	)
+
+	f.Commentf("%s identifies the rows of the integration test", target)
+	f.Type().Id(target).String()
+
+	f.Func().Params(Id("t").Id(target)).Id(appgen.DistinguishableMethod).Params().String().Block(
+		Return(String().Call(Id("t"))),
+	)
+	f.Func().Params(Id("t").Id(target)).Id(appgen.DistinguishableAsserterMethod).Params().Bool().Block(
+		Return(Id("t").Op("!=").Lit("")),
+	)
+
+	f.Commentf("%s returns the full state of %s entity for comparison", fields, entity.Id)
+	f.Func().Id(fields).Params(
+		Id("e").Op("*").Qual(entity.Qual, entity.Id),
+	).Index().Interface().BlockFunc(func(g *Group) {
+		g.ListFunc(func(g *Group) {
+			for _, col := range cols {
+				g.Id(sqlStoreVar(col.Field))
+			}
+		}).Op(":=").Id("e").Dot(domaingen.MarshalToStore).Call()
+		if hasTime {
+			g.Comment("databases may return times in another location")
+		}
+		g.Return(Index().Interface().ValuesFunc(func(g *Group) {
+			for _, col := range cols {
+				if col.Kind == TimeColumn {
+					g.Id(sqlStoreVar(col.Field)).Dot("UTC").Call()
+				} else {
+					g.Id(sqlStoreVar(col.Field))
+				}
+			}
+		}))
+	})
+
+	ident := "Test" + typ
+	log.Printf("%s: generating '%s()'\n", typ, ident)
+	f.Commentf("%s round-trips %s entity through the database at %s_DSN", ident, entity.Id, env)
+	f.Commentf("the driver named by %s_DRIVER must be registered, e.g. by a blank import in a test file of this package", env)
+	f.Func().Id(ident).Params(
+		Id("t").Op("*").Qual("testing", "T"),
+	).Block(
+		If(Id(sqlStoreTestDriver(typ)).Op("==").Lit("").Op("||").Id(sqlStoreTestDSN(typ)).Op("==").Lit("")).Block(
+			Id("t").Dot("Skip").Call(Lit(env+"_DRIVER and "+env+"_DSN are not set")),
+		),
+		List(Id("db"), Id("err")).Op(":=").Qual("database/sql", "Open").Call(Id(sqlStoreTestDriver(typ)), Id(sqlStoreTestDSN(typ))),
+		If(Id("err").Op("!=").Nil()).Block(
+			Id("t").Dot("Fatal").Call(Id("err")),
+		),
+		Defer().Id("db").Dot("Close").Call(),
+
+		Id("ctx").Op(":=").Qual("context", "Background").Call(),
+		Id("s").Op(":=").Id("New"+typ).Call(Id("db")),
+		If(
+			Id("err").Op(":=").Id("s").Dot("Migrate").Call(Id("ctx")),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Id("t").Dot("Fatal").Call(Id("err")),
+		),
+		Id("target").Op(":=").Id(target).Call(Qual("fmt", "Sprintf").Call(
+			Lit("test-%d"),
+			Qual("time", "Now").Call().Dot("UnixNano").Call(),
+		)),
+		Comment("an unknown target loads without error"),
+		If(
+			List(Id("_"), Id("err")).Op(":=").Id("s").Dot(appgen.StorageLoadMethod).Call(Id("ctx"), Id("target")),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Id("t").Dot("Fatal").Call(Id("err")),
+		),
+		Id("e").Op(":=").Func().Params().Op("*").Qual(entity.Qual, entity.Id).Block(
+			Defer().Func().Params().Block(
+				If(Id("r").Op(":=").Recover(), Id("r").Op("!=").Nil()).Block(
+					Id("t").Dot("Fatalf").Call(Lit("the sample values do not validate, replace "+sample+": %v"), Id("r")),
+				),
+			).Call(),
+			Return(Id(sample).Call()),
+		).Call(),
+		Comment("the first save inserts, the second one updates the row"),
+		For(Id("i").Op(":=").Lit(0), Id("i").Op("<").Lit(2), Id("i").Op("++")).Block(
+			If(
+				Id("err").Op(":=").Id("s").Dot(appgen.StorageSaveMethod).Call(Id("ctx"), Id("target"), Id("e")),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Id("t").Dot("Fatal").Call(Id("err")),
+			),
+			List(Id("got"), Id("err")).Op(":=").Id("s").Dot(appgen.StorageLoadMethod).Call(Id("ctx"), Id("target")),
+			If(Id("err").Op("!=").Nil()).Block(
+				Id("t").Dot("Fatal").Call(Id("err")),
+			),
+			If(Op("!").Qual("reflect", "DeepEqual").Call(Id(fields).Call(Id("e")), Id(fields).Call(Id("got")))).Block(
+				Id("t").Dot("Fatalf").Call(Lit("loaded %v, saved %v"), Id(fields).Call(Id("got")), Id(fields).Call(Id("e"))),
+			),
+		),
+	)
+}
+
+func addSQLStoreSQLiteTest(f *File, typ string) {
+	log.Printf("%s: generating '%s()'\n", typ, "init")
+	f.Comment("runs the integration test against an in-memory sqlite database with 'go test -tags sqlite',")
+	f.Comment("unless the environment names another database")
+	f.Func().Id("init").Params().Block(
+		If(Id(sqlStoreTestDriver(typ)).Op("==").Lit("").Op("&&").Id(sqlStoreTestDSN(typ)).Op("==").Lit("")).Block(
+			List(Id(sqlStoreTestDriver(typ)), Id(sqlStoreTestDSN(typ))).Op("=").List(Lit("sqlite3"), Lit("file::memory:?cache=shared")),
+		),
+	)
+}
+
+// Composers ...
+
+func GenSQLStore(pkgName, typ, appPkg string, entity QualId, table string, d SQLDialect, cols []Column) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenSQLStore))
+	ret.Line()
+	addSQLStoreQueries(ret, typ, entity.Id, table, d, cols)
+	addSQLStoreType(ret, typ, entity)
+	addSQLStoreMethods(ret, typ, appPkg, entity, cols)
+	ret.Comment("compile time assertions")
+	ret.Var().Id("_").Qual(appPkg, appgen.StorageWriterReader).Op("=").Parens(Op("*").Id(typ)).Call(Nil())
+	return ret
+}
+
+func GenSQLStoreTest(pkgName, typ string, entity QualId, cols []Column) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenSQLStore))
+	ret.Line()
+	addSQLStoreTest(ret, typ, entity, cols)
+	return ret
+}
+
+func GenSQLStoreSQLiteTest(pkgName, typ string) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment("+build sqlite")
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenSQLStore))
+	ret.Line()
+	ret.Anon(sqliteDriver)
+	addSQLStoreSQLiteTest(ret, typ)
+	return ret
+}
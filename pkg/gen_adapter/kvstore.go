@@ -0,0 +1,34 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"fmt"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter/generator"
+	domaingen "github.com/xoe-labs/ddd-gen/pkg/gen_domain/generator"
+)
+
+// GenKVStore generates the key-value storage adapter into the current working directory
+func GenKVStore(typ string, conf *Config) error {
+	if conf.Entity.Id == "" {
+		return fmt.Errorf("'entity' is not configured")
+	}
+	cwd, goPackage, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	cols, err := storeColumns(conf.Entity)
+	if err != nil {
+		return err
+	}
+	// the entity is stored JSON encoded, a field which does not survive the round trip would be lost
+	for _, col := range cols {
+		if err := domaingen.JSONRoundTrip(col.Type); err != nil {
+			return fmt.Errorf("%s: field '%s': %w", conf.Entity.Id, col.Field, err)
+		}
+	}
+	gf := generator.GenKVStore(goPackage, typ, conf.App, conf.Entity, cols)
+	return save(gf, genPath(cwd, "kvstore_gen.go"))
+}
@@ -0,0 +1,59 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/dave/jennifer/jen"
+	"golang.org/x/tools/go/packages"
+)
+
+// initMain resolves the package which invokes the generator
+func initMain(typ string) (cwd, goPackage string, err error) {
+	if typ == "" {
+		return "", "", fmt.Errorf("'typ' is empty")
+	}
+
+	// Get the package of the file with go:generate comment
+	goPackage = os.Getenv("GOPACKAGE")
+	cwd, err = os.Getwd()
+	if err != nil {
+		return "", "", err
+	}
+	if goPackage == "" {
+		pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName}, cwd)
+		if err != nil {
+			return "", "", err
+		}
+		goPackage = pkgs[0].Name
+	}
+	log.Printf("Generating adapter in package: %s\n", goPackage)
+	return cwd, goPackage, nil
+}
+
+// save replaces genFile with f
+func save(f *jen.File, genFile string) error {
+	if fileExists(genFile) {
+		if err := os.Remove(genFile); err != nil {
+			return err
+		}
+	}
+	return f.Save(genFile)
+}
+
+func genPath(cwd, name string) string {
+	return path.Join(cwd, name)
+}
+
+func fileExists(filename string) bool {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return !info.IsDir()
+}
@@ -0,0 +1,206 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"log"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter/generator"
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+// PolicyExpr is a policy expression of the config file
+type PolicyExpr struct {
+	Command string // name of the command
+	Allow   string // boolean expression over actor, entity and - with payload - cmd
+}
+
+// loadTypes loads the type information of packages, keyed by import path
+func loadTypes(paths ...string) (map[string]*types.Package, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedTypes}, paths...)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]*types.Package)
+	for _, pkg := range pkgs {
+		if pkg.Types != nil {
+			loaded[pkg.PkgPath] = pkg.Types
+		}
+	}
+	for _, path := range paths {
+		if _, ok := loaded[path]; !ok {
+			return nil, fmt.Errorf("cannot load package %s", path)
+		}
+	}
+	return loaded, nil
+}
+
+// lookupType looks up the type name in pkg
+func lookupType(pkg *types.Package, name string) (types.Type, error) {
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s not found in %s", name, pkg.Path())
+	}
+	return obj.Type(), nil
+}
+
+// callGetters rewrites members of the roots actor, entity and cmd, which are getters
+// (methods without parameters and a single result), into calls: actor.GetUser → actor.GetUser()
+func callGetters(expr ast.Expr, pkg *types.Package) ast.Expr {
+	return astutil.Apply(expr, func(c *astutil.Cursor) bool {
+		sel, ok := c.Node().(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if call, ok := c.Parent().(*ast.CallExpr); ok && call.Fun == sel {
+			return true
+		}
+		root, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		v, ok := pkg.Scope().Lookup(root.Name).(*types.Var)
+		if !ok {
+			return true
+		}
+		obj, _, _ := types.LookupFieldOrMethod(v.Type(), true, pkg, sel.Sel.Name)
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			return true
+		}
+		if sig := fn.Type().(*types.Signature); sig.Params().Len() == 0 && sig.Results().Len() == 1 {
+			c.Replace(&ast.CallExpr{Fun: sel})
+		}
+		return true
+	}, nil).(ast.Expr)
+}
+
+// compilePolicy parses and type checks a policy expression: actor is the authorizable,
+// entity a pointer to the entity and - with payload - cmd the domain command
+func compilePolicy(pe PolicyExpr, actorTyp, entityTyp, cmdTyp types.Type, payload bool) (generator.Policy, error) {
+	policy := generator.Policy{Command: pe.Command, Source: pe.Allow}
+	fset := token.NewFileSet()
+	expr, err := parser.ParseExprFrom(fset, "", pe.Allow, 0)
+	if err != nil {
+		return policy, err
+	}
+
+	pkg := types.NewPackage("policy", "policy")
+	actor := types.NewVar(token.NoPos, pkg, "actor", actorTyp)
+	entity := types.NewVar(token.NoPos, pkg, "entity", types.NewPointer(entityTyp))
+	cmd := types.NewVar(token.NoPos, pkg, "cmd", cmdTyp)
+	pkg.Scope().Insert(actor)
+	pkg.Scope().Insert(entity)
+	if payload {
+		pkg.Scope().Insert(cmd)
+	}
+
+	expr = callGetters(expr, pkg)
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	if err := types.CheckExpr(fset, pkg, token.NoPos, expr, info); err != nil {
+		if !payload {
+			ast.Inspect(expr, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && id.Name == cmd.Name() {
+					err = fmt.Errorf("%w ('cmd' requires the policy payload variant)", err)
+					return false
+				}
+				return true
+			})
+		}
+		return policy, err
+	}
+	if b, ok := info.Types[expr].Type.Underlying().(*types.Basic); !ok || b.Info()&types.IsBoolean == 0 {
+		return policy, fmt.Errorf("expression is of type %s, not bool", info.Types[expr].Type)
+	}
+	for _, obj := range info.Uses {
+		switch obj {
+		case entity:
+			policy.Entity = true
+		case cmd:
+			policy.Cmd = true
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, expr); err != nil {
+		return policy, err
+	}
+	policy.Expr = buf.String()
+	return policy, nil
+}
+
+// compilePolicies compiles the policy expressions of the commands of the domain
+func compilePolicies(exprs []PolicyExpr, appPkg, domainPkg string, entity generator.QualId, payload bool) ([]generator.Policy, error) {
+	pkgs, err := loadTypes(appPkg, domainPkg, entity.Qual)
+	if err != nil {
+		return nil, err
+	}
+	actorTyp, err := lookupType(pkgs[appPkg], appgen.Authorizable)
+	if err != nil {
+		return nil, err
+	}
+	entityTyp, err := lookupType(pkgs[entity.Qual], entity.Id)
+	if err != nil {
+		return nil, err
+	}
+	var policies []generator.Policy
+	seen := make(map[string]bool)
+	for _, pe := range exprs {
+		if pe.Command == "" {
+			return nil, fmt.Errorf("policy without command")
+		}
+		if seen[pe.Command] {
+			return nil, fmt.Errorf("policy %s: duplicate policy", pe.Command)
+		}
+		seen[pe.Command] = true
+		cmdTyp, err := lookupType(pkgs[domainPkg], pe.Command)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: unknown command: %w", pe.Command, err)
+		}
+		log.Printf("%s: policy '%s'\n", pe.Command, pe.Allow)
+		policy, err := compilePolicy(pe, actorTyp, entityTyp, cmdTyp, payload)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", pe.Command, err)
+		}
+		policies = append(policies, policy)
+	}
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("'policies' is not configured")
+	}
+	return policies, nil
+}
+
+// GenPolicyExpr generates the expression based policy adapter into the current working directory
+// payload is set, if the policy adapter receives the command payload
+func GenPolicyExpr(typ, domain string, exprs []PolicyExpr, payload bool, conf *Config) error {
+	if conf.Entity.Id == "" {
+		return fmt.Errorf("'entity' is not configured")
+	}
+	if domain == "" {
+		return fmt.Errorf("'domain' is not configured")
+	}
+	policies, err := compilePolicies(exprs, conf.App, domain, conf.Entity, payload)
+	if err != nil {
+		return err
+	}
+	cwd, goPackage, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	gf := generator.GenPolicyExpr(goPackage, typ, conf.App, domain, conf.Entity, policies, payload)
+	return save(gf, genPath(cwd, "policy_expr_gen.go"))
+}
@@ -0,0 +1,25 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"fmt"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter/generator"
+)
+
+// GenPolicyRBAC generates the role based policy adapter into the current working directory
+// payload is set, if the policy adapter receives the command payload
+// tenancy is set, if actors belong to a tenant: users are then bound to roles per tenant
+func GenPolicyRBAC(typ string, payload, tenancy bool, conf *Config) error {
+	if conf.Entity.Id == "" {
+		return fmt.Errorf("'entity' is not configured")
+	}
+	cwd, goPackage, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	gf := generator.GenPolicyRBAC(goPackage, typ, conf.App, conf.Entity, payload, tenancy)
+	return save(gf, genPath(cwd, "policy_rbac_gen.go"))
+}
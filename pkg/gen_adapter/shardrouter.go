@@ -0,0 +1,26 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"fmt"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter/generator"
+)
+
+// GenShardRouter generates the shard routing storage adapter into the current working directory
+func GenShardRouter(typ string, useFactStorage, useSnapshots bool, conf *Config) error {
+	if conf.Entity.Id == "" {
+		return fmt.Errorf("'entity' is not configured")
+	}
+	if useSnapshots && !useFactStorage {
+		return fmt.Errorf("snapshots require fact-based storage")
+	}
+	cwd, goPackage, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	gf := generator.GenShardRouter(goPackage, typ, conf.App, conf.Entity, useFactStorage, useSnapshots)
+	return save(gf, genPath(cwd, "shardrouter_gen.go"))
+}
@@ -0,0 +1,49 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_adapter
+
+import (
+	"fmt"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_adapter/generator"
+)
+
+// GenSQLStore generates the database/sql storage adapter and its integration test into the current working directory
+// with the sqlite dialect, the integration test runs against an in-memory database under the sqlite build tag
+func GenSQLStore(typ, dialect, table string, conf *Config) error {
+	if conf.Entity.Id == "" {
+		return fmt.Errorf("'entity' is not configured")
+	}
+	d, ok := generator.SQLDialects[dialect]
+	if !ok {
+		return fmt.Errorf("unknown dialect '%s'", dialect)
+	}
+	if table == "" {
+		table = generator.ToSnakeCase(conf.Entity.Id)
+	}
+	cwd, goPackage, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	cols, err := storeColumns(conf.Entity)
+	if err != nil {
+		return err
+	}
+	for i := range cols {
+		cols[i].SQLTyp = d.ColumnType(cols[i].Kind)
+	}
+	gf := generator.GenSQLStore(goPackage, typ, conf.App, conf.Entity, table, d, cols)
+	if err := save(gf, genPath(cwd, "sqlstore_gen.go")); err != nil {
+		return err
+	}
+	gf = generator.GenSQLStoreTest(goPackage, typ, conf.Entity, cols)
+	if err := save(gf, genPath(cwd, "sqlstore_gen_test.go")); err != nil {
+		return err
+	}
+	if dialect != "sqlite" {
+		return nil
+	}
+	gf = generator.GenSQLStoreSQLiteTest(goPackage, typ)
+	return save(gf, genPath(cwd, "sqlstore_sqlite_gen_test.go"))
+}
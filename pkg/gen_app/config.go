@@ -15,6 +15,7 @@ type Config struct {
 	Adapters generator.Adapters
 	Errors   generator.Errors
 	Objects  generator.Objects
+	Variants generator.Variants
 }
 
 func NewConfig(
@@ -0,0 +1,58 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_app
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+// GenErrors generates the error package into the current working directory
+// and returns the config entries that point to its error constructors
+func GenErrors(typ string) (entries map[string]string, err error) {
+	if typ == "" {
+		return nil, fmt.Errorf("'typ' is empty")
+	}
+
+	// Get the package of the file with go:generate comment
+	goPackage := os.Getenv("GOPACKAGE")
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	// determin the fully qualified package path
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName}, cwd)
+	if err != nil {
+		return nil, err
+	}
+	pkgPath := pkgs[0].PkgPath
+	if goPackage == "" {
+		goPackage = pkgs[0].Name
+	}
+	log.Printf("Generating package: %s\n", pkgPath)
+
+	genFile := path.Join(cwd, "errors_gen.go")
+	if fileExists(genFile) {
+		if err := os.Remove(genFile); err != nil {
+			return nil, err
+		}
+	}
+	gf := generator.GenErrors(goPackage, typ)
+	if err := gf.Save(genFile); err != nil {
+		return nil, err
+	}
+
+	entries = make(map[string]string)
+	for _, kind := range generator.ErrorKinds {
+		entries[kind.ConfigKey] = pkgPath + "." + generator.ErrorConstructor(kind)
+	}
+	return entries, nil
+}
@@ -15,6 +15,9 @@ type Adapters struct {
 	StorageR           NamedQualId
 	StorageRW          NamedQualId
 	Policer            NamedQualId
+	TargetPolicer      NamedQualId
+	Auditor            NamedQualId
+	Scheduler          NamedQualId
 	DomServiceAdapters []NamedQualId
 }
 
@@ -38,3 +41,29 @@ type Errors struct {
 	StorageSavingErrorNew        QualId
 	DomainErrorNew               QualId
 }
+
+// Constructor returns the error constructor of an error kind
+func (e Errors) Constructor(kind string) QualId {
+	switch kind {
+	case "Authorization":
+		return e.AuthorizationErrorNew
+	case "TargetIdentification":
+		return e.TargetIdentificationErrorNew
+	case "StorageLoading":
+		return e.StorageLoadingErrorNew
+	case "StorageSaving":
+		return e.StorageSavingErrorNew
+	case "Domain":
+		return e.DomainErrorNew
+	}
+	panic("unknown error kind '" + kind + "'")
+}
+
+// Variants toggle optional features of the generated code
+type Variants struct {
+	UseTenancy       bool // actors and targets belong to a tenant, storage is scoped per tenant
+	UseAudit         bool // every command outcome is recorded by an auditor
+	UsePolicyPayload bool // the policer receives the command payload
+	UseScheduling    bool // commands can be scheduled to be handled later
+	SnapshotEvery    uint // fact-based storage snapshots the entity every n facts (0: never)
+}
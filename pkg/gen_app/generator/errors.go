@@ -0,0 +1,244 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+)
+
+var cmdGenErrors string = "ddd-gen app errors"
+
+// ErrorKind describes a kind of error raised by the command handler wrappers
+type ErrorKind struct {
+	Name      string // name of the kind, e.g. Authorization
+	ConfigKey string // config key of its constructor, e.g. authorizationErrorNew
+	Doc       string // short description used in generated comments
+}
+
+// ErrorKinds are the kinds of errors the command handler wrappers raise
+var ErrorKinds = []ErrorKind{
+	{Name: "Authorization", ConfigKey: "authorizationErrorNew", Doc: "the caller is not authorized"},
+	{Name: "TargetIdentification", ConfigKey: "targetIdentificationErrorNew", Doc: "the target could not be identified"},
+	{Name: "StorageLoading", ConfigKey: "storageLoadingErrorNew", Doc: "the storage failed to load the entity"},
+	{Name: "StorageSaving", ConfigKey: "storageSavingErrorNew", Doc: "the storage failed to save the entity"},
+	{Name: "Domain", ConfigKey: "domainErrorNew", Doc: "the domain failed to handle the command"},
+}
+
+// RetryableErrorKinds are the kinds of errors which may go away when the command is handled again
+var RetryableErrorKinds = []string{"StorageLoading", "StorageSaving"}
+
+// ErrorConstructor returns the constructor identifier of an error kind
+func ErrorConstructor(kind ErrorKind) string {
+	return "New" + kind.Name + "Error"
+}
+
+// ErrorKindConst returns the identifier of the Kind constant of an error kind
+func ErrorKindConst(kind string) string {
+	return "Kind" + kind
+}
+
+func addErrorKind(f *File) {
+	f.Comment("Kind classifies application errors")
+	f.Type().Id("Kind").Int()
+
+	f.Const().DefsFunc(func(g *Group) {
+		g.Commentf("%s classifies errors not raised by the application layer", ErrorKindConst("Unknown"))
+		g.Id(ErrorKindConst("Unknown")).Id("Kind").Op("=").Iota()
+		for _, kind := range ErrorKinds {
+			g.Commentf("%s classifies errors signaling that %s", ErrorKindConst(kind.Name), kind.Doc)
+			g.Id(ErrorKindConst(kind.Name))
+		}
+	})
+
+	f.Comment("String implements the fmt.Stringer interface")
+	f.Func().Params(
+		Id("k").Id("Kind"),
+	).Id("String").Params().String().Block(
+		Switch(Id("k")).BlockFunc(func(g *Group) {
+			for _, kind := range ErrorKinds {
+				g.Case(Id(ErrorKindConst(kind.Name))).Block(
+					Return(Lit(kind.Name)),
+				)
+			}
+			g.Default().Block(
+				Return(Lit("Unknown")),
+			)
+		}),
+	)
+}
+
+func addErrorType(f *File, typ string) {
+	short := cmdShortForm(typ)
+
+	f.Commentf("%s is an application error of a distinct Kind", typ)
+	f.Comment("its code identifies the error, structured fields may carry additional context")
+	f.Type().Id(typ).Struct(
+		Id("kind").Id("Kind"),
+		Id("code").String(),
+		Id("fields").Map(String()).Interface(),
+	)
+
+	f.Comment("Error implements the error interface")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Error").Params().String().Block(
+		If(Len(Id(short).Dot("fields")).Op("==").Lit(0)).Block(
+			Return(Id(short).Dot("code")),
+		),
+		Id("keys").Op(":=").Make(Index().String(), Lit(0), Len(Id(short).Dot("fields"))),
+		For(Id("k").Op(":=").Range().Id(short).Dot("fields")).Block(
+			Id("keys").Op("=").Append(Id("keys"), Id("k")),
+		),
+		Qual("sort", "Strings").Call(Id("keys")),
+		Var().Id("b").Qual("strings", "Builder"),
+		Id("b").Dot("WriteString").Call(Id(short).Dot("code")),
+		For(List(Id("i"), Id("k")).Op(":=").Range().Id("keys")).Block(
+			If(Id("i").Op("==").Lit(0)).Block(
+				Id("b").Dot("WriteString").Call(Lit(" (")),
+			).Else().Block(
+				Id("b").Dot("WriteString").Call(Lit(", ")),
+			),
+			Qual("fmt", "Fprintf").Call(Op("&").Id("b"), Lit("%s=%v"), Id("k"), Id(short).Dot("fields").Index(Id("k"))),
+		),
+		Id("b").Dot("WriteString").Call(Lit(")")),
+		Return(Id("b").Dot("String").Call()),
+	)
+
+	f.Comment("Kind returns the kind of the error")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Kind").Params().Id("Kind").Block(
+		Return(Id(short).Dot("kind")),
+	)
+
+	f.Comment("Code returns the code which identifies the error")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Code").Params().String().Block(
+		Return(Id(short).Dot("code")),
+	)
+
+	f.Commentf("Is reports whether target is a %s of the same kind and code", typ)
+	f.Comment("a target without code (e.g. ErrAuthorization) matches any error of its kind")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Is").Params(
+		Id("target").Error(),
+	).Bool().Block(
+		List(Id("t"), Id("ok")).Op(":=").Id("target").Assert(Op("*").Id(typ)),
+		If(Op("!").Id("ok")).Block(
+			Return(False()),
+		),
+		Return(
+			Id("t").Dot("kind").Op("==").Id(short).Dot("kind").Op("&&").Parens(
+				Id("t").Dot("code").Op("==").Lit("").Op("||").Id("t").Dot("code").Op("==").Id(short).Dot("code"),
+			),
+		),
+	)
+
+	f.Commentf("With returns a copy of %s which carries the structured field key", short)
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("With").Params(
+		Id("key").String(),
+		Id("value").Interface(),
+	).Op("*").Id(typ).Block(
+		Id("fields").Op(":=").Make(Map(String()).Interface(), Len(Id(short).Dot("fields")).Op("+").Lit(1)),
+		For(List(Id("k"), Id("v")).Op(":=").Range().Id(short).Dot("fields")).Block(
+			Id("fields").Index(Id("k")).Op("=").Id("v"),
+		),
+		Id("fields").Index(Id("key")).Op("=").Id("value"),
+		Return(Op("&").Id(typ).Values(Dict{
+			Id("kind"):   Id(short).Dot("kind"),
+			Id("code"):   Id(short).Dot("code"),
+			Id("fields"): Id("fields"),
+		})),
+	)
+
+	f.Comment("Fields returns a copy of the structured fields of the error")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Fields").Params().Map(String()).Interface().Block(
+		Id("fields").Op(":=").Make(Map(String()).Interface(), Len(Id(short).Dot("fields"))),
+		For(List(Id("k"), Id("v")).Op(":=").Range().Id(short).Dot("fields")).Block(
+			Id("fields").Index(Id("k")).Op("=").Id("v"),
+		),
+		Return(Id("fields")),
+	)
+}
+
+func addErrorKindSentinels(f *File, typ string) {
+	f.Var().DefsFunc(func(g *Group) {
+		for _, kind := range ErrorKinds {
+			g.Commentf("Err%s matches any error of %s", kind.Name, ErrorKindConst(kind.Name))
+			g.Id("Err"+kind.Name).Op("=").Op("&").Id(typ).Values(Dict{
+				Id("kind"): Id(ErrorKindConst(kind.Name)),
+			})
+		}
+	})
+}
+
+func addErrorConstructors(f *File, typ string) {
+	for _, kind := range ErrorKinds {
+		ident := ErrorConstructor(kind)
+		log.Printf("%s: generating '%s()'\n", typ, ident)
+
+		f.Commentf("%s returns a %s of %s identified by code", ident, typ, ErrorKindConst(kind.Name))
+		f.Func().Id(ident).Params(
+			Id("code").String(),
+		).Op("*").Id(typ).Block(
+			Return(Op("&").Id(typ).Values(Dict{
+				Id("kind"): Id(ErrorKindConst(kind.Name)),
+				Id("code"): Id("code"),
+			})),
+		)
+	}
+}
+
+func addErrorKindOf(f *File, typ string) {
+	f.Comment("KindOf returns the kind of the first application error found in err")
+	f.Comment("it understands both errwrap wrapping and Unwrap() chains")
+	f.Func().Id("KindOf").Params(
+		Id("err").Error(),
+	).Id("Kind").Block(
+		Id("kind").Op(":=").Id(ErrorKindConst("Unknown")),
+		Qual("github.com/hashicorp/errwrap", "Walk").Call(
+			Id("err"),
+			Func().Params(Id("err").Error()).Block(
+				For(
+					Id("kind").Op("==").Id(ErrorKindConst("Unknown")).Op("&&").Id("err").Op("!=").Nil(),
+				).Block(
+					If(List(Id("e"), Id("ok")).Op(":=").Id("err").Assert(Op("*").Id(typ)), Id("ok")).Block(
+						Id("kind").Op("=").Id("e").Dot("kind"),
+						Return(),
+					),
+					List(Id("u"), Id("ok")).Op(":=").Id("err").Assert(Interface(Id("Unwrap").Params().Error())),
+					If(Op("!").Id("ok")).Block(
+						Return(),
+					),
+					Id("err").Op("=").Id("u").Dot("Unwrap").Call(),
+				),
+			),
+		),
+		Return(Id("kind")),
+	)
+}
+
+// Composers ...
+
+func GenErrors(pkgName, typ string) *File {
+	log.Printf("%s: generating error kinds\n", typ)
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenErrors))
+	ret.Line()
+	addErrorKind(ret)
+	addErrorType(ret, typ)
+	addErrorKindSentinels(ret, typ)
+	addErrorConstructors(ret, typ)
+	addErrorKindOf(ret, typ)
+	return ret
+}
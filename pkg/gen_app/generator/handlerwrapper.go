@@ -13,54 +13,92 @@ var cmdGenCommand string = "ddd-gen app command"
 
 // CommandHandlerWrapper ...
 
+// SentinelError is a sentinel error which the command handler wrapper of a command raises
+type SentinelError struct {
+	Name string // identifier and message, e.g. ErrNotAuthorizedToDoSomething
+	Kind string // name of its error kind, e.g. Authorization
+	Doc  string // what it signals
+}
+
+// crossTenant is the condition under which actor may not act on target: either has an empty tenant
+// (e.g. an unset tenant field) or they belong to different tenants
+func crossTenant() *Statement {
+	return Id("actor").Dot(TenantMethod).Call().Op("==").Lit("").Op("||").
+		Id("actor").Dot(TenantMethod).Call().Op("!=").Id("target").Dot(TenantMethod).Call()
+}
+
+// SentinelErrors returns the sentinel errors of the command handler wrapper of DoSomething
+func SentinelErrors(DoSomething string, assertAuthorization bool, variants Variants) []SentinelError {
+	var errs []SentinelError
+	if variants.UseTenancy {
+		errs = append(errs, SentinelError{
+			Name: "Err" + DoSomething + "CrossTenant",
+			Kind: "Authorization",
+			Doc:  fmt.Sprintf("the caller attempted %s on a target of another tenant, or either has no tenant", DoSomething),
+		})
+	}
+	if assertAuthorization {
+		errs = append(errs, SentinelError{
+			Name: "ErrNotAuthorizedTo" + DoSomething,
+			Kind: "Authorization",
+			Doc:  fmt.Sprintf("the caller is not authorized to perform %s", DoSomething),
+		})
+	}
+	errs = append(errs,
+		SentinelError{
+			Name: "Err" + DoSomething + "HasNoTarget",
+			Kind: "TargetIdentification",
+			Doc:  fmt.Sprintf("%s's target was not distinguishable", DoSomething),
+		},
+		SentinelError{
+			Name: "Err" + DoSomething + "LoadingFailed",
+			Kind: "StorageLoading",
+			Doc:  fmt.Sprintf("%s storage failed to load the entity", DoSomething),
+		},
+		SentinelError{
+			Name: "Err" + DoSomething + "SavingFailed",
+			Kind: "StorageSaving",
+			Doc:  fmt.Sprintf("%s failed to save the entity", DoSomething),
+		},
+	)
+	if variants.UseScheduling {
+		errs = append(errs, SentinelError{
+			Name: "Err" + DoSomething + "SchedulingFailed",
+			Kind: "StorageSaving",
+			Doc:  fmt.Sprintf("%s failed to be scheduled", DoSomething),
+		})
+	}
+	return append(errs, SentinelError{
+		Name: "Err" + DoSomething + "FailedInDomain",
+		Kind: "Domain",
+		Doc:  fmt.Sprintf("%s failed in the domain layer", DoSomething),
+	})
+}
+
 func addCommandHandlerWrapperErrors(f *File,
 	DoSomething string,
 	assertAuthorization bool,
+	variants Variants,
 	errors Errors) {
 	f.Null().Var().DefsFunc(func(g *Group) {
-		if assertAuthorization {
-			g.Commentf("ErrNotAuthorizedTo%s signals that the caller is not authorized to perform %s", DoSomething, DoSomething)
-			g.Id("ErrNotAuthorizedTo"+DoSomething).Op("=").Qual(
-				errors.AuthorizationErrorNew.Qual,
-				errors.AuthorizationErrorNew.Id,
+		for _, se := range SentinelErrors(DoSomething, assertAuthorization, variants) {
+			newErr := errors.Constructor(se.Kind)
+			g.Commentf("%s signals that %s", se.Name, se.Doc)
+			g.Id(se.Name).Op("=").Qual(
+				newErr.Qual,
+				newErr.Id,
 			).Call(
-				Lit("ErrNotAuthorizedTo" + DoSomething),
+				Lit(se.Name),
 			)
 		}
-		g.Commentf("Err%sHasNoTarget signals that %s's target was not distinguishable", DoSomething, DoSomething)
-		g.Id("Err"+DoSomething+"HasNoTarget").Op("=").Qual(
-			errors.TargetIdentificationErrorNew.Qual,
-			errors.TargetIdentificationErrorNew.Id,
-		).Call(
-			Lit("Err" + DoSomething + "HasNoTarget"),
-		)
-		g.Commentf("Err%sLoadingFailed signals that %s storage failed to load the entity", DoSomething, DoSomething)
-		g.Id("Err"+DoSomething+"LoadingFailed").Op("=").Qual(
-			errors.StorageLoadingErrorNew.Qual,
-			errors.StorageLoadingErrorNew.Id,
-		).Call(
-			Lit("Err" + DoSomething + "LoadingFailed"),
-		)
-		g.Commentf("Err%sSavingFailed signals that %s failed to save the entity", DoSomething, DoSomething)
-		g.Id("Err"+DoSomething+"SavingFailed").Op("=").Qual(
-			errors.StorageSavingErrorNew.Qual,
-			errors.StorageSavingErrorNew.Id,
-		).Call(
-			Lit("Err" + DoSomething + "SavingFailed"),
-		)
-		g.Commentf("Err%sFailedInDomain signals that %s failed in the domain layer", DoSomething, DoSomething)
-		g.Id("Err"+DoSomething+"FailedInDomain").Op("=").Qual(
-			errors.DomainErrorNew.Qual,
-			errors.DomainErrorNew.Id,
-		).Call(
-			Lit("Err" + DoSomething + "FailedInDomain"),
-		)
 	})
 }
 
 func addCommandHandlerWrapperType(f *File,
 	DoSomething string,
-	assertAuthorization bool,
+	assertAuthorization,
+	authorizeBeforeLoad bool,
+	variants Variants,
 	adapters Adapters) {
 	f.Commentf("%sHandlerWrapper knows how to perform %s", DoSomething, DoSomething)
 	f.Null().Type().Id(
@@ -68,7 +106,14 @@ func addCommandHandlerWrapperType(f *File,
 	).StructFunc(func(g *Group) {
 		g.Id(adapters.StorageRW.Name).Qual(adapters.StorageRW.Qual, adapters.StorageRW.Id)
 		if assertAuthorization {
-			g.Id(adapters.Policer.Name).Qual(adapters.Policer.Qual, adapters.Policer.Id)
+			p := policer(authorizeBeforeLoad, adapters)
+			g.Id(p.Name).Qual(p.Qual, p.Id)
+		}
+		if variants.UseAudit {
+			g.Id(adapters.Auditor.Name).Qual(adapters.Auditor.Qual, adapters.Auditor.Id)
+		}
+		if variants.UseScheduling {
+			g.Id(adapters.Scheduler.Name).Qual(adapters.Scheduler.Qual, adapters.Scheduler.Id)
 		}
 		for _, a := range adapters.DomServiceAdapters {
 			g.Id(a.Name).Qual(a.Qual, a.Id)
@@ -77,11 +122,19 @@ func addCommandHandlerWrapperType(f *File,
 }
 func addCommandHandlerWrapperConstructor(f *File,
 	DoSomething string,
-	assertAuthorization bool,
+	assertAuthorization,
+	authorizeBeforeLoad bool,
+	variants Variants,
 	adapters Adapters) {
 	usedAdapters := append(adapters.DomServiceAdapters, adapters.StorageRW)
 	if assertAuthorization {
-		usedAdapters = append(usedAdapters, adapters.Policer)
+		usedAdapters = append(usedAdapters, policer(authorizeBeforeLoad, adapters))
+	}
+	if variants.UseAudit {
+		usedAdapters = append(usedAdapters, adapters.Auditor)
+	}
+	if variants.UseScheduling {
+		usedAdapters = append(usedAdapters, adapters.Scheduler)
 	}
 	f.Commentf("New%sHandlerWrapper returns %sHandlerWrapper", DoSomething, DoSomething)
 	f.Func().Id(
@@ -117,12 +170,30 @@ func addCommandHandlerWrapperConstructor(f *File,
 }
 
 func addCommandFuncHandle(f *File,
-	DoSomething string,
+	DoSomething,
+	topic string,
 	assertAuthorization,
+	authorizeBeforeLoad,
 	useFactStorage bool,
+	variants Variants,
 	objects Objects,
 	adapters Adapters) {
 	entityShort := cmdShortForm(objects.Entity.Id)
+	storage := func() *Statement { return Id("h").Dot(adapters.StorageRW.Name) }
+	if variants.UseTenancy {
+		storage = func() *Statement { return Id("rw") }
+	}
+	// exit returns from Handle, recording the outcome when auditing
+	exit := func(outcome string, ret *Statement) []Code {
+		if !variants.UseAudit {
+			return []Code{ret}
+		}
+		return []Code{Id("outcome").Op("=").Qual(adapters.Auditor.Qual, outcome), ret}
+	}
+	results := List(Id("error"))
+	if variants.UseAudit {
+		results = List(Id("err").Id("error"))
+	}
 	f.Commentf("Handle generically performs %s", DoSomething)
 	f.Func().Params(
 		Id("h").Id(DoSomething+"HandlerWrapper"),
@@ -134,60 +205,175 @@ func addCommandFuncHandle(f *File,
 		Id("actor").Qual(objects.Actor.Qual, objects.Actor.Id),
 		Id("target").Qual(objects.Target.Qual, objects.Target.Id),
 	).Parens(
-		List(
-			Id("error"),
-		),
+		results,
 	).BlockFunc(func(g *Group) {
+		if variants.UseAudit {
+			g.Comment("audit every exit path with the error returned to the caller")
+			g.Comment("outcome remains aborted if a panic unwinds Handle")
+			g.Id("outcome").Op(":=").Qual(adapters.Auditor.Qual, "AuditOutcomeAborted")
+			g.Defer().Func().Params().BlockFunc(func(g *Group) {
+				g.Id("h").Dot("audit").Call(
+					Id("ctx"),
+					Id("target"),
+					Qual(adapters.Auditor.Qual, AuditRecord).Values(DictFunc(func(d Dict) {
+						d[Id("Actor")] = Id("actor")
+						d[Id("Command")] = Lit(DoSomething)
+						d[Id("Topic")] = Lit(topic)
+						if useFactStorage {
+							d[Id("Facts")] = Id(cmdShortForm(DoSomething)).Dot(FactKeeperMethod).Call()
+						}
+						d[Id("Outcome")] = Id("outcome")
+						d[Id("Err")] = Id("err")
+						d[Id("Time")] = Qual("time", "Now").Call()
+					})),
+				)
+			}).Call()
+		}
+
 		g.Comment("assert that target is distinguishable")
 		g.If(
 			Op("!").Id("target").Dot(DistinguishableAsserterMethod).Call(),
 		).Block(
-			Return().Id(
-				"Err" + DoSomething + "HasNoTarget",
-			),
+			exit("AuditOutcomeInvalidTarget", Return().Id(
+				"Err"+DoSomething+"HasNoTarget",
+			))...,
 		)
 
-		g.Comment("load entity from store; handle + wrap error")
-		g.List(
-			Id(entityShort),
-			Id("loadErr"),
-		).Op(":=").Id("h").Dot(adapters.StorageRW.Name).Dot(
-			StorageLoadMethod,
-		).Call(
-			Id("ctx"),
-			Id("target"),
-		)
+		if variants.UseTenancy {
+			g.Comment("assert that actor and target belong to the same tenant, an empty tenant belongs to none")
+			g.If(
+				crossTenant(),
+			).Block(
+				exit("AuditOutcomeDenied", Return().Id(
+					"Err"+DoSomething+"CrossTenant",
+				))...,
+			)
+			g.Comment("scope storage to the tenant of the target")
+			g.Id("rw").Op(":=").Id("h").Dot(adapters.StorageRW.Name).Dot(
+				StorageForTenantMethod,
+			).Call(
+				Id("target").Dot(TenantMethod).Call(),
+			)
+		}
+
+		if assertAuthorization && authorizeBeforeLoad {
+			g.Comment("assert authorization on the target via policy interface, before paying for a load")
+			g.If(
+				Id(
+					"ok",
+				).Op(":=").Id("h").Dot(adapters.TargetPolicer.Name).Dot(
+					TargetPolicerMethod,
+				).CallFunc(func(g *Group) {
+					g.Id("ctx")
+					g.Id("actor")
+					g.Lit(DoSomething)
+					if variants.UsePolicyPayload {
+						g.Id(cmdShortForm(DoSomething))
+					}
+					g.Id("target")
+				}),
+				Op("!").Id("ok"),
+			).Block(
+				append([]Code{
+					Comment("return opaque error: handle potentially sensitive policy errors out-of-band!"),
+				}, exit("AuditOutcomeDenied", Return().Id(
+					"ErrNotAuthorizedTo"+DoSomething,
+				))...)...,
+			)
+		}
+
+		useSnapshots := useFactStorage && variants.SnapshotEvery > 0
+		if useSnapshots {
+			g.Comment("load entity from its latest snapshot, replaying only later facts; handle + wrap error")
+			g.Comment("a snapshot is an optimization: failing to load one replays all facts")
+			g.List(
+				Id("snapshot"),
+				Id("snapshotVersion"),
+				Id("snapshotErr"),
+			).Op(":=").Add(storage()).Dot(
+				StorageLoadSnapshot,
+			).Call(
+				Id("ctx"),
+				Id("target"),
+			)
+			g.If(
+				Id("snapshotErr").Op("!=").Id("nil"),
+			).Block(
+				List(Id("snapshot"), Id("snapshotVersion")).Op("=").List(Nil(), Lit(0)),
+			)
+			g.List(
+				Id(entityShort),
+				Id("loadedVersion"),
+				Id("loadErr"),
+			).Op(":=").Add(storage()).Dot(
+				StorageLoadSince,
+			).Call(
+				Id("ctx"),
+				Id("target"),
+				Id("snapshot"),
+				Id("snapshotVersion"),
+			)
+		} else if useFactStorage {
+			g.Comment("load entity from store, remembering its version to detect concurrent facts; handle + wrap error")
+			g.List(
+				Id(entityShort),
+				Id("loadedVersion"),
+				Id("loadErr"),
+			).Op(":=").Add(storage()).Dot(
+				StorageLoadSince,
+			).Call(
+				Id("ctx"),
+				Id("target"),
+				Nil(),
+				Lit(0),
+			)
+		} else {
+			g.Comment("load entity from store; handle + wrap error")
+			g.List(
+				Id(entityShort),
+				Id("loadErr"),
+			).Op(":=").Add(storage()).Dot(
+				StorageLoadMethod,
+			).Call(
+				Id("ctx"),
+				Id("target"),
+			)
+		}
 		g.If(
 			Id("loadErr").Op("!=").Id("nil"),
 		).Block(
-			Return().Qual(
+			exit("AuditOutcomeStorageFailure", Return().Qual(
 				"github.com/hashicorp/errwrap",
 				"Wrap",
 			).Call(
 				Id("Err"+DoSomething+"LoadingFailed"),
 				Id("loadErr"),
-			),
+			))...,
 		)
 
-		if assertAuthorization {
+		if assertAuthorization && !authorizeBeforeLoad {
 			g.Comment("assert authorization via policy interface")
 			g.If(
 				Id(
 					"ok",
 				).Op(":=").Id("h").Dot(adapters.Policer.Name).Dot(
 					PolicerMethod,
-				).Call(
-					Id("ctx"),
-					Id("actor"),
-					Lit(DoSomething),
-					Id(entityShort),
-				),
+				).CallFunc(func(g *Group) {
+					g.Id("ctx")
+					g.Id("actor")
+					g.Lit(DoSomething)
+					if variants.UsePolicyPayload {
+						g.Id(cmdShortForm(DoSomething))
+					}
+					g.Id(entityShort)
+				}),
 				Op("!").Id("ok"),
 			).Block(
-				Comment("return opaque error: handle potentially sensitive policy errors out-of-band!"),
-				Return().Id(
+				append([]Code{
+					Comment("return opaque error: handle potentially sensitive policy errors out-of-band!"),
+				}, exit("AuditOutcomeDenied", Return().Id(
 					"ErrNotAuthorizedTo"+DoSomething,
-				),
+				))...)...,
 			)
 		}
 
@@ -205,9 +391,9 @@ func addCommandFuncHandle(f *File,
 				}
 			}),
 			Op("!").Id("ok"),
-		).Block(
-			Var().Id("domErr").Id("error"),
-			For(
+		).BlockFunc(func(g *Group) {
+			g.Var().Id("domErr").Id("error")
+			g.For(
 				List(
 					Id("i"),
 					Id("e"),
@@ -228,17 +414,22 @@ func addCommandFuncHandle(f *File,
 						Id("e"),
 					),
 				),
-			),
-			Return().Id(
+			)
+			for _, c := range exit("AuditOutcomeDomainFailure", Return().Id(
 				"Err"+DoSomething+"FailedInDomain",
-			),
-		)
+			)) {
+				g.Add(c)
+			}
+		})
 
 		if useFactStorage { // a event sourcing storage
-			g.Comment("save domain facts to storage")
-			g.Id(
-				"saveErr",
-			).Op(":=").Id("h").Dot(adapters.StorageRW.Name).Dot(
+			g.Comment("save domain facts to storage, failing if facts of a concurrent command were persisted since loading")
+			g.ListFunc(func(g *Group) {
+				if useSnapshots {
+					g.Id("savedVersion")
+				}
+				g.Id("saveErr")
+			}).Op(":=").Add(storage()).Dot(
 				StorageSaveFactsMethod,
 			).Call(
 				Id("ctx"),
@@ -251,23 +442,24 @@ func addCommandFuncHandle(f *File,
 						cmdShortForm(DoSomething),
 					),
 				),
+				Id("loadedVersion"),
 			)
 			g.If(
 				Id("saveErr").Op("!=").Id("nil"),
 			).Block(
-				Return().Qual(
+				exit("AuditOutcomeStorageFailure", Return().Qual(
 					"github.com/hashicorp/errwrap",
 					"Wrap",
 				).Call(
 					Id("Err"+DoSomething+"SavingFailed"),
 					Id("saveErr"),
-				),
+				))...,
 			)
 		} else { // a modelStorage
 			g.Comment("save entity to storage")
 			g.Id(
 				"saveErr",
-			).Op(":=").Id("h").Dot(adapters.StorageRW.Name).Dot(
+			).Op(":=").Add(storage()).Dot(
 				StorageSaveMethod,
 			).Call(
 				Id("ctx"),
@@ -277,18 +469,121 @@ func addCommandFuncHandle(f *File,
 			g.If(
 				Id("saveErr").Op("!=").Id("nil"),
 			).Block(
-				Return().Qual(
+				exit("AuditOutcomeStorageFailure", Return().Qual(
 					"github.com/hashicorp/errwrap",
 					"Wrap",
 				).Call(
 					Id("Err"+DoSomething+"SavingFailed"),
 					Id("saveErr"),
+				))...,
+			)
+		}
+		if useSnapshots {
+			g.Commentf("snapshot every %d facts", variants.SnapshotEvery)
+			g.Commentf("a snapshot is an optimization: failing to take one does not fail %s", DoSomething)
+			g.If(
+				Id("savedVersion").Op("/").Lit(int(variants.SnapshotEvery)).Op("!=").
+					Id("loadedVersion").Op("/").Lit(int(variants.SnapshotEvery)),
+			).Block(
+				Id("_").Op("=").Add(storage()).Dot(StorageSaveSnapshot).Call(
+					Id("ctx"),
+					Id("target"),
+					Id(entityShort),
+					Id("savedVersion"),
 				),
 			)
 		}
-		g.Return().Id("nil")
+		for _, c := range exit("AuditOutcomeSuccess", Return().Id("nil")) {
+			g.Add(c)
+		}
 
 	})
+	if variants.UseAudit {
+		f.Commentf("audit records the outcome of %s; a failing auditor cannot alter the result", DoSomething)
+		f.Func().Params(
+			Id("h").Id(DoSomething+"HandlerWrapper"),
+		).Id(
+			"audit",
+		).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("target").Qual(objects.Target.Qual, objects.Target.Id),
+			Id("r").Qual(adapters.Auditor.Qual, AuditRecord),
+		).Block(
+			Defer().Func().Params().Block(
+				Id("_").Op("=").Recover(),
+			).Call(),
+			Id("r").Dot("Target").Op("=").Id("target").Dot(DistinguishableMethod).Call(),
+			Id("h").Dot(adapters.Auditor.Name).Dot(AuditMethod).Call(
+				Id("ctx"),
+				Id("r"),
+			),
+		)
+	}
+}
+
+func addCommandFuncSchedule(f *File,
+	DoSomething string,
+	variants Variants,
+	objects Objects,
+	adapters Adapters) {
+	f.Commentf("Schedule%s schedules %s to be handled at a later point in time", DoSomething, DoSomething)
+	f.Comment("authorization is asserted when the command is handled, not when it is scheduled")
+	f.Func().Params(
+		Id("h").Id(DoSomething+"HandlerWrapper"),
+	).Id(
+		"Schedule"+DoSomething,
+	).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("at").Qual("time", "Time"),
+		Id(cmdShortForm(DoSomething)).Qual(objects.Domain.Qual, DoSomething),
+		Id("actor").Qual(objects.Actor.Qual, objects.Actor.Id),
+		Id("target").Qual(objects.Target.Qual, objects.Target.Id),
+	).Parens(
+		List(
+			Id("error"),
+		),
+	).BlockFunc(func(g *Group) {
+		g.Comment("assert that target is distinguishable")
+		g.If(
+			Op("!").Id("target").Dot(DistinguishableAsserterMethod).Call(),
+		).Block(
+			Return().Id(
+				"Err" + DoSomething + "HasNoTarget",
+			),
+		)
+		if variants.UseTenancy {
+			g.Comment("assert that actor and target belong to the same tenant, an empty tenant belongs to none")
+			g.If(
+				crossTenant(),
+			).Block(
+				Return().Id(
+					"Err" + DoSomething + "CrossTenant",
+				),
+			)
+		}
+		g.Comment("persist the command with the scheduler; handle + wrap error")
+		g.If(
+			Id("err").Op(":=").Id("h").Dot(adapters.Scheduler.Name).Dot(
+				ScheduleMethod,
+			).Call(
+				Id("ctx"),
+				Id("at"),
+				Id(cmdShortForm(DoSomething)),
+				Id("actor"),
+				Id("target"),
+			),
+			Id("err").Op("!=").Id("nil"),
+		).Block(
+			Return().Qual(
+				"github.com/hashicorp/errwrap",
+				"Wrap",
+			).Call(
+				Id("Err"+DoSomething+"SchedulingFailed"),
+				Id("err"),
+			),
+		)
+		g.Return().Id("nil")
+	})
 }
 
 func addCommandHandlerWrapperTypeAssertions(f *File, DoSomething string, useFactStorage bool, objects Objects) {
@@ -337,7 +632,9 @@ func addCommandHandlerWrapperTypeAssertions(f *File, DoSomething string, useFact
 func GenCommandHandlerWrapper(cmd,
 	topic string,
 	useFactStorage,
-	withPolicyEnforcement bool,
+	withPolicyEnforcement,
+	authorizeBeforeLoad bool,
+	variants Variants,
 	adapters Adapters,
 	objects Objects,
 	errors Errors) *File {
@@ -348,18 +645,31 @@ func GenCommandHandlerWrapper(cmd,
 	ret.Line()
 	addCommandHandlerWrapperErrors(ret, cmd,
 		withPolicyEnforcement,
+		variants,
 		errors)
 	addCommandHandlerWrapperType(ret, cmd,
 		withPolicyEnforcement,
+		authorizeBeforeLoad,
+		variants,
 		adapters)
 	addCommandHandlerWrapperConstructor(ret, cmd,
 		withPolicyEnforcement,
+		authorizeBeforeLoad,
+		variants,
 		adapters)
-	addCommandFuncHandle(ret, cmd,
+	addCommandFuncHandle(ret, cmd, topic,
 		withPolicyEnforcement,
+		authorizeBeforeLoad,
 		useFactStorage,
+		variants,
 		objects,
 		adapters)
+	if variants.UseScheduling {
+		addCommandFuncSchedule(ret, cmd,
+			variants,
+			objects,
+			adapters)
+	}
 	addCommandHandlerWrapperTypeAssertions(ret, cmd,
 		useFactStorage,
 		objects)
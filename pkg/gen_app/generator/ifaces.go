@@ -60,7 +60,50 @@ func genIfaceStorageReader(f *File, entity QualId) (typIdent string) {
 	return StorageReader
 }
 
-func genIfaceStorageWriterReader(f *File, entity QualId, useFactStorage bool) (typIdent string) {
+func genIfaceSnapshotStorage(f *File, entity QualId) (typIdent string) {
+	entityShort := cmdShortForm(entity.Id)
+	f.Commentf("%s knows how to load and persist snapshots of %s entity", StorageSnapshot, entity.Id)
+	f.Comment("a snapshot covers the first 'version' facts, so that only later facts need to be replayed")
+	f.Comment("application requires storage adapter to implement this interface.")
+	f.Type().Id(
+		StorageSnapshot,
+	).Interface(
+		Commentf(
+			"%s knows how to load the latest snapshot of %s entity and the version it covers", StorageLoadSnapshot, entity.Id,
+		),
+		Comment("without snapshot, it returns a nil entity"),
+		Id(
+			StorageLoadSnapshot,
+		).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("target").Id(
+				Distinguishable,
+			),
+		).Params(
+			Id(entityShort).Op("*").Qual(entity.Qual, entity.Id),
+			Id("version").Id("uint64"),
+			Id("err").Id("error"),
+		),
+		Commentf(
+			"%s knows how to persist a snapshot of %s entity which covers version facts", StorageSaveSnapshot, entity.Id,
+		),
+		Id(
+			StorageSaveSnapshot,
+		).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("target").Id(
+				Distinguishable,
+			),
+			Id(entityShort).Op("*").Qual(entity.Qual, entity.Id),
+			Id("version").Id("uint64"),
+		).Params(
+			Id("err").Id("error"),
+		),
+	)
+	return StorageSnapshot
+}
+
+func genIfaceStorageWriterReader(f *File, entity QualId, useFactStorage, useSnapshots bool) (typIdent string) {
 	entityShort := cmdShortForm(entity.Id)
 	f.Commentf("%s knows how load and persist %s entity", StorageWriterReader, entity.Id)
 	f.Comment("application requires storage adapter to implement this interface.")
@@ -70,21 +113,54 @@ func genIfaceStorageWriterReader(f *File, entity QualId, useFactStorage bool) (t
 		g.Id(
 			StorageReader,
 		)
+		if useSnapshots {
+			g.Id(
+				StorageSnapshot,
+			)
+		}
 		if useFactStorage {
 			g.Commentf(
-				"%s knows how to persist domain facts on %s entity", StorageSaveFactsMethod, entity.Id,
+				"%s knows how to apply the facts persisted after the first snapshotVersion facts onto snapshot", StorageLoadSince,
+			)
+			g.Commentf(
+				"and how many facts have been persisted on %s entity; a nil snapshot replays all facts", entity.Id,
 			)
 			g.Id(
-				StorageSaveFactsMethod,
+				StorageLoadSince,
 			).Params(
 				Id("ctx").Qual("context", "Context"),
 				Id("target").Id(
 					Distinguishable,
 				),
-				Id("fk").Id(FactKeeper),
+				Id("snapshot").Op("*").Qual(entity.Qual, entity.Id),
+				Id("snapshotVersion").Id("uint64"),
 			).Params(
+				Id(entityShort).Op("*").Qual(entity.Qual, entity.Id),
+				Id("version").Id("uint64"),
 				Id("err").Id("error"),
 			)
+			g.Commentf(
+				"%s knows how to persist domain facts on %s entity", StorageSaveFactsMethod, entity.Id,
+			)
+			g.Comment("unless other than expectedVersion facts have been persisted on it, e.g. by a concurrent command")
+			if useSnapshots {
+				g.Comment("and how many facts have been persisted on it, including the persisted facts")
+			}
+			g.Id(
+				StorageSaveFactsMethod,
+			).Params(
+				Id("ctx").Qual("context", "Context"),
+				Id("target").Id(
+					Distinguishable,
+				),
+				Id("fk").Id(FactKeeper),
+				Id("expectedVersion").Id("uint64"),
+			).ParamsFunc(func(g *Group) {
+				if useSnapshots {
+					g.Id("version").Id("uint64")
+				}
+				g.Id("err").Id("error")
+			})
 		} else {
 			g.Commentf(
 				"%s knows how to persist %s entity", StorageSaveMethod, entity.Id,
@@ -105,26 +181,55 @@ func genIfaceStorageWriterReader(f *File, entity QualId, useFactStorage bool) (t
 	return StorageWriterReader
 }
 
-func GenIfacePolicer(entity QualId, pkgName string) (f *File, typIdent string) {
+func GenIfacePolicer(entity QualId, variants Variants, pkgName string) (f *File, typIdent, targetTypIdent string) {
 	entityShort := cmdShortForm(entity.Id)
 	f = NewFile(pkgName)
 	f.Commentf("%s knows to make decisions on access policy", Policer)
 	f.Comment("application requires policy adapter to implement this interface.")
 	f.Type().Id(
 		Policer,
-	).Interface(
-		Id("Can").Params(
-			Id("ctx").Qual("context", "Context"),
-			Id("p").Id(
+	).InterfaceFunc(func(g *Group) {
+		if variants.UsePolicyPayload {
+			g.Commentf("%s knows whether p may perform action with the command payload cmd on %s entity", PolicerMethod, entity.Id)
+			g.Comment("cmd is the domain command (e.g. a value of the command type named by action)")
+		}
+		g.Id(PolicerMethod).ParamsFunc(func(g *Group) {
+			g.Id("ctx").Qual("context", "Context")
+			g.Id("p").Id(
 				Authorizable,
-			),
-			Id("action").Id("string"),
-			Id(entityShort).Op("*").Qual(entity.Qual, entity.Id),
-		).Params(
+			)
+			g.Id("action").Id("string")
+			if variants.UsePolicyPayload {
+				g.Id("cmd").Interface()
+			}
+			g.Id(entityShort).Op("*").Qual(entity.Qual, entity.Id)
+		}).Params(
 			Id("bool"),
-		),
-	)
-	return f, Policer
+		)
+	})
+	f.Commentf("%s knows to make decisions on access policy before %s entity is loaded", TargetPolicer, entity.Id)
+	f.Comment("application requires policy adapter to implement this interface for commands tagged 'authorize-before-load'.")
+	f.Type().Id(
+		TargetPolicer,
+	).InterfaceFunc(func(g *Group) {
+		g.Commentf("%s knows whether p may perform action on target, judging only by actor and target", TargetPolicerMethod)
+		g.Id(TargetPolicerMethod).ParamsFunc(func(g *Group) {
+			g.Id("ctx").Qual("context", "Context")
+			g.Id("p").Id(
+				Authorizable,
+			)
+			g.Id("action").Id("string")
+			if variants.UsePolicyPayload {
+				g.Id("cmd").Interface()
+			}
+			g.Id("target").Id(
+				Distinguishable,
+			)
+		}).Params(
+			Id("bool"),
+		)
+	})
+	return f, Policer, TargetPolicer
 }
 
 func genIfaceCommandHandler(f *File, entity QualId) (typIdent string) {
@@ -200,13 +305,122 @@ func GenIfaceDistinguishableAsserter(pkgName string) (f *File, typIdent string)
 	return f, DistinguishableAsserter
 }
 
-func GenStorageIface(entity QualId, useFactStorage bool, pkgName string) (f *File, storageReaderTypeIdent, storageReaderWriterTypeIdent string) {
+func genIfaceTenantScopedStorage(f *File, entity QualId) (typIdent string) {
+	f.Commentf("%s knows how to scope %s entity storage to a tenant", StorageTenantScoped, entity.Id)
+	f.Comment("application requires storage adapter to implement this interface.")
+	f.Type().Id(
+		StorageTenantScoped,
+	).Interface(
+		Commentf(
+			"%s knows how to return the storage of a tenant", StorageForTenantMethod,
+		),
+		Id(
+			StorageForTenantMethod,
+		).Params(
+			Id("tenant").Id("string"),
+		).Params(
+			Id(StorageWriterReader),
+		),
+	)
+	return StorageTenantScoped
+}
+
+func GenStorageIface(entity QualId, useFactStorage bool, variants Variants, pkgName string) (f *File, storageReaderTypeIdent, storageReaderWriterTypeIdent string) {
 	ret := NewFile(pkgName)
 	storageReader := genIfaceStorageReader(ret, entity)
-	storageReaderWriter := genIfaceStorageWriterReader(ret, entity, useFactStorage)
+	useSnapshots := useFactStorage && variants.SnapshotEvery > 0
+	if useSnapshots {
+		genIfaceSnapshotStorage(ret, entity)
+	}
+	storageReaderWriter := genIfaceStorageWriterReader(ret, entity, useFactStorage, useSnapshots)
+	if variants.UseTenancy {
+		// the command handler wrappers only know the tenant scoped storage
+		storageReaderWriter = genIfaceTenantScopedStorage(ret, entity)
+	}
 	return ret, storageReader, storageReaderWriter
 }
 
+func GenIfaceAuditor(pkgName string) (f *File, typIdent string) {
+	f = NewFile(pkgName)
+	f.Commentf("%s classifies the outcome of a command", AuditOutcome)
+	f.Type().Id(AuditOutcome).String()
+	f.Const().DefsFunc(func(g *Group) {
+		for _, o := range AuditOutcomes {
+			g.Commentf("%s signals that %s", o.Id, o.Doc)
+			g.Id(o.Id).Id(AuditOutcome).Op("=").Lit(o.Value)
+		}
+	})
+
+	f.Commentf("%s records who ran which command against which target", AuditRecord)
+	f.Type().Id(
+		AuditRecord,
+	).StructFunc(func(g *Group) {
+		g.Id("Actor").Id(Authorizable)
+		g.Id("Target").Id("string").Comment("identifier of the target")
+		g.Id("Command").Id("string")
+		g.Id("Topic").Id("string")
+		g.Id("Facts").Index().Interface().Comment("facts raised by the domain (fact-based storage only)")
+		g.Id("Outcome").Id(AuditOutcome)
+		g.Id("Err").Id("error").Comment("error returned to the caller, if any")
+		g.Id("Time").Qual("time", "Time")
+	})
+
+	f.Commentf("%s knows how to record the audit trail of commands", Auditor)
+	f.Comment("application requires audit adapter to implement this interface.")
+	f.Type().Id(
+		Auditor,
+	).Interface(
+		Commentf(
+			"%s knows how to record an %s; it cannot alter the outcome of the command", AuditMethod, AuditRecord,
+		),
+		Id(
+			AuditMethod,
+		).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("r").Id(AuditRecord),
+		),
+	)
+	return f, Auditor
+}
+
+func GenIfaceScheduler(pkgName string) (f *File, typIdent string) {
+	f = NewFile(pkgName)
+	f.Commentf("%s is a command which is due at a point in time", ScheduledCommand)
+	f.Type().Id(
+		ScheduledCommand,
+	).Struct(
+		Id("ID").Id("string").Comment("assigned by the scheduler"),
+		Id("At").Qual("time", "Time"),
+		Id("Command").Interface().Comment("domain command, e.g. a value of domain.ArchiveAccount"),
+		Id("Actor").Id(Authorizable),
+		Id("Target").Id(Distinguishable),
+		Id("Attempts").Int().Comment("failed attempts, recorded by the scheduler"),
+		Id("NextAttempt").Qual("time", "Time").Comment("when the command is due again after a failed attempt"),
+	)
+
+	f.Commentf("%s knows how to defer commands until they are due", Scheduler)
+	f.Comment("application requires scheduler adapter to implement this interface.")
+	f.Type().Id(
+		Scheduler,
+	).Interface(
+		Commentf(
+			"%s knows how to persist cmd to be handled at a later point in time", ScheduleMethod,
+		),
+		Id(
+			ScheduleMethod,
+		).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("at").Qual("time", "Time"),
+			Id("cmd").Interface(),
+			Id("actor").Id(Authorizable),
+			Id("target").Id(Distinguishable),
+		).Params(
+			Id("err").Id("error"),
+		),
+	)
+	return f, Scheduler
+}
+
 func GenCmdHandlerIface(entity QualId, useFactStorage bool, pkgName string) (f *File, cmd, ek, fk string) {
 	ret := NewFile(pkgName)
 	cmd = genIfaceCommandHandler(ret, entity)
@@ -220,46 +434,61 @@ func GenCmdHandlerIface(entity QualId, useFactStorage bool, pkgName string) (f *
 
 // Offered interfaces ...
 
-func GenIfaceDistinguishable(pkgName string) (f *File, typIdent string) {
+func GenIfaceDistinguishable(variants Variants, pkgName string) (f *File, typIdent string) {
 	f = NewFile(pkgName)
 	f.Commentf("%s can be identified", Distinguishable)
 	f.Commentf("application implements %s and thereby offers storage adapter and external consumers a common language to reason about identity", Distinguishable)
-	f.Commentf("TODO: implement %s", Distinguishable)
+	f.Commentf("TODO: implement %s or generate it with protoc-gen-ddd (--ddd_out=target=<Message>)", Distinguishable)
 	f.Type().Id(
 		Distinguishable,
-	).Interface(
-		Id(DistinguishableAsserter),
-		Commentf("%s knows how to identify %s", DistinguishableMethod, Distinguishable),
-		Comment("TODO: adapt return type to your needs "),
-		Id(
+	).InterfaceFunc(func(g *Group) {
+		g.Id(DistinguishableAsserter)
+		g.Commentf("%s knows how to identify %s", DistinguishableMethod, Distinguishable)
+		g.Id(
 			DistinguishableMethod,
 		).Params().Params(
 			Id("string"),
-		),
-	)
+		)
+		if variants.UseTenancy {
+			g.Commentf("%s knows to which tenant %s belongs", TenantMethod, Distinguishable)
+			g.Id(
+				TenantMethod,
+			).Params().Params(
+				Id("string"),
+			)
+		}
+	})
 	return f, Distinguishable
 }
 
-func GenIfaceAuthorizable(pkgName string) (f *File, typIdent string) {
+func GenIfaceAuthorizable(variants Variants, pkgName string) (f *File, typIdent string) {
 	f = NewFile(pkgName)
 	f.Commentf("%s is an actor that can be policed", Authorizable)
 	f.Commentf("application implements %s and thereby offers policy adapter and external consumers a common language to reason about a authorizable actor", Authorizable)
-	f.Commentf("TODO: implement %s", Authorizable)
+	f.Commentf("TODO: implement %s or generate it with protoc-gen-ddd (--ddd_out=actor=<Message>)", Authorizable)
 	f.Type().Id(
 		Authorizable,
-	).Interface(
-		Comment("TODO: adapt to your needs"),
-		Line(),
-		Id(
-			"User",
+	).InterfaceFunc(func(g *Group) {
+		g.Comment("TODO: adapt to your needs")
+		g.Line()
+		g.Id(
+			AuthorizableUserMethod,
 		).Params().Params(
 			Id("string"),
-		),
-		Id(
-			"ElevationToken",
+		)
+		g.Id(
+			AuthorizableElevationTokenMethod,
 		).Params().Params(
 			Id("string"),
-		),
-	)
+		)
+		if variants.UseTenancy {
+			g.Commentf("%s knows to which tenant %s belongs", TenantMethod, Authorizable)
+			g.Id(
+				TenantMethod,
+			).Params().Params(
+				Id("string"),
+			)
+		}
+	})
 	return f, Authorizable
 }
@@ -11,16 +11,30 @@ const (
 	DistinguishableAsserter              = "RequiresDistinguishableAsserter"
 	DistinguishableMethod                = "Identifier"
 	DistinguishableAsserterMethod        = "IsDistinguishable"
+	TenantMethod                         = "GetTenant" // the getter protoc-gen-go generates for a tenant field
 
 	Authorizable    = "OffersAuthorizable"
 	Policer       = "RequiresPolicer"
 	PolicerMethod = "Can"
 
+	// the getters protoc-gen-go generates for the fields of an actor message
+	AuthorizableUserMethod           = "GetUser"
+	AuthorizableElevationTokenMethod = "GetElevationToken"
+
+	TargetPolicer       = "RequiresTargetPolicer"
+	TargetPolicerMethod = "CanOnTarget"
+
 	StorageReader          = "RequiresStorageReader"
 	StorageWriterReader    = "RequiresStorageWriterReader"
 	StorageLoadMethod      = "Load"
 	StorageSaveMethod      = "Save"
 	StorageSaveFactsMethod = "SaveFacts"
+	StorageTenantScoped    = "RequiresTenantScopedStorage"
+	StorageForTenantMethod = "ForTenant"
+	StorageSnapshot        = "RequiresSnapshotStorage"
+	StorageLoadSnapshot    = "LoadSnapshot"
+	StorageSaveSnapshot    = "SaveSnapshot"
+	StorageLoadSince       = "LoadSince"
 
 	CommandHandler       = "RequiresCommandHandler"
 	CommandHandlerMethod = "Handle"
@@ -28,4 +42,23 @@ const (
 	ErrorKeeperMethod    = "Errors"
 	FactKeeper           = "OffersFactKeeper"
 	FactKeeperMethod     = "Facts"
+
+	Scheduler        = "RequiresCommandScheduler"
+	ScheduleMethod   = "Schedule"
+	ScheduledCommand = "ScheduledCommand"
+
+	Auditor      = "RequiresAuditor"
+	AuditMethod  = "Audit"
+	AuditRecord  = "AuditRecord"
+	AuditOutcome = "AuditOutcome"
 )
+
+// AuditOutcomes classify the exit paths of a command handler wrapper
+var AuditOutcomes = []struct{ Id, Value, Doc string }{
+	{"AuditOutcomeSuccess", "success", "the command succeeded"},
+	{"AuditOutcomeInvalidTarget", "invalid_target", "the target was not distinguishable"},
+	{"AuditOutcomeDenied", "denied", "the actor was not authorized"},
+	{"AuditOutcomeDomainFailure", "domain_failure", "the domain failed to handle the command"},
+	{"AuditOutcomeStorageFailure", "storage_failure", "the storage failed to load or save"},
+	{"AuditOutcomeAborted", "aborted", "the command handler panicked"},
+}
@@ -0,0 +1,380 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+)
+
+// Scheduler ...
+
+func addSchedulerClock(f *File) {
+	f.Comment("Clock tells the time; inject a fake clock in tests")
+	f.Type().Id("Clock").Interface(
+		Id("Now").Params().Qual("time", "Time"),
+	)
+
+	f.Comment("SystemClock is the Clock of the system")
+	f.Type().Id("SystemClock").Struct()
+
+	f.Comment("Now returns the current system time")
+	f.Func().Params(
+		Id("SystemClock"),
+	).Id("Now").Params().Qual("time", "Time").Block(
+		Return(Qual("time", "Now").Call()),
+	)
+}
+
+func addSchedulerDispatcher(f *File, sourceTypeName string, cmds []string, objects Objects) {
+	f.Comment("Dispatcher knows how to hand a domain command to its command handler wrapper")
+	f.Type().Id("Dispatcher").Interface(
+		Id("Dispatch").Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("cmd").Interface(),
+			Id("actor").Qual(objects.Actor.Qual, objects.Actor.Id),
+			Id("target").Qual(objects.Target.Qual, objects.Target.Id),
+		).Error(),
+	)
+
+	short := cmdShortForm(sourceTypeName)
+	log.Printf("%s: generating 'Dispatch()'\n", sourceTypeName)
+	f.Comment("Dispatch hands cmd to the command handler wrapper of its type")
+	f.Func().Params(
+		Id(short).Op("*").Id(sourceTypeName),
+	).Id("Dispatch").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("cmd").Interface(),
+		Id("actor").Qual(objects.Actor.Qual, objects.Actor.Id),
+		Id("target").Qual(objects.Target.Qual, objects.Target.Id),
+	).Error().Block(
+		Switch(Id("cmd").Op(":=").Id("cmd").Assert(Type())).BlockFunc(func(g *Group) {
+			for _, cmd := range cmds {
+				g.Case(Qual(objects.Domain.Qual, cmd)).Block(
+					Return(Id(short).Dot(cmd).Dot("Handle").Call(
+						Id("ctx"),
+						Id("cmd"),
+						Id("actor"),
+						Id("target"),
+					)),
+				)
+			}
+			g.Default().Block(
+				Return(Qual("fmt", "Errorf").Call(Lit("no command handler wrapper for %T"), Id("cmd"))),
+			)
+		}),
+	)
+}
+
+func addSchedulerStore(f *File, adapters Adapters, objects Objects) {
+	f.Comment("ScheduleStore knows how to keep scheduled commands until they are due")
+	f.Type().Id("ScheduleStore").Interface(
+		Qual(adapters.Scheduler.Qual, adapters.Scheduler.Id),
+		Comment("Due knows how to return the commands due at now, ordered by time"),
+		Id("Due").Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("now").Qual("time", "Time"),
+		).Params(
+			Index().Qual(adapters.Scheduler.Qual, ScheduledCommand),
+			Error(),
+		),
+		Comment("Retry knows how to record a failed attempt of the command identified by id, which is due again at next"),
+		Id("Retry").Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("id").String(),
+			Id("next").Qual("time", "Time"),
+		).Error(),
+		Comment("Done knows how to discard a handled command"),
+		Id("Done").Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("id").String(),
+		).Error(),
+	)
+
+	f.Comment("memoryScheduleEntry keeps a scheduled command in order of scheduling")
+	f.Type().Id("memoryScheduleEntry").Struct(
+		Id("seq").Uint64(),
+		Id("at").Qual("time", "Time").Comment("when the command is due, its next attempt once an attempt failed"),
+		Id("cmd").Qual(adapters.Scheduler.Qual, ScheduledCommand),
+	)
+
+	f.Comment("MemoryScheduleStore keeps scheduled commands in memory")
+	f.Comment("it is meant for tests and single process deployments")
+	f.Type().Id("MemoryScheduleStore").Struct(
+		Id("mu").Qual("sync", "Mutex"),
+		Id("seq").Uint64(),
+		Id("entries").Map(String()).Id("memoryScheduleEntry"),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "MemoryScheduleStore", "NewMemoryScheduleStore")
+	f.Comment("NewMemoryScheduleStore returns an empty MemoryScheduleStore")
+	f.Func().Id("NewMemoryScheduleStore").Params().Op("*").Id("MemoryScheduleStore").Block(
+		Return(Op("&").Id("MemoryScheduleStore").Values(Dict{
+			Id("entries"): Make(Map(String()).Id("memoryScheduleEntry")),
+		})),
+	)
+
+	f.Commentf("%s keeps cmd until it is due at", ScheduleMethod)
+	f.Func().Params(
+		Id("m").Op("*").Id("MemoryScheduleStore"),
+	).Id(ScheduleMethod).Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("at").Qual("time", "Time"),
+		Id("cmd").Interface(),
+		Id("actor").Qual(objects.Actor.Qual, objects.Actor.Id),
+		Id("target").Qual(objects.Target.Qual, objects.Target.Id),
+	).Error().Block(
+		Id("m").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("m").Dot("mu").Dot("Unlock").Call(),
+		Id("m").Dot("seq").Op("++"),
+		Id("id").Op(":=").Qual("strconv", "FormatUint").Call(Id("m").Dot("seq"), Lit(10)),
+		Id("m").Dot("entries").Index(Id("id")).Op("=").Id("memoryScheduleEntry").Values(Dict{
+			Id("seq"): Id("m").Dot("seq"),
+			Id("at"):  Id("at"),
+			Id("cmd"): Qual(adapters.Scheduler.Qual, ScheduledCommand).Values(Dict{
+				Id("ID"):      Id("id"),
+				Id("At"):      Id("at"),
+				Id("Command"): Id("cmd"),
+				Id("Actor"):   Id("actor"),
+				Id("Target"):  Id("target"),
+			}),
+		}),
+		Return(Nil()),
+	)
+
+	f.Comment("Due returns the commands due at now, ordered by time and order of scheduling")
+	f.Func().Params(
+		Id("m").Op("*").Id("MemoryScheduleStore"),
+	).Id("Due").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("now").Qual("time", "Time"),
+	).Params(
+		Index().Qual(adapters.Scheduler.Qual, ScheduledCommand),
+		Error(),
+	).Block(
+		Id("m").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("m").Dot("mu").Dot("Unlock").Call(),
+		Var().Id("due").Index().Id("memoryScheduleEntry"),
+		For(List(Id("_"), Id("e")).Op(":=").Range().Id("m").Dot("entries")).Block(
+			If(Op("!").Id("e").Dot("at").Dot("After").Call(Id("now"))).Block(
+				Id("due").Op("=").Append(Id("due"), Id("e")),
+			),
+		),
+		Qual("sort", "Slice").Call(Id("due"), Func().Params(Id("i"), Id("j").Int()).Bool().Block(
+			If(Op("!").Id("due").Index(Id("i")).Dot("at").Dot("Equal").Call(Id("due").Index(Id("j")).Dot("at"))).Block(
+				Return(Id("due").Index(Id("i")).Dot("at").Dot("Before").Call(Id("due").Index(Id("j")).Dot("at"))),
+			),
+			Return(Id("due").Index(Id("i")).Dot("seq").Op("<").Id("due").Index(Id("j")).Dot("seq")),
+		)),
+		Id("ret").Op(":=").Make(Index().Qual(adapters.Scheduler.Qual, ScheduledCommand), Len(Id("due"))),
+		For(List(Id("i"), Id("e")).Op(":=").Range().Id("due")).Block(
+			Id("ret").Index(Id("i")).Op("=").Id("e").Dot("cmd"),
+		),
+		Return(Id("ret"), Nil()),
+	)
+
+	f.Comment("Retry records a failed attempt of the command identified by id and keeps it until next")
+	f.Func().Params(
+		Id("m").Op("*").Id("MemoryScheduleStore"),
+	).Id("Retry").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("id").String(),
+		Id("next").Qual("time", "Time"),
+	).Error().Block(
+		Id("m").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("m").Dot("mu").Dot("Unlock").Call(),
+		List(Id("e"), Id("ok")).Op(":=").Id("m").Dot("entries").Index(Id("id")),
+		If(Op("!").Id("ok")).Block(
+			Return(Qual("fmt", "Errorf").Call(Lit("no scheduled command %s"), Id("id"))),
+		),
+		Id("e").Dot("at").Op("=").Id("next"),
+		Id("e").Dot("cmd").Dot("Attempts").Op("++"),
+		Id("e").Dot("cmd").Dot("NextAttempt").Op("=").Id("next"),
+		Id("m").Dot("entries").Index(Id("id")).Op("=").Id("e"),
+		Return(Nil()),
+	)
+
+	f.Comment("Done discards the command identified by id")
+	f.Func().Params(
+		Id("m").Op("*").Id("MemoryScheduleStore"),
+	).Id("Done").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("id").String(),
+	).Error().Block(
+		Id("m").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("m").Dot("mu").Dot("Unlock").Call(),
+		Delete(Id("m").Dot("entries"), Id("id")),
+		Return(Nil()),
+	)
+}
+
+func addSchedulerRetryable(f *File, errors Errors) {
+	errs := errors.StorageLoadingErrorNew.Qual
+	f.Comment("retryable reports whether err may go away when the command is handled again, which is the case for storage errors")
+	f.Comment("authorization, target identification and domain errors, as well as unknown commands, are final")
+	f.Func().Id("retryable").Params(Err().Error()).Bool().Block(
+		Switch(Qual(errs, "KindOf").Call(Err())).Block(
+			Case(ListFunc(func(g *Group) {
+				for _, kind := range RetryableErrorKinds {
+					g.Qual(errs, ErrorKindConst(kind))
+				}
+			})).Block(
+				Return(True()),
+			),
+		),
+		Return(False()),
+	)
+}
+
+func addSchedulerRetryDelay(f *File) {
+	f.Comment("retryDelay returns the delay after attempts failed attempts: backoff, doubled for every further")
+	f.Comment("failed attempt until it exceeds an hour")
+	f.Func().Id("retryDelay").Params(
+		Id("backoff").Qual("time", "Duration"),
+		Id("attempts").Int(),
+	).Qual("time", "Duration").Block(
+		For(Id("i").Op(":=").Lit(1), Id("i").Op("<").Id("attempts").Op("&&").Id("backoff").Op("<").Qual("time", "Hour"), Id("i").Op("++")).Block(
+			Id("backoff").Op("*=").Lit(2),
+		),
+		Return(Id("backoff")),
+	)
+}
+
+func addSchedulerRunner(f *File) {
+	f.Comment("SchedulerRunner hands scheduled commands to their command handler wrappers once they are due")
+	f.Type().Id("SchedulerRunner").Struct(
+		Id("store").Id("ScheduleStore"),
+		Id("d").Id("Dispatcher"),
+		Id("clock").Id("Clock"),
+		Id("maxAttempts").Int(),
+		Id("backoff").Qual("time", "Duration"),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "SchedulerRunner", "NewSchedulerRunner")
+	f.Comment("NewSchedulerRunner returns SchedulerRunner, which gives up on a command once handling it failed")
+	f.Comment("maxAttempts times, retryable failures are retried after backoff, doubled for every further attempt")
+	f.Func().Id("NewSchedulerRunner").Params(
+		Id("store").Id("ScheduleStore"),
+		Id("d").Id("Dispatcher"),
+		Id("clock").Id("Clock"),
+		Id("maxAttempts").Int(),
+		Id("backoff").Qual("time", "Duration"),
+	).Op("*").Id("SchedulerRunner").BlockFunc(func(g *Group) {
+		for _, a := range []string{"store", "d", "clock"} {
+			g.If(Id(a).Op("==").Nil()).Block(
+				Id("panic").Call(Lit("no '" + a + "' provided!")),
+			)
+		}
+		g.If(Id("maxAttempts").Op("<").Lit(1)).Block(
+			Id("panic").Call(Lit("'maxAttempts' must be at least 1!")),
+		)
+		g.Return(Op("&").Id("SchedulerRunner").Values(Dict{
+			Id("store"):       Id("store"),
+			Id("d"):           Id("d"),
+			Id("clock"):       Id("clock"),
+			Id("maxAttempts"): Id("maxAttempts"),
+			Id("backoff"):     Id("backoff"),
+		}))
+	})
+
+	f.Comment("RunDue handles the commands which are due according to the clock")
+	f.Comment("a command is done once handled or once handling failed for good; retryable failures keep it")
+	f.Comment("scheduled for a later attempt with backoff, up to maxAttempts. All failures are returned by command ID")
+	f.Func().Params(
+		Id("r").Op("*").Id("SchedulerRunner"),
+	).Id("RunDue").Params(
+		Id("ctx").Qual("context", "Context"),
+	).Params(
+		Id("failed").Map(String()).Error(),
+		Id("err").Error(),
+	).Block(
+		Id("now").Op(":=").Id("r").Dot("clock").Dot("Now").Call(),
+		List(Id("due"), Id("err")).Op(":=").Id("r").Dot("store").Dot("Due").Call(Id("ctx"), Id("now")),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Nil(), Id("err")),
+		),
+		Id("failed").Op("=").Make(Map(String()).Error()),
+		For(List(Id("_"), Id("c")).Op(":=").Range().Id("due")).Block(
+			If(
+				Id("err").Op(":=").Id("r").Dot("d").Dot("Dispatch").Call(Id("ctx"), Id("c").Dot("Command"), Id("c").Dot("Actor"), Id("c").Dot("Target")),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Id("failed").Index(Id("c").Dot("ID")).Op("=").Id("err"),
+				Id("attempts").Op(":=").Id("c").Dot("Attempts").Op("+").Lit(1),
+				If(Id("retryable").Call(Id("err")).Op("&&").Id("attempts").Op("<").Id("r").Dot("maxAttempts")).Block(
+					If(
+						Id("err").Op(":=").Id("r").Dot("store").Dot("Retry").Call(Id("ctx"), Id("c").Dot("ID"), Id("now").Dot("Add").Call(Id("retryDelay").Call(Id("r").Dot("backoff"), Id("attempts")))),
+						Id("err").Op("!=").Nil(),
+					).Block(
+						Return(Id("failed"), Id("err")),
+					),
+					Continue(),
+				),
+				If(Id("retryable").Call(Id("err"))).Block(
+					Id("failed").Index(Id("c").Dot("ID")).Op("=").Qual("fmt", "Errorf").Call(Lit("giving up after %d attempts: %w"), Id("attempts"), Id("err")),
+				),
+			),
+			If(
+				Id("err").Op(":=").Id("r").Dot("store").Dot("Done").Call(Id("ctx"), Id("c").Dot("ID")),
+				Id("err").Op("!=").Nil(),
+			).Block(
+				Return(Id("failed"), Id("err")),
+			),
+		),
+		Return(Id("failed"), Nil()),
+	)
+
+	f.Comment("Run runs due commands every interval until ctx is done")
+	f.Comment("onFailure, if not nil, is called for every command which failed to be handled")
+	f.Func().Params(
+		Id("r").Op("*").Id("SchedulerRunner"),
+	).Id("Run").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("interval").Qual("time", "Duration"),
+		Id("onFailure").Func().Params(Id("id").String(), Id("err").Error()),
+	).Error().Block(
+		Id("t").Op(":=").Qual("time", "NewTicker").Call(Id("interval")),
+		Defer().Id("t").Dot("Stop").Call(),
+		For().Block(
+			List(Id("failed"), Id("err")).Op(":=").Id("r").Dot("RunDue").Call(Id("ctx")),
+			If(Id("err").Op("!=").Nil()).Block(
+				Return(Id("err")),
+			),
+			If(Id("onFailure").Op("!=").Nil()).Block(
+				For(List(Id("id"), Id("err")).Op(":=").Range().Id("failed")).Block(
+					Id("onFailure").Call(Id("id"), Id("err")),
+				),
+			),
+			Select().Block(
+				Case(Op("<-").Id("ctx").Dot("Done").Call()).Block(
+					Return(Id("ctx").Dot("Err").Call()),
+				),
+				Case(Op("<-").Id("t").Dot("C")).Block(),
+			),
+		),
+	)
+}
+
+// Composers ...
+
+func GenScheduler(sourceTypeName string, cmds []string, adapters Adapters, objects Objects, errors Errors) *File {
+	ret := NewFile("command")
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenCommand))
+	ret.Line()
+	addSchedulerClock(ret)
+	addSchedulerDispatcher(ret, sourceTypeName, cmds, objects)
+	addSchedulerStore(ret, adapters, objects)
+	addSchedulerRetryable(ret, errors)
+	addSchedulerRetryDelay(ret)
+	addSchedulerRunner(ret)
+	ret.Comment("compile time assertions")
+	ret.Var().DefsFunc(func(g *Group) {
+		g.Id("_").Id("Clock").Op("=").Id("SystemClock").Values()
+		g.Id("_").Id("Dispatcher").Op("=").Parens(Op("*").Id(sourceTypeName)).Call(Nil())
+		g.Id("_").Id("ScheduleStore").Op("=").Parens(Op("*").Id("MemoryScheduleStore")).Call(Nil())
+	})
+	return ret
+}
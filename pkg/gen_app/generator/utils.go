@@ -25,3 +25,11 @@ func splitQual(s string) (string, string) {
 	id := s[strings.LastIndex(s, ".")+1:]
 	return imp, id
 }
+
+// policer selects the policy adapter a command handler wrapper asserts authorization with
+func policer(authorizeBeforeLoad bool, adapters Adapters) NamedQualId {
+	if authorizeBeforeLoad {
+		return adapters.TargetPolicer
+	}
+	return adapters.Policer
+}
@@ -14,12 +14,15 @@ import (
 )
 
 const (
-	StorageRWIdent = "rw"
-	StorageRIdent  = "r"
-	PolicerIdent   = "p"
+	StorageRWIdent     = "rw"
+	StorageRIdent      = "r"
+	PolicerIdent       = "p"
+	TargetPolicerIdent = "tp"
+	AuditorIdent       = "au"
+	SchedulerIdent     = "s"
 )
 
-func generateIfaces(genPath string, useFactStorage bool, objects *generator.Objects, adapters *generator.Adapters) error {
+func generateIfaces(genPath string, useFactStorage bool, variants generator.Variants, objects *generator.Objects, adapters *generator.Adapters) error {
 	pkgName := "app"
 	// doc file
 	docFile := path.Join(genPath, "doc.go")
@@ -50,7 +53,7 @@ func generateIfaces(genPath string, useFactStorage bool, objects *generator.Obje
 			return err
 		}
 	}
-	gsf, rTyp, rwTyp := generator.GenStorageIface(objects.Entity, useFactStorage, pkgName)
+	gsf, rTyp, rwTyp := generator.GenStorageIface(objects.Entity, useFactStorage, variants, pkgName)
 	if err := gsf.Save(storageFile); err != nil {
 		return err
 	}
@@ -76,7 +79,7 @@ func generateIfaces(genPath string, useFactStorage bool, objects *generator.Obje
 			return err
 		}
 	}
-	gpf, typ := generator.GenIfacePolicer(objects.Entity, pkgName)
+	gpf, typ, tTyp := generator.GenIfacePolicer(objects.Entity, variants, pkgName)
 	if err := gpf.Save(policyFile); err != nil {
 		return err
 	}
@@ -87,6 +90,55 @@ func generateIfaces(genPath string, useFactStorage bool, objects *generator.Obje
 			Id:   typ,
 		},
 	}
+	adapters.TargetPolicer = generator.NamedQualId{
+		Name: TargetPolicerIdent,
+		QualId: generator.QualId{
+			Qual: pkgPath,
+			Id:   tTyp,
+		},
+	}
+
+	// audit related interfaces
+	auditFile := path.Join(genPath, "audit.go")
+	if fileExists(auditFile) {
+		if err := os.Remove(auditFile); err != nil {
+			return err
+		}
+	}
+	if variants.UseAudit {
+		gaf, audTyp := generator.GenIfaceAuditor(pkgName)
+		if err := gaf.Save(auditFile); err != nil {
+			return err
+		}
+		adapters.Auditor = generator.NamedQualId{
+			Name: AuditorIdent,
+			QualId: generator.QualId{
+				Qual: pkgPath,
+				Id:   audTyp,
+			},
+		}
+	}
+
+	// scheduling related interfaces
+	schedulerFile := path.Join(genPath, "scheduler.go")
+	if fileExists(schedulerFile) {
+		if err := os.Remove(schedulerFile); err != nil {
+			return err
+		}
+	}
+	if variants.UseScheduling {
+		gsf, schedTyp := generator.GenIfaceScheduler(pkgName)
+		if err := gsf.Save(schedulerFile); err != nil {
+			return err
+		}
+		adapters.Scheduler = generator.NamedQualId{
+			Name: SchedulerIdent,
+			QualId: generator.QualId{
+				Qual: pkgPath,
+				Id:   schedTyp,
+			},
+		}
+	}
 
 	// command related interfaces
 	commandFile := path.Join(genPath, "domain.go")
@@ -134,7 +186,7 @@ func generateIfaces(genPath string, useFactStorage bool, objects *generator.Obje
 			return err
 		}
 	}
-	gsf, disTyp := generator.GenIfaceDistinguishable(pkgName)
+	gsf, disTyp := generator.GenIfaceDistinguishable(variants, pkgName)
 	if err := gsf.Save(distinguishableFile); err != nil {
 		return err
 	}
@@ -150,7 +202,7 @@ func generateIfaces(genPath string, useFactStorage bool, objects *generator.Obje
 			return err
 		}
 	}
-	gpf, polTyp := generator.GenIfaceAuthorizable(pkgName)
+	gpf, polTyp := generator.GenIfaceAuthorizable(variants, pkgName)
 	if err := gpf.Save(authorizableFile); err != nil {
 		return err
 	}
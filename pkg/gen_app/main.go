@@ -16,6 +16,9 @@ import (
 )
 
 func Gen(sourceTypeName string, useFactStorage bool, conf *Config) error {
+	if conf.Variants.SnapshotEvery > 0 && !useFactStorage {
+		return fmt.Errorf("snapshots require fact-based storage")
+	}
 
 	// Get the package of the file with go:generate comment
 	goPackage := os.Getenv("GOPACKAGE")
@@ -27,7 +30,7 @@ func Gen(sourceTypeName string, useFactStorage bool, conf *Config) error {
 	ifacesPath := path.Join(cwd, "../")
 
 	// Generate interfaces using jennifer
-	err = generateIfaces(ifacesPath, useFactStorage, &conf.Objects, &conf.Adapters)
+	err = generateIfaces(ifacesPath, useFactStorage, conf.Variants, &conf.Objects, &conf.Adapters)
 	if err != nil {
 		return err
 	}
@@ -68,7 +71,7 @@ func Gen(sourceTypeName string, useFactStorage bool, conf *Config) error {
 	structType := types.NewStruct(fields, tags)
 
 	// Generate code using jennifer
-	err = analyzeStructAndGenerateCommandWrappers(cwd, sourceTypeName, useFactStorage, structType, conf.Adapters, conf.Objects, conf.Errors)
+	err = analyzeStructAndGenerateCommandWrappers(cwd, sourceTypeName, useFactStorage, conf.Variants, structType, conf.Adapters, conf.Objects, conf.Errors)
 	if err != nil {
 		return err
 	}
@@ -28,9 +28,52 @@ var (
 var (
 	topicTagPattern         = regexp.MustCompile(`topic,([^;]+)`)
 	withoutPolicyTagPattern = regexp.MustCompile(`w/o policy`)
+	beforeLoadTagPattern    = regexp.MustCompile(`authorize-before-load`)
 	adaptersTagPattern      = regexp.MustCompile(`adapters(?:,([^;]+:[^;]+))+`) // adapters,a1:github.com/foo/bar.Adapter1,a2:github.com/foo/bar.Adapter2
 )
 
+// CommandTag is the configuration of a command, read off the tag of its field
+type CommandTag struct {
+	Topic                 string
+	WithPolicyEnforcement bool
+	AuthorizeBeforeLoad   bool
+	Adapters              []generator.NamedQualId
+}
+
+// ParseCommandTag parses the 'command' tag of the field of command cmd
+// the topic defaults to the last titled word of cmd
+func ParseCommandTag(cmd string, tag reflect.StructTag) (CommandTag, error) {
+	ct := CommandTag{WithPolicyEnforcement: true}
+	if tagKeyV, ok := tag.Lookup(tagKey); ok {
+		if matches := topicTagPattern.FindStringSubmatch(tagKeyV); matches != nil {
+			ct.Topic = matches[1]
+		}
+		if matches := withoutPolicyTagPattern.FindStringSubmatch(tagKeyV); matches != nil {
+			ct.WithPolicyEnforcement = false
+		}
+		if matches := beforeLoadTagPattern.FindStringSubmatch(tagKeyV); matches != nil {
+			if !ct.WithPolicyEnforcement {
+				return ct, fmt.Errorf("%s: 'authorize-before-load' conflicts with 'w/o policy'", cmd)
+			}
+			ct.AuthorizeBeforeLoad = true
+		}
+		if matches := adaptersTagPattern.FindStringSubmatch(tagKeyV); matches != nil {
+			for _, m := range matches[1:] {
+				ss := strings.Split(m, ":")
+				if !isValidQualId(ss[1]) {
+					return ct, fmt.Errorf("'adapters' tag value %s:%s does not contain a valid full qualifier", ss[0], ss[1])
+				}
+				ct.Adapters = append(ct.Adapters, generator.NamedQualId{Name: ss[0], QualId: splitQual(ss[1])})
+			}
+		}
+	}
+	if ct.Topic == "" {
+		ct.Topic = getLastTitledWord(cmd)
+	}
+	ct.Topic = strings.Title(ct.Topic)
+	return ct, nil
+}
+
 func generateDoc(docFile string) {
 	if !fileExists(docFile) {
 		df := generator.GenCommandDoc(docFile)
@@ -38,7 +81,7 @@ func generateDoc(docFile string) {
 	}
 }
 
-func analyzeStructAndGenerateCommandWrappers(genPath, sourceTypeName string, useFactStorage bool, struuct *types.Struct, adapters generator.Adapters, objects generator.Objects, errors generator.Errors) error {
+func analyzeStructAndGenerateCommandWrappers(genPath, sourceTypeName string, useFactStorage bool, variants generator.Variants, struuct *types.Struct, adapters generator.Adapters, objects generator.Objects, errors generator.Errors) error {
 	// determin the fully qualified package path
 	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName}, genPath)
 	if err != nil {
@@ -57,10 +100,19 @@ func analyzeStructAndGenerateCommandWrappers(genPath, sourceTypeName string, use
 	if useFactStorage {
 		log.Printf("\t%s\n", objects.FactKeeper)
 	}
+	log.Println("  using variants ...")
+	log.Printf("\t%+v\n", variants)
 	log.Println("  using adapter interfaces ...")
 	// log.Printf("\t%s\n", adapters.StorageR)
 	log.Printf("\t%s\n", adapters.StorageRW)
 	log.Printf("\t%s\n", adapters.Policer)
+	log.Printf("\t%s\n", adapters.TargetPolicer)
+	if variants.UseAudit {
+		log.Printf("\t%s\n", adapters.Auditor)
+	}
+	if variants.UseScheduling {
+		log.Printf("\t%s\n", adapters.Scheduler)
+	}
 	// log.Printf("\t%s\n", adapters.DomServiceAdapters)
 	log.Println("  using error constructors ...")
 	log.Printf("\t%s\n", errors.AuthorizationErrorNew)
@@ -70,41 +122,22 @@ func analyzeStructAndGenerateCommandWrappers(genPath, sourceTypeName string, use
 	log.Printf("\t%s\n", errors.DomainErrorNew)
 
 	// 2. iterate over  fields
+	var cmds []string
 	for i := 0; i < struuct.NumFields(); i++ {
 		field := struuct.Field(i)
 		tag := reflect.StructTag(struuct.Tag(i))
 
-		var (
-			cmd                   string
-			topic                 string
-			withPolicyEnforcement bool
-		)
-		cmd = field.Name()
-		withPolicyEnforcement = true
+		cmd := field.Name()
+		cmds = append(cmds, cmd)
 
 		// match and classify fields according to tags
-		if tagKeyV, ok := tag.Lookup(tagKey); ok {
-			if matches := topicTagPattern.FindStringSubmatch(tagKeyV); matches != nil {
-				topic = matches[1]
-			}
-			if matches := withoutPolicyTagPattern.FindStringSubmatch(tagKeyV); matches != nil {
-				withPolicyEnforcement = false
-			}
-			if matches := adaptersTagPattern.FindStringSubmatch(tagKeyV); matches != nil {
-				for _, m := range matches[1:] {
-					ss := strings.Split(m, ":")
-					if !isValidQualId(ss[1]) {
-						return fmt.Errorf("'adapters' tag value %s:%s does not contain a valid full qualifier", ss[0], ss[1])
-					}
-					adapters.DomServiceAdapters = append(adapters.DomServiceAdapters, generator.NamedQualId{Name: ss[0], QualId: splitQual(ss[1])})
-				}
-			}
-		}
-		if topic == "" {
-			topic = getLastTitledWord(cmd)
+		ct, err := ParseCommandTag(cmd, tag)
+		if err != nil {
+			return err
 		}
+		topic := ct.Topic
+		adapters.DomServiceAdapters = append(adapters.DomServiceAdapters, ct.Adapters...)
 
-		topic = strings.Title(topic)
 		log.Printf("topic %s -> %s: generating handler wrapper\n", topic, cmd)
 
 		fileBaseName := toSnakeCase(cmd)
@@ -120,13 +153,28 @@ func analyzeStructAndGenerateCommandWrappers(genPath, sourceTypeName string, use
 				return err
 			}
 		}
-		gf := generator.GenCommandHandlerWrapper(cmd, topic, useFactStorage, withPolicyEnforcement, adapters, objects, errors)
+		gf := generator.GenCommandHandlerWrapper(cmd, topic, useFactStorage, ct.WithPolicyEnforcement, ct.AuthorizeBeforeLoad, variants, adapters, objects, errors)
 		if err := gf.Save(genFile); err != nil {
 			return err
 		}
 
 	}
 
+	// 3. scheduling runner and dispatcher
+	schedulerFile := path.Join(genPath, "scheduler_gen.go")
+	if fileExists(schedulerFile) {
+		if err := os.Remove(schedulerFile); err != nil {
+			return err
+		}
+	}
+	if variants.UseScheduling {
+		log.Printf("%s: generating scheduler\n", sourceTypeName)
+		gf := generator.GenScheduler(sourceTypeName, cmds, adapters, objects, errors)
+		if err := gf.Save(schedulerFile); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -6,7 +6,6 @@ package gen_domain
 import (
 	"fmt"
 	"go/types"
-	"log"
 	"reflect"
 	"regexp"
 	"strings"
@@ -29,9 +28,11 @@ var (
 	structSetterTagPattern   = regexp.MustCompile(`setter`)
 	structStringerTagPattern = regexp.MustCompile(`stringer`)
 	structEqualTagPattern    = regexp.MustCompile(`equal(,reflect)?`)
+	structCopyTagPattern     = regexp.MustCompile(`(?:^|;)copy(?:;|$)`)
+	structNoCopyTagPattern   = regexp.MustCompile(`(?:^|;)nocopy(?:;|$)`)
 )
 
-func generateEntityHelperMethods(f *jen.File, typ, validatorMethod string, typStruct *types.Struct) (err error) {
+func generateEntityHelperMethods(f *jen.File, typ, validatorMethod string, typStruct *types.Struct, useSnapshots bool) (err error) {
 	// Add a package comment, so IDEs detect files as generated
 	f.PackageComment("Code generated by 'ddd-gen domain entity', DO NOT EDIT.")
 
@@ -44,24 +45,44 @@ func generateEntityHelperMethods(f *jen.File, typ, validatorMethod string, typSt
 		genGetterFields   []generator.QualField
 		genSetterFields   []generator.QualField
 		genStringerFields []generator.QualField
+		copier            = generator.NewCopier(typ, goPackagePath)
 	)
 
 	// 2. iterate over struct fields and populate those variables
 	for i := 0; i < typStruct.NumFields(); i++ {
 		fld := typStruct.Field(i)
 
-		// 2.1 error if pointer field encountered
-		if _, ok := fld.Type().(*types.Pointer); ok {
-			return fmt.Errorf("%s type is a pointer - can evade validation", fld.Name())
+		field, err := getRelativeQualField(fld)
+		if err != nil {
+			return err
 		}
-
-		field := getRelativeQualField(fld)
 		tag := reflect.StructTag(typStruct.Tag(i))
+		structTagEntityKeyValue, hasTag := tag.Lookup(structTagEntityKey)
+
+		// 2.1 error if pointer field encountered, unless it is deep copied
+		// slices and maps are deep copied, unless opted out (e.g. on hot paths)
+		_, isPointer := fld.Type().(*types.Pointer)
+		copyTag := hasTag && structCopyTagPattern.MatchString(structTagEntityKeyValue)
+		noCopyTag := hasTag && structNoCopyTagPattern.MatchString(structTagEntityKeyValue)
+		switch {
+		case copyTag && noCopyTag:
+			return fmt.Errorf("%s is tagged both copy and nocopy", fld.Name())
+		case isPointer && !copyTag:
+			return fmt.Errorf("%s type is a pointer - can evade validation, tag it `entity:\"copy\"` to deep copy it", fld.Name())
+		case copyTag && copier.NeedsCopy(fld.Type()), !noCopyTag && isCollection(fld.Type()):
+			field.Copy = copier.Func(fld.Type())
+		}
 
+		// 2.2 error if a snapshot would lose the field's state
+		if useSnapshots {
+			if err := generator.JSONRoundTrip(fld.Type()); err != nil {
+				return fmt.Errorf("%s cannot be snapshotted: %w", fld.Name(), err)
+			}
+		}
 
-		// 2.2 match and classify fields according to tags
+		// 2.3 match and classify fields according to tags
 		var private bool
-		if structTagEntityKeyValue, ok := tag.Lookup(structTagEntityKey); ok {
+		if hasTag {
 			if matches := structGetterTagPattern.FindStringSubmatch(structTagEntityKeyValue); matches != nil {
 				genGetterFields = append(genGetterFields, field)
 			}
@@ -86,11 +107,8 @@ func generateEntityHelperMethods(f *jen.File, typ, validatorMethod string, typSt
 				validations = append(validations, generator.Validation{Field: field, ErrMsg: errMsg})
 			}
 			if equalMatches := structEqualTagPattern.FindStringSubmatch(structTagEntityKeyValue); equalMatches != nil {
-				if len(equalMatches) > 1 && equalMatches[1] != "" {
-					equalFlds = append(equalFlds, generator.EqualFld{Field: field, IsDeepEqual: true})
-				} else {
-					equalFlds = append(equalFlds, generator.EqualFld{Field: field, IsDeepEqual: false})
-				}
+				reflectTag := len(equalMatches) > 1 && equalMatches[1] != ""
+				equalFlds = append(equalFlds, generator.NewEqualFld(field, fld.Type(), reflectTag))
 			}
 		} else {
 			publicFlds = append(publicFlds, field)
@@ -107,6 +125,10 @@ func generateEntityHelperMethods(f *jen.File, typ, validatorMethod string, typSt
 	f.Comment("Marshalers ...")
 	f.Line()
 	generator.GenUnmarshalFromStore(f, typ, publicFlds, privateFlds)
+	generator.GenMarshalToStore(f, typ, publicFlds, privateFlds)
+	if useSnapshots {
+		generator.GenSnapshot(f, typ, publicFlds, privateFlds)
+	}
 
 	f.Comment("Accessors ...")
 	f.Line()
@@ -117,6 +139,9 @@ func generateEntityHelperMethods(f *jen.File, typ, validatorMethod string, typSt
 	f.Line()
 	generator.GenEqual(f, typ, equalFlds)
 	generator.GenStringer(f, typ, genStringerFields)
+	if err := copier.GenCopiers(f); err != nil {
+		return err
+	}
 
 	// Write generated file
 	return nil
@@ -126,34 +151,26 @@ func generateEntityApplyStub(g *jen.Group, typ string) {
 	_ = generator.GenApplyStub(g, typ)
 }
 
+// isCollection reports whether t is a slice or a map, which share their elements when assigned
+func isCollection(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Map:
+		return true
+	}
+	return false
+}
+
 func isPointer(s string) bool {
 	return strings.HasPrefix(s, "*")
 }
 
-func getRelativeQualField(field *types.Var) generator.QualField {
+func getRelativeQualField(field *types.Var) (generator.QualField, error) {
+	typ, err := generator.QualifiedType(field.Type())
+	if err != nil {
+		return generator.QualField{}, fmt.Errorf("%s: %w", field.Name(), err)
+	}
 	return generator.QualField{
 		Id:      field.Name(),
-		QualTyp: getQualifiedJenType(field.Type()),
-	}
-}
-
-func getQualifiedJenType(ft types.Type) *jen.Statement {
-	switch t := ft.(type) {
-	case *types.Basic:
-		return jen.Id(t.Name())
-	case *types.Array:
-		return jen.Index().Add(getQualifiedJenType(t.Elem()))
-	case *types.Slice:
-		return jen.Index().Add(getQualifiedJenType(t.Elem()))
-	case *types.Map:
-		return jen.Map(getQualifiedJenType(t.Key())).Add(getQualifiedJenType(t.Elem()))
-	case *types.Named:
-		return jen.Qual(
-			t.Obj().Pkg().Path(),
-			t.Obj().Name(),
-		)
-	default:
-		log.Printf("Unsupported field type: %v\n", ft)
-		return jen.Id("nil")
-	}
+		QualTyp: typ,
+	}, nil
 }
@@ -8,6 +8,7 @@ import "github.com/dave/jennifer/jen"
 type QualField struct {
 	Id      string
 	QualTyp *jen.Statement
+	Copy    string // deep copy function of the value, if the field is copied
 }
 
 type QualId struct {
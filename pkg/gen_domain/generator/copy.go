@@ -0,0 +1,258 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	. "github.com/dave/jennifer/jen"
+	"go/types"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Copier generates the deep copy functions of the field types of an entity
+// there is one function per type, so recursive types are copied by recursion
+type Copier struct {
+	typ     string
+	pkgPath string
+	funcs   map[string]string // type string -> function
+	structs map[string]int    // type string -> number of an anonymous struct
+	order   []types.Type
+}
+
+// NewCopier returns a Copier for the fields of the entity typ, declared in pkgPath
+func NewCopier(typ, pkgPath string) *Copier {
+	return &Copier{
+		typ:     typ,
+		pkgPath: pkgPath,
+		funcs:   make(map[string]string),
+		structs: make(map[string]int),
+	}
+}
+
+// NeedsCopy reports whether values of t share state when assigned: pointers, slices and
+// maps, as well as arrays and structs which (in an accessible field) contain one of them
+// structs with a Clone or Copy method need copy if any field contains one of them
+// interfaces, channels and functions are shared, they cannot be copied generically
+func (c *Copier) NeedsCopy(t types.Type) bool {
+	return c.needsCopy(t, false, make(map[types.Type]bool))
+}
+
+// holdsReferences reports whether values of t share state when assigned, including unexported fields
+func (c *Copier) holdsReferences(t types.Type) bool {
+	return c.needsCopy(t, true, make(map[types.Type]bool))
+}
+
+func (c *Copier) needsCopy(t types.Type, all bool, visiting map[types.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	switch u := t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map:
+		return true
+	case *types.Array:
+		return c.needsCopy(u.Elem(), all, visiting)
+	case *types.Struct:
+		all = all || cloneMethod(t) != ""
+		for i := 0; i < u.NumFields(); i++ {
+			if (all || c.accessible(u.Field(i))) && c.needsCopy(u.Field(i).Type(), all, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// accessible reports whether fld can be assigned from the package of the entity
+func (c *Copier) accessible(fld *types.Var) bool {
+	return fld.Exported() || fld.Pkg() != nil && fld.Pkg().Path() == c.pkgPath
+}
+
+// cloneMethod returns the name of the Clone or Copy method which returns a deep copy of t, if any
+// it is looked up on *t as well, since the copy functions receive addressable values
+func cloneMethod(t types.Type) string {
+	ms := methodSet(t, true)
+	for _, name := range []string{"Clone", "Copy"} {
+		sel := ms.Lookup(nil, name)
+		if sel == nil {
+			continue
+		}
+		sig, ok := sel.Type().(*types.Signature)
+		if ok && sig.Params().Len() == 0 && sig.Results().Len() == 1 && types.Identical(sig.Results().At(0).Type(), t) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Func returns the name of the deep copy function of t, which needs copy
+func (c *Copier) Func(t types.Type) string {
+	key := types.TypeString(t, nil)
+	if name, ok := c.funcs[key]; ok {
+		return name
+	}
+	name := "copy" + c.typ + c.typName(t)
+	c.funcs[key] = name
+	c.order = append(c.order, t)
+	return name
+}
+
+// typName names a type for the name of its copy function
+func (c *Copier) typName(t types.Type) string {
+	switch u := t.(type) {
+	case *types.Named:
+		if u.Obj().Pkg() != nil && u.Obj().Pkg().Path() != c.pkgPath {
+			return strings.Title(u.Obj().Pkg().Name()) + u.Obj().Name()
+		}
+		return strings.Title(u.Obj().Name())
+	case *types.Basic:
+		return strings.Title(u.Name())
+	case *types.Pointer:
+		return "Ptr" + c.typName(u.Elem())
+	case *types.Slice:
+		return "Slice" + c.typName(u.Elem())
+	case *types.Array:
+		return "Array" + strconv.FormatInt(u.Len(), 10) + c.typName(u.Elem())
+	case *types.Map:
+		return "Map" + c.typName(u.Key()) + c.typName(u.Elem())
+	case *types.Struct:
+		// anonymous structs are numbered in the order they are named
+		key := types.TypeString(u, nil)
+		if _, ok := c.structs[key]; !ok {
+			c.structs[key] = len(c.structs) + 1
+		}
+		return "Struct" + strconv.Itoa(c.structs[key])
+	default:
+		return "Value"
+	}
+}
+
+// copyOf returns the deep copy of v, of type t
+func (c *Copier) copyOf(t types.Type, v *Statement) *Statement {
+	if !c.NeedsCopy(t) {
+		return v
+	}
+	return Id(c.Func(t)).Call(v)
+}
+
+// copyable reports why the pointer t cannot be deep copied field by field: a pointee of another package
+// which holds references in unexported fields (e.g. *big.Int) needs a Clone or Copy method
+// values of such structs (e.g. time.Time) are copied by assignment, as their package intends
+func (c *Copier) copyable(t types.Type) error {
+	p, ok := t.Underlying().(*types.Pointer)
+	if !ok || cloneMethod(t) != "" || cloneMethod(p.Elem()) != "" {
+		return nil
+	}
+	u, ok := p.Elem().Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < u.NumFields(); i++ {
+		if fld := u.Field(i); !c.accessible(fld) && c.holdsReferences(fld.Type()) {
+			return fmt.Errorf("unexported field %s holds references and %s has neither a Clone nor a Copy method", fld.Name(), p.Elem())
+		}
+	}
+	return nil
+}
+
+// copyBody generates the body of the deep copy function of t, rendered as typ, which copies v
+// a Clone or Copy method of t is trusted to return a deep copy
+func (c *Copier) copyBody(g *Group, t types.Type, typ *Statement) {
+	if m := cloneMethod(t); m != "" {
+		if _, ok := t.Underlying().(*types.Pointer); ok {
+			g.If(Id("v").Op("==").Nil()).Block(
+				Return(Nil()),
+			)
+		}
+		g.Return(Id("v").Dot(m).Call())
+		return
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		g.If(Id("v").Op("==").Nil()).Block(
+			Return(Nil()),
+		)
+		g.Id("c").Op(":=").Add(c.copyOf(u.Elem(), Op("*").Id("v")))
+		if _, ok := t.(*types.Named); ok {
+			g.Return(typ.Clone().Call(Op("&").Id("c")))
+		} else {
+			g.Return(Op("&").Id("c"))
+		}
+	case *types.Slice:
+		g.If(Id("v").Op("==").Nil()).Block(
+			Return(Nil()),
+		)
+		g.Id("c").Op(":=").Make(typ.Clone(), Len(Id("v")))
+		if c.NeedsCopy(u.Elem()) {
+			g.For(List(Id("i"), Id("e")).Op(":=").Range().Id("v")).Block(
+				Id("c").Index(Id("i")).Op("=").Add(c.copyOf(u.Elem(), Id("e"))),
+			)
+		} else {
+			g.Copy(Id("c"), Id("v"))
+		}
+		g.Return(Id("c"))
+	case *types.Map:
+		g.If(Id("v").Op("==").Nil()).Block(
+			Return(Nil()),
+		)
+		g.Id("c").Op(":=").Make(typ.Clone(), Len(Id("v")))
+		g.For(List(Id("k"), Id("e")).Op(":=").Range().Id("v")).Block(
+			Id("c").Index(Id("k")).Op("=").Add(c.copyOf(u.Elem(), Id("e"))),
+		)
+		g.Return(Id("c"))
+	case *types.Array:
+		g.Id("c").Op(":=").Id("v")
+		g.For(List(Id("i"), Id("e")).Op(":=").Range().Id("v")).Block(
+			Id("c").Index(Id("i")).Op("=").Add(c.copyOf(u.Elem(), Id("e"))),
+		)
+		g.Return(Id("c"))
+	case *types.Struct:
+		g.Id("c").Op(":=").Id("v")
+		for i := 0; i < u.NumFields(); i++ {
+			fld := u.Field(i)
+			if c.accessible(fld) && c.NeedsCopy(fld.Type()) {
+				g.Id("c").Dot(fld.Name()).Op("=").Add(c.copyOf(fld.Type(), Id("v").Dot(fld.Name())))
+			}
+		}
+		g.Return(Id("c"))
+	}
+}
+
+// GenCopiers generates the deep copy functions of all types requested via Func
+// functions requested while generating are generated as well
+func (c *Copier) GenCopiers(f *File) error {
+	for i := 0; i < len(c.order); i++ {
+		t := c.order[i]
+		name := c.funcs[types.TypeString(t, nil)]
+		typ, err := QualifiedType(t)
+		if err != nil {
+			return fmt.Errorf("cannot deep copy %s: %w", t, err)
+		}
+
+		if err := c.copyable(t); err != nil {
+			return fmt.Errorf("cannot deep copy %s: %w", t, err)
+		}
+
+		log.Printf("%s: generating '%s()'\n", c.typ, name)
+
+		f.Commentf("%s returns a deep copy of v", name)
+		f.Func().Id(name).Params(
+			Id("v").Add(typ.Clone()),
+		).Add(typ.Clone()).BlockFunc(func(g *Group) {
+			c.copyBody(g, t, typ)
+		})
+	}
+	return nil
+}
+
+// copied returns v, deep copied if the field is copied
+func copied(field QualField, v *Statement) *Statement {
+	if field.Copy == "" {
+		return v
+	}
+	return Id(field.Copy).Call(v)
+}
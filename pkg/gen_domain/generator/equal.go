@@ -5,12 +5,49 @@ package generator
 
 import (
 	. "github.com/dave/jennifer/jen"
+	"go/types"
 	"log"
 )
 
 type EqualFld struct {
 	Field       QualField
 	IsDeepEqual bool
+	IsPointer   bool // compares the pointed-to values
+}
+
+// NewEqualFld returns how field, of type t, is compared, deep forces reflect.DeepEqual
+// values which refer to other values (pointers, slices, maps, interfaces, ...) are compared deeply,
+// a pointer is dereferenced if the pointed-to value is compared by == as a whole
+func NewEqualFld(field QualField, t types.Type, deep bool) EqualFld {
+	ptr, isPointer := t.(*types.Pointer)
+	switch {
+	case deep:
+		return EqualFld{Field: field, IsDeepEqual: true}
+	case isPointer && comparedWhole(ptr.Elem()):
+		return EqualFld{Field: field, IsPointer: true}
+	case !comparedWhole(t):
+		return EqualFld{Field: field, IsDeepEqual: true}
+	}
+	return EqualFld{Field: field}
+}
+
+// comparedWhole reports whether == compares all of the state of values of t
+// i.e. t is comparable and neither is nor contains a pointer, interface or channel
+func comparedWhole(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return true
+	case *types.Array:
+		return comparedWhole(u.Elem())
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if !comparedWhole(u.Field(i).Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
 }
 
 func GenEqual(f *File, typ string, flds []EqualFld) {
@@ -55,6 +92,21 @@ func GenEqual(f *File, typ string, flds []EqualFld) {
 						),
 					),
 				)
+			} else if fld.IsPointer {
+				g.If(
+					Parens(Id(shortForm(typ)).Dot(field.Id).Op("==").Nil()).Op("!=").Parens(Id("other").Dot(field.Id).Op("==").Nil()),
+				).Block(
+					Return(
+						Id("false"),
+					),
+				)
+				g.If(
+					Id(shortForm(typ)).Dot(field.Id).Op("!=").Nil().Op("&&").Op("*").Id(shortForm(typ)).Dot(field.Id).Op("!=").Op("*").Id("other").Dot(field.Id),
+				).Block(
+					Return(
+						Id("false"),
+					),
+				)
 			} else {
 				g.If(
 					Id(shortForm(typ)).Dot(field.Id).Op("!=").Id("other").Dot(field.Id),
@@ -15,7 +15,11 @@ func GenGetters(f *File, typ string, flds []QualField) {
 
 		log.Printf("%s: generating '%s()'\n", typ, strings.Title(field.Id))
 
-		f.Commentf("%s returns %s value", strings.Title(field.Id), field.Id)
+		if field.Copy != "" {
+			f.Commentf("%s returns a deep copy of %s value", strings.Title(field.Id), field.Id)
+		} else {
+			f.Commentf("%s returns %s value", strings.Title(field.Id), field.Id)
+		}
 
 		f.Func().Params(
 			Id(shortForm(typ)).Op("*").Id(typ),
@@ -25,7 +29,7 @@ func GenGetters(f *File, typ string, flds []QualField) {
 			field.QualTyp,
 		).Block(
 			Return(
-				Id(shortForm(typ)).Dot(field.Id),
+				copied(field, Id(shortForm(typ)).Dot(field.Id)),
 			),
 		)
 	}
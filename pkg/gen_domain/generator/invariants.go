@@ -14,8 +14,14 @@ const (
 	MustNew                   = "MustNew"
 	Equal                     = "Equal"
 	UnmarshalFromStore        = "UnmarshalFromStore"
+	MarshalToStore            = "MarshalToStore"
+	MarshalSnapshot           = "MarshalSnapshot"
+	UnmarshalSnapshot         = "UnmarshalSnapshot"
 	Apply                     = "Apply"
 
+	// Value
+	WithPrefix = "With"
+
 	// DomainCommandHandler
 	Handle      = "Handle"
 	Facts       = "Facts"
@@ -0,0 +1,108 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"reflect"
+)
+
+// JSONRoundTrip reports why a field of type t does not survive a round trip through encoding/json,
+// or nil if it does: interfaces, channels, functions and complex numbers cannot be decoded, unexported
+// and skipped fields are lost, unless t implements json.Marshaler and json.Unmarshaler (or the text variants)
+func JSONRoundTrip(t types.Type) error {
+	return jsonRoundTrip(t, true, make(map[types.Type]bool))
+}
+
+// jsonRoundTrip checks t, addressable reports whether encoding/json sees values of t through a pointer
+func jsonRoundTrip(t types.Type, addressable bool, visiting map[types.Type]bool) error {
+	if _, ok := t.Underlying().(*types.Interface); !ok && marshals(t, addressable) {
+		return nil
+	}
+	if visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if u.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) == 0 {
+			return fmt.Errorf("%s cannot be encoded", t)
+		}
+	case *types.Pointer:
+		return jsonRoundTrip(u.Elem(), true, visiting)
+	case *types.Slice:
+		return jsonRoundTrip(u.Elem(), true, visiting)
+	case *types.Array:
+		return jsonRoundTrip(u.Elem(), addressable, visiting)
+	case *types.Map:
+		// map keys and elements are not addressable
+		k, ok := u.Key().Underlying().(*types.Basic)
+		if !(ok && k.Info()&(types.IsInteger|types.IsString) != 0) && !hasMethods(u.Key(), false, textMarshaler) {
+			return fmt.Errorf("%s has keys which cannot be encoded", t)
+		}
+		return jsonRoundTrip(u.Elem(), false, visiting)
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			fld := u.Field(i)
+			if reflect.StructTag(u.Tag(i)).Get("json") == "-" {
+				return fmt.Errorf("%s skips the field %s", t, fld.Name())
+			}
+			if !fld.Exported() {
+				// only the fields of embedded structs are promoted
+				if _, ok := fld.Type().Underlying().(*types.Struct); !ok || !fld.Anonymous() {
+					return fmt.Errorf("%s has the unexported field %s", t, fld.Name())
+				}
+			}
+			if err := jsonRoundTrip(fld.Type(), addressable, visiting); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s cannot be decoded", t)
+	}
+	return nil
+}
+
+// the signatures of the marshaler methods encoding/json looks for, by name
+var (
+	marshalSig = types.NewSignature(nil, nil, types.NewTuple(
+		types.NewVar(token.NoPos, nil, "", types.NewSlice(types.Typ[types.Byte])),
+		types.NewVar(token.NoPos, nil, "", types.Universe.Lookup("error").Type()),
+	), false)
+	unmarshalSig = types.NewSignature(nil, types.NewTuple(
+		types.NewVar(token.NoPos, nil, "", types.NewSlice(types.Typ[types.Byte])),
+	), types.NewTuple(
+		types.NewVar(token.NoPos, nil, "", types.Universe.Lookup("error").Type()),
+	), false)
+	jsonMarshaler = map[string]*types.Signature{"MarshalJSON": marshalSig, "UnmarshalJSON": unmarshalSig}
+	textMarshaler = map[string]*types.Signature{"MarshalText": marshalSig, "UnmarshalText": unmarshalSig}
+)
+
+// marshals reports whether t encodes and decodes itself
+func marshals(t types.Type, addressable bool) bool {
+	return hasMethods(t, addressable, jsonMarshaler) || hasMethods(t, addressable, textMarshaler)
+}
+
+// hasMethods reports whether t has the methods of the signatures by name
+// unmarshalers are always called on a pointer, marshalers only if t is addressable
+func hasMethods(t types.Type, addressable bool, sigs map[string]*types.Signature) bool {
+	for name, sig := range sigs {
+		sel := methodSet(t, addressable || name[:2] == "Un").Lookup(nil, name)
+		if sel == nil || !types.Identical(sel.Type(), sig) {
+			return false
+		}
+	}
+	return true
+}
+
+// methodSet returns the method set of t, or of *t if t is addressable
+func methodSet(t types.Type, addressable bool) *types.MethodSet {
+	if _, ok := t.Underlying().(*types.Pointer); addressable && !ok {
+		return types.NewMethodSet(types.NewPointer(t))
+	}
+	return types.NewMethodSet(t)
+}
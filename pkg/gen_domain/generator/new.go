@@ -61,7 +61,7 @@ func GenNew(f *File, typ string, publicFlds []QualField, validations []Validatio
 		).Values(
 			DictFunc(func(d Dict) {
 				for _, field := range publicFlds {
-					d[Id(field.Id)] = Id(field.Id)
+					d[Id(field.Id)] = copied(field, Id(field.Id))
 				}
 			}),
 		)
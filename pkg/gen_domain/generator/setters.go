@@ -16,7 +16,11 @@ func GenSetters(f *File, typ string, flds []QualField) {
 
 		log.Printf("%s: generating '%s()'\n", typ, fN)
 
-		f.Commentf("%s sets %s value", fN, field.Id)
+		if field.Copy != "" {
+			f.Commentf("%s sets %s value to a deep copy", fN, field.Id)
+		} else {
+			f.Commentf("%s sets %s value", fN, field.Id)
+		}
 
 		f.Func().Params(
 			Id(
@@ -35,8 +39,8 @@ func GenSetters(f *File, typ string, flds []QualField) {
 				shortForm(typ),
 			).Dot(
 				field.Id,
-			).Op("=").Id(
-				field.Id,
+			).Op("=").Add(
+				copied(field, Id(field.Id)),
 			),
 		)
 	}
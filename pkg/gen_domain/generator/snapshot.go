@@ -0,0 +1,98 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	. "github.com/dave/jennifer/jen"
+	"log"
+	"strings"
+)
+
+func snapshotTyp(typ string) string {
+	return strings.ToLower(typ[:1]) + typ[1:] + "Snapshot"
+}
+
+func GenSnapshot(f *File, typ string, publicFlds, privateFlds []QualField) {
+
+	allFlds := append(publicFlds, privateFlds...)
+
+	f.Commentf("%s is the full state of %s, including private fields", snapshotTyp(typ), typ)
+	f.Type().Id(
+		snapshotTyp(typ),
+	).StructFunc(func(g *Group) {
+		for _, field := range allFlds {
+			g.Id(
+				strings.Title(field.Id),
+			).Add(
+				field.QualTyp,
+			).Tag(map[string]string{"json": field.Id})
+		}
+	})
+
+	log.Printf("%s: generating '%s()'\n", typ, MarshalSnapshot)
+
+	f.Commentf("%s marshals the full state of %s, including private fields, into a snapshot", MarshalSnapshot, typ)
+	f.Commentf("the repository may persist it to avoid replaying all facts through %s", Apply)
+
+	f.Func().Params(
+		Id(shortForm(typ)).Op("*").Id(typ),
+	).Id(
+		MarshalSnapshot,
+	).Params().Params(
+		Index().Byte(),
+		Error(),
+	).Block(
+		Return(
+			Qual("encoding/json", "Marshal").Call(
+				Op("&").Id(snapshotTyp(typ)).ValuesFunc(func(g *Group) {
+					for _, field := range allFlds {
+						g.Id(strings.Title(field.Id)).Op(":").Id(shortForm(typ)).Dot(field.Id)
+					}
+				}),
+			),
+		),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, UnmarshalSnapshot)
+
+	f.Commentf("%s unmarshals %s from a snapshot taken by %s", UnmarshalSnapshot, typ, MarshalSnapshot)
+	f.Commentf("public fields are validated like %s, private fields are initialized the same way as %s", Neww, UnmarshalFromStore)
+	f.Comment("")
+	f.Comment("Important: DO NEVER USE THIS METHOD EXCEPT FROM THE REPOSITORY")
+	f.Comment("Reason: This method initializes private state, so you could corrupt the domain.")
+
+	f.Func().Id(
+		UnmarshalSnapshot,
+	).Params(
+		Id("data").Index().Byte(),
+	).Params(
+		Op("*").Id(typ),
+		Error(),
+	).BlockFunc(func(g *Group) {
+		g.Var().Id("snapshot").Id(snapshotTyp(typ))
+		g.If(
+			Id("err").Op(":=").Qual("encoding/json", "Unmarshal").Call(Id("data"), Op("&").Id("snapshot")),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Id("err")),
+		)
+		g.List(Id(shortForm(typ)), Id("err")).Op(":=").Id(Neww).CallFunc(func(g *Group) {
+			for _, field := range publicFlds {
+				g.Id("snapshot").Dot(strings.Title(field.Id))
+			}
+		})
+		g.If(
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Id("err")),
+		)
+		for _, field := range privateFlds {
+			g.Id(shortForm(typ)).Dot(field.Id).Op("=").Id("snapshot").Dot(strings.Title(field.Id))
+		}
+		g.Return(
+			Id(shortForm(typ)),
+			Nil(),
+		)
+	})
+}
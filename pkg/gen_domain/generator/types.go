@@ -0,0 +1,78 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"go/types"
+
+	. "github.com/dave/jennifer/jen"
+)
+
+// QualifiedType renders t with package qualified named types
+// it returns an error for types which cannot be spelled out, e.g. channels, functions or non-empty interface literals
+func QualifiedType(t types.Type) (*Statement, error) {
+	switch t := t.(type) {
+	case *types.Basic:
+		return Id(t.Name()), nil
+	case *types.Named:
+		// predeclared types, e.g. error, have no package
+		if t.Obj().Pkg() == nil {
+			return Id(t.Obj().Name()), nil
+		}
+		return Qual(t.Obj().Pkg().Path(), t.Obj().Name()), nil
+	case *types.Pointer:
+		elem, err := QualifiedType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return Op("*").Add(elem), nil
+	case *types.Slice:
+		elem, err := QualifiedType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return Index().Add(elem), nil
+	case *types.Array:
+		elem, err := QualifiedType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return Index(Lit(int(t.Len()))).Add(elem), nil
+	case *types.Map:
+		key, err := QualifiedType(t.Key())
+		if err != nil {
+			return nil, err
+		}
+		elem, err := QualifiedType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return Map(key).Add(elem), nil
+	case *types.Struct:
+		var flds []Code
+		for i := 0; i < t.NumFields(); i++ {
+			fld := t.Field(i)
+			typ, err := QualifiedType(fld.Type())
+			if err != nil {
+				return nil, err
+			}
+			s := Add(typ)
+			if !fld.Embedded() {
+				s = Id(fld.Name()).Add(typ)
+			}
+			// the tag is part of the type identity, so it is kept verbatim
+			if tag := t.Tag(i); tag != "" {
+				s.Lit(tag)
+			}
+			flds = append(flds, s)
+		}
+		return Struct(flds...), nil
+	case *types.Interface:
+		if t.NumMethods() == 0 {
+			return Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
@@ -41,10 +41,43 @@ func GenUnmarshalFromStore(f *File, typ string, publicFlds, privateFlds []QualFi
 			}
 		})
 		for _, field := range privateFlds {
-			g.Id(shortForm(typ)).Dot(field.Id).Op("=").Id(field.Id)
+			g.Id(shortForm(typ)).Dot(field.Id).Op("=").Add(copied(field, Id(field.Id)))
 		}
 		g.Return(
 			Id(shortForm(typ)),
 		)
 	})
 }
+
+func GenMarshalToStore(f *File, typ string, publicFlds, privateFlds []QualField) {
+
+	allFlds := append(publicFlds, privateFlds...)
+
+	log.Printf("%s: generating '%s()'\n", typ, MarshalToStore)
+
+	f.Commentf("%s marshals %s to the repository, including private fields", MarshalToStore, typ)
+	f.Commentf("it returns the fields in the order %s expects them", UnmarshalFromStore)
+	f.Comment("")
+	f.Comment("Important: DO NEVER USE THIS METHOD EXCEPT FROM THE REPOSITORY")
+	f.Comment("Reason: This method exposes private state, so you could leak the domain.")
+
+	f.Func().Params(
+		Id(shortForm(typ)).Op("*").Id(typ),
+	).Id(
+		MarshalToStore,
+	).Params().ParamsFunc(func(g *Group) {
+		for _, field := range allFlds {
+			g.Id(
+				field.Id,
+			).Add(
+				field.QualTyp,
+			)
+		}
+	}).BlockFunc(func(g *Group) {
+		g.ReturnFunc(func(g *Group) {
+			for _, field := range allFlds {
+				g.Add(copied(field, Id(shortForm(typ)).Dot(field.Id)))
+			}
+		})
+	})
+}
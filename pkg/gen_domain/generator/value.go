@@ -0,0 +1,293 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	. "github.com/dave/jennifer/jen"
+	"log"
+	"strings"
+)
+
+func valueNew(typ string) string     { return Neww + typ }
+func valueMustNew(typ string) string { return MustNew + typ }
+func valueJSONTyp(typ string) string { return strings.ToLower(typ[:1]) + typ[1:] + "JSON" }
+
+// valueFields returns the fields of v in declaration order
+func valueFields(v string, flds []QualField) []Code {
+	var codes []Code
+	for _, field := range flds {
+		codes = append(codes, Id(v).Dot(field.Id))
+	}
+	return codes
+}
+
+func GenValueNew(f *File, typ string, flds []QualField, validations []Validation, validatorMethod string) {
+
+	log.Printf("%s: generating '%s()'\n", typ, valueNew(typ))
+
+	f.Commentf("%s returns a guaranteed-to-be-valid %s or an error", valueNew(typ), typ)
+
+	f.Func().Id(
+		valueNew(typ),
+	).ParamsFunc(func(g *Group) {
+		for _, field := range flds {
+			g.Id(field.Id).Add(field.QualTyp)
+		}
+	}).Params(
+		Id(typ),
+		Id("error"),
+	).BlockFunc(func(g *Group) {
+		for _, fld := range validations {
+			g.If(
+				Qual("reflect", "ValueOf").Call(Id(fld.Field.Id)).Dot("IsZero").Call(),
+			).Block(
+				Return(
+					Id(typ).Values(),
+					Qual("errors", "New").Call(Lit(fld.ErrMsg)),
+				),
+			)
+		}
+		g.Id(shortForm(typ)).Op(":=").Id(typ).Values(
+			DictFunc(func(d Dict) {
+				for _, field := range flds {
+					d[Id(field.Id)] = copied(field, Id(field.Id))
+				}
+			}),
+		)
+		if validatorMethod != "" {
+			g.If(
+				Id("err").Op(":=").Id(shortForm(typ)).Dot(validatorMethod).Call(),
+				Id("err").Op("!=").Id("nil"),
+			).Block(
+				Return(
+					Id(typ).Values(),
+					Id("err"),
+				),
+			)
+		}
+		g.Return(
+			Id(shortForm(typ)),
+			Id("nil"),
+		)
+	})
+
+	log.Printf("%s: generating '%s()'\n", typ, valueMustNew(typ))
+
+	f.Commentf("%s returns a guaranteed-to-be-valid %s or panics", valueMustNew(typ), typ)
+
+	f.Func().Id(
+		valueMustNew(typ),
+	).ParamsFunc(func(g *Group) {
+		for _, field := range flds {
+			g.Id(field.Id).Add(field.QualTyp)
+		}
+	}).Id(typ).Block(
+		List(
+			Id(shortForm(typ)),
+			Id("err"),
+		).Op(":=").Id(valueNew(typ)).CallFunc(func(g *Group) {
+			for _, field := range flds {
+				g.Id(field.Id)
+			}
+		}),
+		If(
+			Id("err").Op("!=").Id("nil"),
+		).Block(
+			Panic(Id("err")),
+		),
+		Return(
+			Id(shortForm(typ)),
+		),
+	)
+}
+
+func GenValueGetters(f *File, typ string, flds []QualField) {
+
+	for _, field := range flds {
+
+		log.Printf("%s: generating '%s()'\n", typ, strings.Title(field.Id))
+
+		if field.Copy != "" {
+			f.Commentf("%s returns a deep copy of %s value", strings.Title(field.Id), field.Id)
+		} else {
+			f.Commentf("%s returns %s value", strings.Title(field.Id), field.Id)
+		}
+
+		f.Func().Params(
+			Id(shortForm(typ)).Id(typ),
+		).Id(
+			strings.Title(field.Id),
+		).Params().Add(
+			field.QualTyp,
+		).Block(
+			Return(
+				copied(field, Id(shortForm(typ)).Dot(field.Id)),
+			),
+		)
+	}
+}
+
+func GenValueWithers(f *File, typ string, flds []QualField) {
+
+	for _, field := range flds {
+		fN := WithPrefix + strings.Title(field.Id)
+
+		log.Printf("%s: generating '%s()'\n", typ, fN)
+
+		f.Commentf("%s returns a copy of %s with %s changed, validated like %s", fN, shortForm(typ), field.Id, valueNew(typ))
+		f.Commentf("%s itself is left unchanged", shortForm(typ))
+
+		f.Func().Params(
+			Id(shortForm(typ)).Id(typ),
+		).Id(fN).Params(
+			Id(field.Id).Add(field.QualTyp),
+		).Params(
+			Id(typ),
+			Id("error"),
+		).Block(
+			Return(
+				Id(valueNew(typ)).CallFunc(func(g *Group) {
+					for _, other := range flds {
+						if other.Id == field.Id {
+							g.Id(field.Id)
+						} else {
+							g.Id(shortForm(typ)).Dot(other.Id)
+						}
+					}
+				}),
+			),
+		)
+	}
+}
+
+func GenValueStringer(f *File, typ string, flds []QualField) {
+
+	log.Printf("%s: generating '%s()'\n", typ, Stringer)
+
+	f.Commentf("%s implements the fmt.Stringer interface and returns the native format of %s", Stringer, typ)
+
+	f.Func().Params(
+		Id(shortForm(typ)).Id(typ),
+	).Id(
+		Stringer,
+	).Params().Id(
+		"string",
+	).Block(
+		Return(
+			Qual("fmt", "Sprintf").Call(
+				append(
+					[]Code{Lit(strings.TrimSpace(strings.Repeat("%v ", len(flds))))},
+					valueFields(shortForm(typ), flds)...,
+				)...,
+			),
+		),
+	)
+}
+
+// GenValueTextMarshalers generates the text marshalers of a value with a single string field
+// unmarshaling validates the text like the constructor
+func GenValueTextMarshalers(f *File, typ string, field QualField) {
+
+	log.Printf("%s: generating '%s()'\n", typ, "MarshalText")
+
+	f.Comment("MarshalText implements the encoding.TextMarshaler interface")
+
+	f.Func().Params(
+		Id(shortForm(typ)).Id(typ),
+	).Id("MarshalText").Params().Params(
+		Index().Byte(),
+		Error(),
+	).Block(
+		Return(
+			Index().Byte().Call(Id(shortForm(typ)).Dot(field.Id)),
+			Nil(),
+		),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, "UnmarshalText")
+
+	f.Commentf("UnmarshalText implements the encoding.TextUnmarshaler interface, the text is validated like %s", valueNew(typ))
+
+	f.Func().Params(
+		Id(shortForm(typ)).Op("*").Id(typ),
+	).Id("UnmarshalText").Params(
+		Id("text").Index().Byte(),
+	).Error().Block(
+		List(Id("valid"), Id("err")).Op(":=").Id(valueNew(typ)).Call(
+			Add(field.QualTyp).Call(Id("text")),
+		),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Id("err")),
+		),
+		Op("*").Id(shortForm(typ)).Op("=").Id("valid"),
+		Return(Nil()),
+	)
+}
+
+// GenValueJSONMarshalers generates the json marshalers of a value, which encode its fields
+// unmarshaling validates the fields like the constructor
+func GenValueJSONMarshalers(f *File, typ string, flds []QualField) {
+
+	f.Commentf("%s is the json encoding of %s", valueJSONTyp(typ), typ)
+	f.Type().Id(
+		valueJSONTyp(typ),
+	).StructFunc(func(g *Group) {
+		for _, field := range flds {
+			g.Id(
+				strings.Title(field.Id),
+			).Add(
+				field.QualTyp,
+			).Tag(map[string]string{"json": field.Id})
+		}
+	})
+
+	log.Printf("%s: generating '%s()'\n", typ, "MarshalJSON")
+
+	f.Comment("MarshalJSON implements the json.Marshaler interface")
+
+	f.Func().Params(
+		Id(shortForm(typ)).Id(typ),
+	).Id("MarshalJSON").Params().Params(
+		Index().Byte(),
+		Error(),
+	).Block(
+		Return(
+			Qual("encoding/json", "Marshal").Call(
+				Id(valueJSONTyp(typ)).ValuesFunc(func(g *Group) {
+					for _, field := range flds {
+						g.Id(strings.Title(field.Id)).Op(":").Id(shortForm(typ)).Dot(field.Id)
+					}
+				}),
+			),
+		),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, "UnmarshalJSON")
+
+	f.Commentf("UnmarshalJSON implements the json.Unmarshaler interface, the fields are validated like %s", valueNew(typ))
+
+	f.Func().Params(
+		Id(shortForm(typ)).Op("*").Id(typ),
+	).Id("UnmarshalJSON").Params(
+		Id("data").Index().Byte(),
+	).Error().Block(
+		Var().Id("decoded").Id(valueJSONTyp(typ)),
+		If(
+			Id("err").Op(":=").Qual("encoding/json", "Unmarshal").Call(Id("data"), Op("&").Id("decoded")),
+			Id("err").Op("!=").Nil(),
+		).Block(
+			Return(Id("err")),
+		),
+		List(Id("valid"), Id("err")).Op(":=").Id(valueNew(typ)).CallFunc(func(g *Group) {
+			for _, field := range flds {
+				g.Id("decoded").Dot(strings.Title(field.Id))
+			}
+		}),
+		If(Id("err").Op("!=").Nil()).Block(
+			Return(Id("err")),
+		),
+		Op("*").Id(shortForm(typ)).Op("=").Id("valid"),
+		Return(Nil()),
+	)
+}
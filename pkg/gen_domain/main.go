@@ -15,6 +15,7 @@ import (
 	"go/token"
 	"go/types"
 	"path/filepath"
+	"strings"
 
 	"github.com/dave/jennifer/jen"
 
@@ -31,7 +32,7 @@ var (
 	pkg            *packages.Package
 )
 
-func GenEntity(typ, validatorMethod string) (err error) {
+func GenEntity(typ, validatorMethod string, useSnapshots bool) (err error) {
 	var (
 		f          *jen.File
 		ok         bool
@@ -40,7 +41,7 @@ func GenEntity(typ, validatorMethod string) (err error) {
 		sourceFile *os.File
 	)
 
-	err = initMain()
+	err = initMain("")
 	if err != nil {
 		return err
 	}
@@ -65,7 +66,7 @@ func GenEntity(typ, validatorMethod string) (err error) {
 	log.Printf("Generating code for: %s.%s\n", goPackagePath, typ)
 	f = jen.NewFilePathName(goPackagePath, goPackage)
 	// Generate code using jennifer
-	err = generateEntityHelperMethods(f, typ, validatorMethod, typStruct)
+	err = generateEntityHelperMethods(f, typ, validatorMethod, typStruct, useSnapshots)
 	if err != nil {
 		return err
 	}
@@ -102,11 +103,56 @@ func GenEntity(typ, validatorMethod string) (err error) {
 	return nil
 }
 
+func GenValue(typ, validatorMethod string) (err error) {
+	var (
+		f         *jen.File
+		ok        bool
+		obj       types.Object
+		typStruct *types.Struct
+	)
+
+	// values are usually declared side by side, each gets a generated file of its own
+	genSuffix := ""
+	if base := strings.ToLower(typ); base != strings.TrimSuffix(os.Getenv("GOFILE"), ".go") {
+		genSuffix = "_" + base
+	}
+	err = initMain(genSuffix)
+	if err != nil {
+		return err
+	}
+
+	// Lookup the given source type name in the package declarations
+	obj = pkg.Types.Scope().Lookup(typ)
+	if obj == nil {
+		return fmt.Errorf("%s not found in declared types of %s",
+			typ, pkg)
+	}
+
+	// We check if it is a declared type
+	if _, ok = obj.(*types.TypeName); !ok {
+		return fmt.Errorf("%v is not a named type", obj)
+	}
+	// We expect the underlying type to be a struct
+	typStruct, ok = obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("type %v is not a struct", obj)
+	}
+
+	log.Printf("Generating code for: %s.%s\n", goPackagePath, typ)
+	f = jen.NewFilePathName(goPackagePath, goPackage)
+	// Generate code using jennifer
+	err = generateValueHelperMethods(f, typ, validatorMethod, typStruct)
+	if err != nil {
+		return err
+	}
+	return f.Save(targetFilename)
+}
+
 func GenCommandHandler(cfg *Config) (err error) {
 	var (
 		f *jen.File
 	)
-	err = initMain()
+	err = initMain("")
 	if err != nil {
 		return err
 	}
@@ -147,7 +193,9 @@ func loadPackage(path string) (*packages.Package, error) {
 	return pkgs[0], nil
 }
 
-func initMain() (err error) {
+// initMain resolves the invoking file and package, the generated file is named after
+// the invoking file, with genSuffix if several types of that file are generated
+func initMain(genSuffix string) (err error) {
 	// Get the package of the file with go:generate comment
 	goPackage = os.Getenv("GOPACKAGE")
 	cwd, err = os.Getwd()
@@ -159,7 +207,7 @@ func initMain() (err error) {
 	goFile = os.Getenv("GOFILE")
 	ext := filepath.Ext(goFile)
 	baseFilename = goFile[0 : len(goFile)-len(ext)]
-	targetFilename = baseFilename + "_gen.go"
+	targetFilename = baseFilename + genSuffix + "_gen.go"
 
 	// Remove existing target file (before loading the package)
 	if _, err = os.Stat(targetFilename); err == nil {
@@ -0,0 +1,105 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_domain
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+
+	"github.com/dave/jennifer/jen"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_domain/generator"
+)
+
+// StructTag Key
+var (
+	structTagValueKey = "value"
+)
+
+func generateValueHelperMethods(f *jen.File, typ, validatorMethod string, typStruct *types.Struct) (err error) {
+	// Add a package comment, so IDEs detect files as generated
+	f.PackageComment("Code generated by 'ddd-gen domain value', DO NOT EDIT.")
+
+	// 1. define code region variables
+	var (
+		flds        []generator.QualField
+		validations []generator.Validation
+		equalFlds   []generator.EqualFld
+		copier      = generator.NewCopier(typ, goPackagePath)
+	)
+
+	// 2. iterate over struct fields and populate those variables
+	for i := 0; i < typStruct.NumFields(); i++ {
+		fld := typStruct.Field(i)
+
+		// 2.1 error if the field could be mutated from outside the package
+		if fld.Exported() || fld.Embedded() {
+			return fmt.Errorf("%s is exported or embedded - breaks immutability", fld.Name())
+		}
+
+		// 2.2 values are immutable: all state which could be shared is deep copied
+		field, err := getRelativeQualField(fld)
+		if err != nil {
+			return err
+		}
+		if copier.NeedsCopy(fld.Type()) {
+			field.Copy = copier.Func(fld.Type())
+		}
+		flds = append(flds, field)
+
+		// 2.3 values are equal, if all their fields are equal
+		equalFlds = append(equalFlds, generator.NewEqualFld(field, fld.Type(), false))
+
+		// 2.4 match tags
+		tag := reflect.StructTag(typStruct.Tag(i))
+		if structTagValueKeyValue, ok := tag.Lookup(structTagValueKey); ok {
+			if requiredMatches := structRequiredTagPattern.FindStringSubmatch(structTagValueKeyValue); requiredMatches != nil {
+				validations = append(validations, generator.Validation{Field: field, ErrMsg: requiredMatches[1]})
+			}
+		}
+	}
+	if len(flds) == 0 {
+		return fmt.Errorf("value %s has no fields", typ)
+	}
+
+	// 3. assemble methods ...
+
+	f.Comment("Constructors ...")
+	f.Line()
+	generator.GenValueNew(f, typ, flds, validations, validatorMethod)
+
+	f.Comment("Marshalers ...")
+	f.Line()
+	if isTextValue(typStruct) {
+		generator.GenValueTextMarshalers(f, typ, flds[0])
+	} else {
+		generator.GenValueJSONMarshalers(f, typ, flds)
+	}
+
+	f.Comment("Accessors ...")
+	f.Line()
+	generator.GenValueGetters(f, typ, flds)
+	generator.GenValueWithers(f, typ, flds)
+
+	f.Comment("Utilities ...")
+	f.Line()
+	generator.GenEqual(f, typ, equalFlds)
+	generator.GenValueStringer(f, typ, flds)
+	if err := copier.GenCopiers(f); err != nil {
+		return err
+	}
+
+	// Write generated file
+	return nil
+}
+
+// isTextValue reports whether the value wraps a single string, which is marshaled as text
+func isTextValue(typStruct *types.Struct) bool {
+	if typStruct.NumFields() != 1 {
+		return false
+	}
+	b, ok := typStruct.Field(0).Type().Underlying().(*types.Basic)
+	return ok && b.Kind() == types.String
+}
@@ -0,0 +1,19 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_ports
+
+import (
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports/generator"
+)
+
+// GenActorAuth generates the JWT based actor extraction into the current working directory
+// claims maps token claims onto the proto names of the actor fields
+func GenActorAuth(typ string, claims map[string]string, conf *Config) error {
+	cwd, goPackage, _, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	gf := generator.GenActorAuth(goPackage, typ, claims, conf.objects())
+	return save(gf, genPath(cwd, "actorauth_gen.go"))
+}
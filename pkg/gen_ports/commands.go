@@ -0,0 +1,99 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_ports
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_app"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports/generator"
+	"golang.org/x/tools/go/packages"
+)
+
+// loadCommands reads the commands off the struct of the command handler wrappers
+// the struct is only parsed (not type checked), so that the ports can be generated
+// while the application layer is still being generated; the tags are parsed like 'ddd-gen app command' does
+func loadCommands(commands generator.QualId) ([]generator.Command, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedFiles}, commands.Qual)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("package '%s' not found", commands.Qual)
+	}
+	fset := token.NewFileSet()
+	for _, file := range pkgs[0].GoFiles {
+		astFile, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		astObj := astFile.Scope.Lookup(commands.Id)
+		if astObj == nil {
+			continue
+		}
+		astTypeSpec, ok := astObj.Decl.(*ast.TypeSpec)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a type declaration", commands.Id)
+		}
+		astStructType, ok := astTypeSpec.Type.(*ast.StructType)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a struct", commands.Id)
+		}
+		var cmds []generator.Command
+		for _, field := range astStructType.Fields.List {
+			var tag reflect.StructTag
+			if field.Tag != nil {
+				v, err := strconv.Unquote(field.Tag.Value)
+				if err != nil {
+					return nil, err
+				}
+				tag = reflect.StructTag(v)
+			}
+			names, err := fieldNames(field)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				ct, err := gen_app.ParseCommandTag(name, tag)
+				if err != nil {
+					return nil, err
+				}
+				cmds = append(cmds, generator.Command{
+					Name:       name,
+					Topic:      ct.Topic,
+					WithPolicy: ct.WithPolicyEnforcement,
+				})
+			}
+		}
+		return cmds, nil
+	}
+	return nil, fmt.Errorf("%s not found in '%s'", commands.Id, commands.Qual)
+}
+
+// fieldNames returns the names of field, an embedded field is named by its type
+func fieldNames(field *ast.Field) ([]string, error) {
+	if len(field.Names) > 0 {
+		var names []string
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		return names, nil
+	}
+	typ := field.Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return []string{t.Name}, nil
+	case *ast.SelectorExpr:
+		return []string{t.Sel.Name}, nil
+	}
+	return nil, fmt.Errorf("cannot name embedded field of type %T", field.Type)
+}
@@ -0,0 +1,87 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_ports
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports/generator"
+)
+
+type Config struct {
+	App      string           // import path of the application interfaces
+	Domain   string           // import path of the domain commands
+	Commands generator.QualId // struct of the command handler wrappers
+	Actor    generator.QualId // actor, implements the authorizable interface
+	Target   generator.QualId // target, implements the distinguishable interface
+	Errors   string           // import path of the errors generated by 'ddd-gen app errors'
+}
+
+func NewConfig(
+	app,
+	domain,
+	commands,
+	actor,
+	target,
+	authorizationErrorNew string,
+) (*Config, error) {
+	if app == "" || strings.HasSuffix(app, "/") {
+		return nil, fmt.Errorf("'%s' is not a valid app import path", app)
+	}
+	if domain == "" || strings.HasSuffix(domain, "/") {
+		return nil, fmt.Errorf("'%s' is not a valid domain import path", domain)
+	}
+	if !isValidQualId(commands) {
+		return nil, fmt.Errorf("'%s' is not a valid full qualifier commands", commands)
+	}
+	if !isValidQualId(actor) {
+		return nil, fmt.Errorf("'%s' is not a valid full qualifier actor", actor)
+	}
+	if !isValidQualId(target) {
+		return nil, fmt.Errorf("'%s' is not a valid full qualifier target", target)
+	}
+	if !isValidQualId(authorizationErrorNew) {
+		return nil, fmt.Errorf("'%s' is not a valid full qualifier authorizationErrorNew", authorizationErrorNew)
+	}
+	return &Config{
+		App:      app,
+		Domain:   domain,
+		Commands: splitQual(commands),
+		Actor:    splitQual(actor),
+		Target:   splitQual(target),
+		Errors:   splitQual(authorizationErrorNew).Qual,
+	}, nil
+}
+
+func isValidQualId(s string) bool {
+	idx := strings.LastIndex(s, ".")
+	if idx != -1 {
+		id := s[strings.LastIndex(s, ".")+1:]         // suggested identifier
+		if strings.Index(id, "/") == -1 && id != "" { // no '/' in suggested identifier
+			return unicode.IsUpper(rune(id[0])) // starts with upper case (is exported)
+		}
+	}
+	return false
+}
+
+func splitQual(s string) generator.QualId {
+	imp := s[:strings.LastIndex(s, ".")]
+	id := s[strings.LastIndex(s, ".")+1:]
+	return generator.QualId{
+		Qual: imp,
+		Id:   id,
+	}
+}
+
+func (c *Config) objects() generator.Objects {
+	return generator.Objects{
+		Domain:   c.Domain,
+		Commands: c.Commands,
+		Actor:    c.Actor,
+		Target:   c.Target,
+		Errors:   c.Errors,
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_ports
+
+import (
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports/generator"
+)
+
+// GenConsumer generates the message queue consumer into the current working directory
+func GenConsumer(typ string, conf *Config) error {
+	cwd, goPackage, _, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	cmds, err := loadCommands(conf.Commands)
+	if err != nil {
+		return err
+	}
+	gf := generator.GenConsumer(goPackage, typ, cmds, conf.objects())
+	return save(gf, genPath(cwd, "consumer_gen.go"))
+}
@@ -0,0 +1,610 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+)
+
+var cmdGenActorAuth string = "ddd-gen ports actorauth"
+
+const (
+	base64Pkg = "encoding/base64"
+	jsonPkg   = "encoding/json"
+)
+
+// actorAuthKind is a kind of error of a failed token verification
+type actorAuthKind struct {
+	Name    string
+	Message string
+	Doc     string
+}
+
+// actorAuthKinds are the kinds of errors of a failed token verification
+var actorAuthKinds = []actorAuthKind{
+	{"NoToken", "no token", "no token was presented"},
+	{"Malformed", "malformed token", "the token is not a JWS in compact serialization"},
+	{"UnsupportedAlgorithm", "unsupported algorithm", "the token is signed with an unsupported algorithm"},
+	{"UnknownKey", "unknown key", "no key of the key set can verify the token"},
+	{"InvalidSignature", "invalid signature", "the signature of the token is invalid"},
+	{"Expired", "token expired", "the token is expired"},
+	{"NotYetValid", "token not yet valid", "the token is not yet valid"},
+	{"InvalidIssuer", "invalid issuer", "the token is issued by an unexpected issuer"},
+	{"InvalidAudience", "invalid audience", "the token is not issued for the audience"},
+	{"InvalidClaims", "invalid claims", "the claims of the token are missing or can't be mapped onto the actor"},
+}
+
+// jwsAlgorithms are the supported signature algorithms with the key type and hash they require
+var jwsAlgorithms = []struct {
+	Name, Kty, Hash string
+}{
+	{"HS256", "oct", "SHA256"},
+	{"HS384", "oct", "SHA384"},
+	{"HS512", "oct", "SHA512"},
+	{"RS256", "RSA", "SHA256"},
+	{"RS384", "RSA", "SHA384"},
+	{"RS512", "RSA", "SHA512"},
+	{"PS256", "RSA", "SHA256"},
+	{"PS384", "RSA", "SHA384"},
+	{"PS512", "RSA", "SHA512"},
+	{"EdDSA", "OKP", ""},
+}
+
+func actorAuthKindConst(kind string) string { return "Kind" + kind }
+func actorAuthErr(kind string) string       { return "Err" + kind }
+
+func addActorAuthErrors(f *File) {
+	f.Comment("Kind classifies the errors of a failed token verification")
+	f.Type().Id("Kind").Int()
+
+	f.Const().DefsFunc(func(g *Group) {
+		g.Comment("KindUnknown classifies errors not raised by the token verification")
+		g.Id("KindUnknown").Id("Kind").Op("=").Iota()
+		for _, kind := range actorAuthKinds {
+			g.Commentf("%s classifies errors signaling that %s", actorAuthKindConst(kind.Name), kind.Doc)
+			g.Id(actorAuthKindConst(kind.Name))
+		}
+	})
+
+	f.Comment("String implements the fmt.Stringer interface")
+	f.Func().Params(Id("k").Id("Kind")).Id("String").Params().String().Block(
+		Switch(Id("k")).BlockFunc(func(g *Group) {
+			for _, kind := range actorAuthKinds {
+				g.Case(Id(actorAuthKindConst(kind.Name))).Block(Return(Lit(kind.Name)))
+			}
+			g.Default().Block(Return(Lit("Unknown")))
+		}),
+	)
+
+	f.Comment("Error is the error of a failed token verification of a distinct Kind")
+	f.Type().Id("Error").Struct(
+		Id("kind").Id("Kind"),
+		Id("detail").String(),
+	)
+
+	f.Comment("Error implements the error interface")
+	f.Func().Params(Id("e").Op("*").Id("Error")).Id("Error").Params().String().Block(
+		Var().Id("msg").String(),
+		Switch(Id("e").Dot("kind")).BlockFunc(func(g *Group) {
+			for _, kind := range actorAuthKinds {
+				g.Case(Id(actorAuthKindConst(kind.Name))).Block(Id("msg").Op("=").Lit(kind.Message))
+			}
+			g.Default().Block(Id("msg").Op("=").Lit("unknown error"))
+		}),
+		If(Id("e").Dot("detail").Op("==").Lit("")).Block(
+			Return(Id("msg")),
+		),
+		Return(Id("msg").Op("+").Lit(": ").Op("+").Id("e").Dot("detail")),
+	)
+
+	f.Comment("Kind returns the kind of the error")
+	f.Func().Params(Id("e").Op("*").Id("Error")).Id("Kind").Params().Id("Kind").Block(
+		Return(Id("e").Dot("kind")),
+	)
+
+	f.Comment("Is reports whether target is a Error of the same kind, e.g. ErrExpired")
+	f.Func().Params(Id("e").Op("*").Id("Error")).Id("Is").Params(Id("target").Error()).Bool().Block(
+		List(Id("t"), Id("ok")).Op(":=").Id("target").Assert(Op("*").Id("Error")),
+		Return(Id("ok").Op("&&").Id("t").Dot("kind").Op("==").Id("e").Dot("kind")),
+	)
+
+	f.Var().DefsFunc(func(g *Group) {
+		for _, kind := range actorAuthKinds {
+			g.Commentf("%s matches any error of %s", actorAuthErr(kind.Name), actorAuthKindConst(kind.Name))
+			g.Id(actorAuthErr(kind.Name)).Op("=").Op("&").Id("Error").Values(Dict{
+				Id("kind"): Id(actorAuthKindConst(kind.Name)),
+			})
+		}
+	})
+
+	f.Comment("KindOf returns the kind of the outermost Error in err")
+	f.Func().Id("KindOf").Params(Err().Error()).Id("Kind").Block(
+		Var().Id("e").Op("*").Id("Error"),
+		If(Qual("errors", "As").Call(Err(), Op("&").Id("e"))).Block(
+			Return(Id("e").Dot("kind")),
+		),
+		Return(Id("KindUnknown")),
+	)
+
+	f.Comment("fail returns a Error of kind with a formatted detail")
+	f.Func().Id("fail").Params(
+		Id("kind").Id("Kind"),
+		Id("format").String(),
+		Id("args").Op("...").Interface(),
+	).Error().Block(
+		Return(Op("&").Id("Error").Values(Dict{
+			Id("kind"):   Id("kind"),
+			Id("detail"): Qual("fmt", "Sprintf").Call(Id("format"), Id("args").Op("...")),
+		})),
+	)
+}
+
+func addActorAuthKeySet(f *File) {
+	b64 := func(s Code) *Statement {
+		return Qual(base64Pkg, "RawURLEncoding").Dot("DecodeString").Call(s)
+	}
+
+	f.Comment("algorithms are the supported signature algorithms with the key type and hash they require")
+	f.Var().Id("algorithms").Op("=").Map(String()).Struct(
+		Id("kty").String(),
+		Id("hash").Qual("crypto", "Hash"),
+	).Values(DictFunc(func(d Dict) {
+		for _, alg := range jwsAlgorithms {
+			hash := Lit(0)
+			if alg.Hash != "" {
+				hash = Qual("crypto", alg.Hash)
+			}
+			d[Lit(alg.Name)] = Values(Lit(alg.Kty), hash)
+		}
+	}))
+
+	f.Comment("key is a verification key of a KeySet")
+	f.Type().Id("key").Struct(
+		Id("id").String(),
+		Id("alg").String(),
+		Id("kty").String(),
+		Id("secret").Index().Byte(),
+		Id("rsa").Op("*").Qual("crypto/rsa", "PublicKey"),
+		Id("ed").Qual("crypto/ed25519", "PublicKey"),
+	)
+
+	f.Comment("verify reports whether sig is a signature of input by k with alg")
+	f.Func().Params(Id("k").Id("key")).Id("verify").Params(
+		Id("alg").String(),
+		List(Id("input"), Id("sig")).Index().Byte(),
+	).Bool().Block(
+		Id("hash").Op(":=").Id("algorithms").Index(Id("alg")).Dot("hash"),
+		Switch(Id("alg").Index(Empty(), Lit(2))).Block(
+			Case(Lit("HS")).Block(
+				Id("mac").Op(":=").Qual("crypto/hmac", "New").Call(Id("hash").Dot("New"), Id("k").Dot("secret")),
+				Id("mac").Dot("Write").Call(Id("input")),
+				Return(Qual("crypto/hmac", "Equal").Call(Id("mac").Dot("Sum").Call(Nil()), Id("sig"))),
+			),
+			Case(Lit("RS")).Block(
+				Id("h").Op(":=").Id("hash").Dot("New").Call(),
+				Id("h").Dot("Write").Call(Id("input")),
+				Return(Qual("crypto/rsa", "VerifyPKCS1v15").Call(Id("k").Dot("rsa"), Id("hash"), Id("h").Dot("Sum").Call(Nil()), Id("sig")).Op("==").Nil()),
+			),
+			Case(Lit("PS")).Block(
+				Id("h").Op(":=").Id("hash").Dot("New").Call(),
+				Id("h").Dot("Write").Call(Id("input")),
+				Id("opts").Op(":=").Op("&").Qual("crypto/rsa", "PSSOptions").Values(Dict{
+					Id("SaltLength"): Qual("crypto/rsa", "PSSSaltLengthEqualsHash"),
+				}),
+				Return(Qual("crypto/rsa", "VerifyPSS").Call(Id("k").Dot("rsa"), Id("hash"), Id("h").Dot("Sum").Call(Nil()), Id("sig"), Id("opts")).Op("==").Nil()),
+			),
+			Default().Block(
+				Return(Qual("crypto/ed25519", "Verify").Call(Id("k").Dot("ed"), Id("input"), Id("sig"))),
+			),
+		),
+	)
+
+	f.Comment("KeySet is a set of verification keys")
+	f.Type().Id("KeySet").Struct(
+		Id("keys").Index().Id("key"),
+	)
+
+	f.Comment("jwk is a JSON Web Key (RFC 7517)")
+	f.Type().Id("jwk").StructFunc(func(g *Group) {
+		for _, param := range []string{"Kty", "Kid", "Alg", "Use", "K", "N", "E", "Crv", "X"} {
+			g.Id(param).String().Tag(map[string]string{"json": lowerFirst(param)})
+		}
+	})
+
+	log.Printf("%s: generating '%s()'\n", "KeySet", "ParseJWKS")
+	f.Comment("ParseJWKS parses a JSON Web Key Set of HMAC (oct), RSA and Ed25519 (OKP) keys")
+	f.Comment("keys which can't verify signatures (e.g. of type EC or for encryption) are skipped")
+	f.Func().Id("ParseJWKS").Params(Id("b").Index().Byte()).Params(Op("*").Id("KeySet"), Error()).Block(
+		Var().Id("set").Struct(
+			Id("Keys").Index().Id("jwk").Tag(map[string]string{"json": "keys"}),
+		),
+		If(Err().Op(":=").Qual(jsonPkg, "Unmarshal").Call(Id("b"), Op("&").Id("set")), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Err()),
+		),
+		Id("ks").Op(":=").Op("&").Id("KeySet").Values(),
+		For(List(Id("i"), Id("k")).Op(":=").Range().Id("set").Dot("Keys")).Block(
+			If(Id("k").Dot("Use").Op("!=").Lit("").Op("&&").Id("k").Dot("Use").Op("!=").Lit("sig")).Block(
+				Continue(),
+			),
+			Id("parsed").Op(":=").Id("key").Values(Dict{
+				Id("id"):  Id("k").Dot("Kid"),
+				Id("alg"): Id("k").Dot("Alg"),
+				Id("kty"): Id("k").Dot("Kty"),
+			}),
+			Switch(Id("k").Dot("Kty")).Block(
+				Case(Lit("oct")).Block(
+					List(Id("secret"), Err()).Op(":=").Add(b64(Id("k").Dot("K"))),
+					If(Err().Op("!=").Nil().Op("||").Len(Id("secret")).Op("==").Lit(0)).Block(
+						Return(Nil(), Qual("fmt", "Errorf").Call(Lit("key %d: invalid 'k'"), Id("i"))),
+					),
+					Id("parsed").Dot("secret").Op("=").Id("secret"),
+				),
+				Case(Lit("RSA")).Block(
+					List(Id("n"), Err()).Op(":=").Add(b64(Id("k").Dot("N"))),
+					If(Err().Op("!=").Nil().Op("||").Len(Id("n")).Op("==").Lit(0)).Block(
+						Return(Nil(), Qual("fmt", "Errorf").Call(Lit("key %d: invalid 'n'"), Id("i"))),
+					),
+					List(Id("e"), Err()).Op(":=").Add(b64(Id("k").Dot("E"))),
+					If(Err().Op("!=").Nil().Op("||").Len(Id("e")).Op("==").Lit(0).Op("||").Len(Id("e")).Op(">").Lit(4)).Block(
+						Return(Nil(), Qual("fmt", "Errorf").Call(Lit("key %d: invalid 'e'"), Id("i"))),
+					),
+					Id("parsed").Dot("rsa").Op("=").Op("&").Qual("crypto/rsa", "PublicKey").Values(Dict{
+						Id("N"): New(Qual("math/big", "Int")).Dot("SetBytes").Call(Id("n")),
+						Id("E"): Int().Parens(New(Qual("math/big", "Int")).Dot("SetBytes").Call(Id("e")).Dot("Int64").Call()),
+					}),
+				),
+				Case(Lit("OKP")).Block(
+					If(Id("k").Dot("Crv").Op("!=").Lit("Ed25519")).Block(
+						Continue(),
+					),
+					List(Id("x"), Err()).Op(":=").Add(b64(Id("k").Dot("X"))),
+					If(Err().Op("!=").Nil().Op("||").Len(Id("x")).Op("!=").Qual("crypto/ed25519", "PublicKeySize")).Block(
+						Return(Nil(), Qual("fmt", "Errorf").Call(Lit("key %d: invalid 'x'"), Id("i"))),
+					),
+					Id("parsed").Dot("ed").Op("=").Qual("crypto/ed25519", "PublicKey").Call(Id("x")),
+				),
+				Default().Block(
+					Continue(),
+				),
+			),
+			Id("ks").Dot("keys").Op("=").Append(Id("ks").Dot("keys"), Id("parsed")),
+		),
+		If(Len(Id("ks").Dot("keys")).Op("==").Lit(0)).Block(
+			Return(Nil(), Qual("errors", "New").Call(Lit("no verification keys"))),
+		),
+		Return(Id("ks"), Nil()),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "KeySet", "LoadJWKS")
+	f.Comment("LoadJWKS reads a JSON Web Key Set from a local file")
+	f.Func().Id("LoadJWKS").Params(Id("file").String()).Params(Op("*").Id("KeySet"), Error()).Block(
+		List(Id("b"), Err()).Op(":=").Qual("io/ioutil", "ReadFile").Call(Id("file")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), Err()),
+		),
+		Return(Id("ParseJWKS").Call(Id("b"))),
+	)
+
+	f.Comment("candidates returns the keys which may have signed a token with alg and key id kid")
+	f.Comment("the key type must fit the algorithm, so that e.g. a public RSA key is never used as HMAC secret")
+	f.Func().Params(Id("s").Op("*").Id("KeySet")).Id("candidates").Params(
+		List(Id("alg"), Id("kid")).String(),
+	).Index().Id("key").Block(
+		Var().Id("keys").Index().Id("key"),
+		For(List(Id("_"), Id("k")).Op(":=").Range().Id("s").Dot("keys")).Block(
+			Switch().Block(
+				Case(Id("k").Dot("kty").Op("!=").Id("algorithms").Index(Id("alg")).Dot("kty")),
+				Case(Id("k").Dot("alg").Op("!=").Lit("").Op("&&").Id("k").Dot("alg").Op("!=").Id("alg")),
+				Case(Id("kid").Op("!=").Lit("").Op("&&").Id("k").Dot("id").Op("!=").Id("kid")),
+				Default().Block(
+					Id("keys").Op("=").Append(Id("keys"), Id("k")),
+				),
+			),
+		),
+		Return(Id("keys")),
+	)
+}
+
+func addActorAuthVerifier(f *File, typ string, claims map[string]string, objects Objects) {
+	actor := Op("*").Qual(objects.Actor.Qual, objects.Actor.Id)
+
+	f.Commentf("Claims maps the names of token claims onto the proto names of %s fields", objects.Actor.Id)
+	f.Type().Id("Claims").Map(String()).String()
+
+	f.Commentf("DefaultClaims are mapped onto %s if the Config does not provide any Claims", objects.Actor.Id)
+	f.Commentf("if empty, each field of %s is mapped from the claim of the same name", objects.Actor.Id)
+	f.Var().Id("DefaultClaims").Op("=").Id("Claims").Values(DictFunc(func(d Dict) {
+		for claim, field := range claims {
+			d[Lit(claim)] = Lit(field)
+		}
+	}))
+
+	f.Commentf("Config configures a %s", typ)
+	f.Type().Id("Config").Struct(
+		Comment("Issuer, if set, must match the iss claim"),
+		Id("Issuer").String(),
+		Comment("Audience, if set, must be contained in the aud claim"),
+		Id("Audience").String(),
+		Comment("Leeway tolerates clock skew when validating the exp and nbf claims"),
+		Id("Leeway").Qual("time", "Duration"),
+		Comment("Claims maps claims onto the actor, defaults to DefaultClaims"),
+		Id("Claims").Id("Claims"),
+		Comment("Now returns the current time, defaults to time.Now"),
+		Id("Now").Func().Params().Qual("time", "Time"),
+	)
+
+	f.Commentf("%s verifies JWTs offline against a KeySet and maps their claims onto an %s", typ, objects.Actor.Id)
+	f.Type().Id(typ).Struct(
+		Id("keys").Op("*").Id("KeySet"),
+		Id("conf").Id("Config"),
+		Id("fields").Map(String()).String().Comment("json names of the actor fields by claim"),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, "New"+typ)
+	f.Commentf("New%s returns a %s, the claims of conf must map onto fields of %s", typ, typ, objects.Actor.Id)
+	f.Func().Id("New"+typ).Params(
+		Id("keys").Op("*").Id("KeySet"),
+		Id("conf").Id("Config"),
+	).Params(Op("*").Id(typ), Error()).Block(
+		If(Id("keys").Op("==").Nil()).Block(
+			Panic(Lit("no 'keys' provided!")),
+		),
+		If(Id("conf").Dot("Now").Op("==").Nil()).Block(
+			Id("conf").Dot("Now").Op("=").Qual("time", "Now"),
+		),
+		Id("claims").Op(":=").Id("conf").Dot("Claims"),
+		If(Id("claims").Op("==").Nil()).Block(
+			Id("claims").Op("=").Id("DefaultClaims"),
+		),
+		Id("desc").Op(":=").Parens(Op("&").Qual(objects.Actor.Qual, objects.Actor.Id).Values()).Dot("ProtoReflect").Call().Dot("Descriptor").Call().Dot("Fields").Call(),
+		Id("fields").Op(":=").Make(Map(String()).String()),
+		If(Len(Id("claims")).Op("==").Lit(0)).Block(
+			For(Id("i").Op(":=").Lit(0), Id("i").Op("<").Id("desc").Dot("Len").Call(), Id("i").Op("++")).Block(
+				Id("fd").Op(":=").Id("desc").Dot("Get").Call(Id("i")),
+				Id("fields").Index(String().Parens(Id("fd").Dot("Name").Call())).Op("=").Id("fd").Dot("JSONName").Call(),
+			),
+		),
+		For(List(Id("claim"), Id("name")).Op(":=").Range().Id("claims")).Block(
+			Id("fd").Op(":=").Id("desc").Dot("ByName").Call(Qual(protoreflectPkg, "Name").Call(Id("name"))),
+			If(Id("fd").Op("==").Nil()).Block(
+				Return(Nil(), Qual("fmt", "Errorf").Call(Lit("claim %s: "+objects.Actor.Id+" has no field '%s'"), Id("claim"), Id("name"))),
+			),
+			Id("fields").Index(Id("claim")).Op("=").Id("fd").Dot("JSONName").Call(),
+		),
+		Return(Op("&").Id(typ).Values(Dict{
+			Id("keys"):   Id("keys"),
+			Id("conf"):   Id("conf"),
+			Id("fields"): Id("fields"),
+		}), Nil()),
+	)
+
+	recv := cmdShortForm(typ)
+
+	log.Printf("%s: generating '%s()'\n", typ, "Verify")
+	f.Commentf("Verify verifies the signature and the registered claims of token and maps its claims onto an %s", objects.Actor.Id)
+	f.Comment("tokens must carry an exp claim, nbf, iss and aud are validated if present or configured")
+	f.Func().Params(Id(recv).Op("*").Id(typ)).Id("Verify").Params(Id("token").String()).Params(actor.Clone(), Error()).Block(
+		If(Id("token").Op("==").Lit("")).Block(
+			Return(Nil(), Id("ErrNoToken")),
+		),
+		Id("parts").Op(":=").Qual("strings", "Split").Call(Id("token"), Lit(".")),
+		If(Len(Id("parts")).Op("!=").Lit(3)).Block(
+			Return(Nil(), Id("fail").Call(Id("KindMalformed"), Lit("%d segments"), Len(Id("parts")))),
+		),
+		Var().Id("header").Struct(
+			Id("Alg").String().Tag(map[string]string{"json": "alg"}),
+			Id("Kid").String().Tag(map[string]string{"json": "kid"}),
+			Id("Crit").Index().String().Tag(map[string]string{"json": "crit"}),
+		),
+		If(Err().Op(":=").Id("decodeSegment").Call(Id("parts").Index(Lit(0)), Op("&").Id("header")), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Id("fail").Call(Id("KindMalformed"), Lit("header: %v"), Err())),
+		),
+		If(List(Id("_"), Id("ok")).Op(":=").Id("algorithms").Index(Id("header").Dot("Alg")), Op("!").Id("ok")).Block(
+			Return(Nil(), Id("fail").Call(Id("KindUnsupportedAlgorithm"), Lit("%q"), Id("header").Dot("Alg"))),
+		),
+		If(Len(Id("header").Dot("Crit")).Op(">").Lit(0)).Block(
+			Return(Nil(), Id("fail").Call(Id("KindMalformed"), Lit("unsupported critical headers %v"), Id("header").Dot("Crit"))),
+		),
+		List(Id("sig"), Err()).Op(":=").Qual(base64Pkg, "RawURLEncoding").Dot("DecodeString").Call(Id("parts").Index(Lit(2))),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), Id("fail").Call(Id("KindMalformed"), Lit("signature: %v"), Err())),
+		),
+		Id("keys").Op(":=").Id(recv).Dot("keys").Dot("candidates").Call(Id("header").Dot("Alg"), Id("header").Dot("Kid")),
+		If(Len(Id("keys")).Op("==").Lit(0)).Block(
+			Return(Nil(), Id("fail").Call(Id("KindUnknownKey"), Lit("no %s key %q"), Id("header").Dot("Alg"), Id("header").Dot("Kid"))),
+		),
+		Id("input").Op(":=").Index().Byte().Parens(Id("parts").Index(Lit(0)).Op("+").Lit(".").Op("+").Id("parts").Index(Lit(1))),
+		Id("verified").Op(":=").False(),
+		For(List(Id("_"), Id("k")).Op(":=").Range().Id("keys")).Block(
+			If(Id("k").Dot("verify").Call(Id("header").Dot("Alg"), Id("input"), Id("sig"))).Block(
+				Id("verified").Op("=").True(),
+				Break(),
+			),
+		),
+		If(Op("!").Id("verified")).Block(
+			Return(Nil(), Id("ErrInvalidSignature")),
+		),
+		Var().Id("claims").Map(String()).Qual(jsonPkg, "RawMessage"),
+		If(Err().Op(":=").Id("decodeSegment").Call(Id("parts").Index(Lit(1)), Op("&").Id("claims")), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Id("fail").Call(Id("KindMalformed"), Lit("claims: %v"), Err())),
+		),
+		If(Err().Op(":=").Id(recv).Dot("validate").Call(Id("claims")), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Err()),
+		),
+		Return(Id(recv).Dot("actor").Call(Id("claims"))),
+	)
+
+	f.Comment("validate validates the registered claims exp, nbf, iss and aud")
+	f.Func().Params(Id(recv).Op("*").Id(typ)).Id("validate").Params(
+		Id("claims").Map(String()).Qual(jsonPkg, "RawMessage"),
+	).Error().Block(
+		Id("now").Op(":=").Id(recv).Dot("conf").Dot("Now").Call(),
+		List(Id("exp"), Id("ok"), Err()).Op(":=").Id("numericDate").Call(Id("claims"), Lit("exp")),
+		Switch().Block(
+			Case(Err().Op("!=").Nil()).Block(
+				Return(Err()),
+			),
+			Case(Op("!").Id("ok")).Block(
+				Return(Id("fail").Call(Id("KindInvalidClaims"), Lit("missing exp"))),
+			),
+			Case(Op("!").Id("now").Dot("Before").Call(Id("exp").Dot("Add").Call(Id(recv).Dot("conf").Dot("Leeway")))).Block(
+				Return(Id("fail").Call(Id("KindExpired"), Lit("at %s"), Id("exp").Dot("Format").Call(Qual("time", "RFC3339")))),
+			),
+		),
+		List(Id("nbf"), Id("ok"), Err()).Op(":=").Id("numericDate").Call(Id("claims"), Lit("nbf")),
+		Switch().Block(
+			Case(Err().Op("!=").Nil()).Block(
+				Return(Err()),
+			),
+			Case(Id("ok").Op("&&").Id("now").Dot("Add").Call(Id(recv).Dot("conf").Dot("Leeway")).Dot("Before").Call(Id("nbf"))).Block(
+				Return(Id("fail").Call(Id("KindNotYetValid"), Lit("before %s"), Id("nbf").Dot("Format").Call(Qual("time", "RFC3339")))),
+			),
+		),
+		If(Id(recv).Dot("conf").Dot("Issuer").Op("!=").Lit("")).Block(
+			Var().Id("iss").String(),
+			Qual(jsonPkg, "Unmarshal").Call(Id("claims").Index(Lit("iss")), Op("&").Id("iss")),
+			If(Id("iss").Op("!=").Id(recv).Dot("conf").Dot("Issuer")).Block(
+				Return(Id("fail").Call(Id("KindInvalidIssuer"), Lit("%q"), Id("iss"))),
+			),
+		),
+		If(Id(recv).Dot("conf").Dot("Audience").Op("!=").Lit("")).Block(
+			Comment("aud is either a single audience or a list of audiences"),
+			Var().Id("aud").Index().String(),
+			If(Qual(jsonPkg, "Unmarshal").Call(Id("claims").Index(Lit("aud")), Op("&").Id("aud")).Op("!=").Nil()).Block(
+				Var().Id("one").String(),
+				Qual(jsonPkg, "Unmarshal").Call(Id("claims").Index(Lit("aud")), Op("&").Id("one")),
+				Id("aud").Op("=").Index().String().Values(Id("one")),
+			),
+			If(Op("!").Id("contains").Call(Id("aud"), Id(recv).Dot("conf").Dot("Audience"))).Block(
+				Return(Id("fail").Call(Id("KindInvalidAudience"), Lit("%q"), Id("aud"))),
+			),
+		),
+		Return(Nil()),
+	)
+
+	f.Commentf("actor maps the claims onto an %s, as if they were its protojson encoded fields", objects.Actor.Id)
+	f.Func().Params(Id(recv).Op("*").Id(typ)).Id("actor").Params(
+		Id("claims").Map(String()).Qual(jsonPkg, "RawMessage"),
+	).Params(actor.Clone(), Error()).Block(
+		Id("obj").Op(":=").Make(Map(String()).Qual(jsonPkg, "RawMessage"), Len(Id(recv).Dot("fields"))),
+		For(List(Id("claim"), Id("field")).Op(":=").Range().Id(recv).Dot("fields")).Block(
+			If(List(Id("raw"), Id("ok")).Op(":=").Id("claims").Index(Id("claim")), Id("ok")).Block(
+				Id("obj").Index(Id("field")).Op("=").Id("raw"),
+			),
+		),
+		List(Id("b"), Err()).Op(":=").Qual(jsonPkg, "Marshal").Call(Id("obj")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), Id("fail").Call(Id("KindInvalidClaims"), Lit("%v"), Err())),
+		),
+		Id("a").Op(":=").Op("&").Qual(objects.Actor.Qual, objects.Actor.Id).Values(),
+		If(Err().Op(":=").Qual(protojsonPkg, "Unmarshal").Call(Id("b"), Id("a")), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Id("fail").Call(Id("KindInvalidClaims"), Lit("%v"), Err())),
+		),
+		Return(Id("a"), Nil()),
+	)
+
+	f.Comment("decodeSegment decodes a base64url encoded json segment of a token into v")
+	f.Func().Id("decodeSegment").Params(Id("seg").String(), Id("v").Interface()).Error().Block(
+		List(Id("b"), Err()).Op(":=").Qual(base64Pkg, "RawURLEncoding").Dot("DecodeString").Call(Id("seg")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Err()),
+		),
+		Return(Qual(jsonPkg, "Unmarshal").Call(Id("b"), Id("v"))),
+	)
+
+	f.Comment("numericDate returns the time of the numeric date claim name, ok is false if the claim is absent")
+	f.Func().Id("numericDate").Params(
+		Id("claims").Map(String()).Qual(jsonPkg, "RawMessage"),
+		Id("name").String(),
+	).Params(Id("t").Qual("time", "Time"), Id("ok").Bool(), Err().Error()).Block(
+		List(Id("raw"), Id("ok")).Op(":=").Id("claims").Index(Id("name")),
+		If(Op("!").Id("ok")).Block(
+			Return(Id("t"), False(), Nil()),
+		),
+		Var().Id("sec").Float64(),
+		If(Err().Op(":=").Qual(jsonPkg, "Unmarshal").Call(Id("raw"), Op("&").Id("sec")), Err().Op("!=").Nil()).Block(
+			Return(Id("t"), False(), Id("fail").Call(Id("KindInvalidClaims"), Lit("%s: %v"), Id("name"), Err())),
+		),
+		Return(Qual("time", "Unix").Call(Int64().Parens(Id("sec")), Lit(0)), True(), Nil()),
+	)
+
+	f.Comment("contains reports whether s is in list")
+	f.Func().Id("contains").Params(Id("list").Index().String(), Id("s").String()).Bool().Block(
+		For(List(Id("_"), Id("v")).Op(":=").Range().Id("list")).Block(
+			If(Id("v").Op("==").Id("s")).Block(
+				Return(True()),
+			),
+		),
+		Return(False()),
+	)
+}
+
+func addActorAuthExtractors(f *File, typ string, objects Objects) {
+	actor := Op("*").Qual(objects.Actor.Qual, objects.Actor.Id)
+	recv := cmdShortForm(typ)
+
+	log.Printf("%s: generating '%s()'\n", typ, "ExtractActor")
+	f.Comment("ExtractActor verifies the bearer token of the Authorization header of r")
+	f.Comment("it implements the ActorExtractor of the http port")
+	f.Func().Params(Id(recv).Op("*").Id(typ)).Id("ExtractActor").Params(
+		Id("r").Op("*").Qual(httpPkg, "Request"),
+	).Params(actor.Clone(), Error()).Block(
+		Return(Id(recv).Dot("Verify").Call(Id("BearerToken").Call(Id("r").Dot("Header").Dot("Get").Call(Lit("Authorization"))))),
+	)
+
+	f.Comment("BearerToken returns the token of a bearer authorization, or \"\" if there is none")
+	f.Func().Id("BearerToken").Params(Id("authorization").String()).String().Block(
+		Const().Id("prefix").Op("=").Lit("Bearer "),
+		If(Len(Id("authorization")).Op("<").Len(Id("prefix")).Op("||").Op("!").Qual("strings", "EqualFold").Call(
+			Id("authorization").Index(Empty(), Len(Id("prefix"))),
+			Id("prefix"),
+		)).Block(
+			Return(Lit("")),
+		),
+		Return(Qual("strings", "TrimSpace").Call(Id("authorization").Index(Len(Id("prefix")), Empty()))),
+	)
+
+	f.Commentf("ContextExtractor verifies the token which Token finds in a context, e.g. in the incoming grpc metadata")
+	f.Comment("it implements the ActorExtractor of the grpc port")
+	f.Type().Id("ContextExtractor").Struct(
+		Id(typ).Op("*").Id(typ),
+		Id("Token").Func().Params(Id("ctx").Qual("context", "Context")).String(),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "ContextExtractor", "ExtractActor")
+	f.Comment("ExtractActor verifies the token of ctx")
+	f.Func().Params(Id("e").Id("ContextExtractor")).Id("ExtractActor").Params(
+		Id("ctx").Qual("context", "Context"),
+	).Params(actor.Clone(), Error()).Block(
+		Return(Id("e").Dot(typ).Dot("Verify").Call(Id("e").Dot("Token").Call(Id("ctx")))),
+	)
+}
+
+// Composers ...
+
+func GenActorAuth(pkgName, typ string, claims map[string]string, objects Objects) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenActorAuth))
+	ret.Line()
+	ret.Anon("crypto/sha256", "crypto/sha512")
+	addActorAuthErrors(ret)
+	addActorAuthKeySet(ret)
+	addActorAuthVerifier(ret, typ, claims, objects)
+	addActorAuthExtractors(ret, typ, objects)
+
+	actor := Op("*").Qual(objects.Actor.Qual, objects.Actor.Id)
+	ret.Comment("compile time assertions")
+	ret.Var().Defs(
+		Id("_").Interface(
+			Id("ExtractActor").Params(Op("*").Qual(httpPkg, "Request")).Params(actor.Clone(), Error()),
+		).Op("=").Parens(Op("*").Id(typ)).Call(Nil()),
+		Id("_").Interface(
+			Id("ExtractActor").Params(Qual("context", "Context")).Params(actor.Clone(), Error()),
+		).Op("=").Id("ContextExtractor").Values(),
+	)
+	return ret
+}
@@ -0,0 +1,267 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenActorAuth generates the actor extraction into a temporary module and
+// runs actorAuthTest against it, the actor is a FileDescriptorProto with sub mapped onto its name
+func TestGenActorAuth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a temporary module")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+	sum, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "actorauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := GenActorAuth("actorauth", "Verifier", map[string]string{"sub": "name"}, Objects{
+		Actor: QualId{Id: "FileDescriptorProto", Qual: "google.golang.org/protobuf/types/descriptorpb"},
+	})
+	if err := f.Save(filepath.Join(dir, "actorauth_gen.go")); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":            actorAuthTestMod,
+		"go.sum":            string(sum),
+		"actorauth_test.go": actorAuthTest,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "test", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
+
+const actorAuthTestMod = `module example.com/actorauth
+
+go 1.14
+
+require google.golang.org/protobuf v1.25.0
+`
+
+const actorAuthTest = `package actorauth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+const issuer = "https://id.example.com"
+
+var (
+	now    = time.Unix(1600000000, 0)
+	secret = []byte("0123456789abcdef0123456789abcdef")
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func hs256(secret []byte) func([]byte) []byte {
+	return func(input []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		return mac.Sum(nil)
+	}
+}
+
+func rs256(k *rsa.PrivateKey) func([]byte) []byte {
+	return func(input []byte) []byte {
+		h := sha256.Sum256(input)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, h[:])
+		if err != nil {
+			panic(err)
+		}
+		return sig
+	}
+}
+
+func ps256(k *rsa.PrivateKey) func([]byte) []byte {
+	return func(input []byte) []byte {
+		h := sha256.Sum256(input)
+		sig, err := rsa.SignPSS(rand.Reader, k, crypto.SHA256, h[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		if err != nil {
+			panic(err)
+		}
+		return sig
+	}
+}
+
+func eddsa(k ed25519.PrivateKey) func([]byte) []byte {
+	return func(input []byte) []byte { return ed25519.Sign(k, input) }
+}
+
+func token(header, claims map[string]interface{}, sign func([]byte) []byte) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	input := b64(h) + "." + b64(c)
+	return input + "." + b64(sign([]byte(input)))
+}
+
+// claims returns valid claims with overrides, a nil override removes the claim
+func claims(overrides map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub": "alice",
+		"iss": issuer,
+		"aud": "svc",
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hmac", "k": b64(secret)},
+		{"kty": "RSA", "kid": "rsa", "alg": "RS256", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64(edPub)},
+		{"kty": "EC", "kid": "ec", "crv": "P-256"},
+	}})
+	keys, err := ParseJWKS(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(keys, Config{
+		Issuer:   issuer,
+		Audience: "svc",
+		Leeway:   time.Minute,
+		Now:      func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hs := map[string]interface{}{"alg": "HS256", "kid": "hmac"}
+	valid := token(hs, claims(nil), hs256(secret))
+	parts := strings.Split(valid, ".")
+	other, _ := json.Marshal(claims(map[string]interface{}{"sub": "mallory"}))
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	sig[0] ^= 1
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"HS256", valid, nil},
+		{"RS256", token(map[string]interface{}{"alg": "RS256", "kid": "rsa"}, claims(nil), rs256(rsaKey)), nil},
+		{"EdDSA", token(map[string]interface{}{"alg": "EdDSA", "kid": "ed"}, claims(nil), eddsa(edKey)), nil},
+		{"no kid", token(map[string]interface{}{"alg": "HS256"}, claims(nil), hs256(secret)), nil},
+		{"no token", "", ErrNoToken},
+		{"two segments", parts[0] + "." + parts[1], ErrMalformed},
+		{"critical header", token(map[string]interface{}{"alg": "HS256", "crit": []string{"exp"}}, claims(nil), hs256(secret)), ErrMalformed},
+		{"alg none", token(map[string]interface{}{"alg": "none"}, claims(nil), func([]byte) []byte { return nil }), ErrUnsupportedAlgorithm},
+		{"alg confusion", token(map[string]interface{}{"alg": "HS256", "kid": "rsa"}, claims(nil), hs256(rsaKey.N.Bytes())), ErrUnknownKey},
+		{"kty confusion", token(map[string]interface{}{"alg": "RS256", "kid": "hmac"}, claims(nil), rs256(rsaKey)), ErrUnknownKey},
+		{"alg not of key", token(map[string]interface{}{"alg": "PS256", "kid": "rsa"}, claims(nil), ps256(rsaKey)), ErrUnknownKey},
+		{"unknown kid", token(map[string]interface{}{"alg": "HS256", "kid": "nope"}, claims(nil), hs256(secret)), ErrUnknownKey},
+		{"wrong secret", token(hs, claims(nil), hs256([]byte("guessed"))), ErrInvalidSignature},
+		{"tampered claims", parts[0] + "." + b64(other) + "." + parts[2], ErrInvalidSignature},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + b64(sig), ErrInvalidSignature},
+		{"missing exp", token(hs, claims(map[string]interface{}{"exp": nil}), hs256(secret)), ErrInvalidClaims},
+		{"exp not a number", token(hs, claims(map[string]interface{}{"exp": "tomorrow"}), hs256(secret)), ErrInvalidClaims},
+		{"expired within leeway", token(hs, claims(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()}), hs256(secret)), nil},
+		{"expired", token(hs, claims(map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}), hs256(secret)), ErrExpired},
+		{"expired at leeway", token(hs, claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()}), hs256(secret)), ErrExpired},
+		{"not yet valid within leeway", token(hs, claims(map[string]interface{}{"nbf": now.Add(30 * time.Second).Unix()}), hs256(secret)), nil},
+		{"not yet valid", token(hs, claims(map[string]interface{}{"nbf": now.Add(2 * time.Minute).Unix()}), hs256(secret)), ErrNotYetValid},
+		{"wrong issuer", token(hs, claims(map[string]interface{}{"iss": "https://evil.example.com"}), hs256(secret)), ErrInvalidIssuer},
+		{"missing issuer", token(hs, claims(map[string]interface{}{"iss": nil}), hs256(secret)), ErrInvalidIssuer},
+		{"aud list", token(hs, claims(map[string]interface{}{"aud": []string{"other", "svc"}}), hs256(secret)), nil},
+		{"wrong aud", token(hs, claims(map[string]interface{}{"aud": "other"}), hs256(secret)), ErrInvalidAudience},
+		{"wrong aud list", token(hs, claims(map[string]interface{}{"aud": []string{"other"}}), hs256(secret)), ErrInvalidAudience},
+		{"missing aud", token(hs, claims(map[string]interface{}{"aud": nil}), hs256(secret)), ErrInvalidAudience},
+		{"sub not a string", token(hs, claims(map[string]interface{}{"sub": 42}), hs256(secret)), ErrInvalidClaims},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := v.Verify(tc.token)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if a.GetName() != "alice" {
+					t.Fatalf("sub is mapped onto %q", a.GetName())
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+			if a != nil {
+				t.Fatal("got an actor of a rejected token")
+			}
+		})
+	}
+}
+
+func TestNewVerifierUnknownField(t *testing.T) {
+	keys, err := ParseJWKS([]byte(` + "`" + `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}` + "`" + `))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewVerifier(keys, Config{Claims: Claims{"sub": "nope"}}); err == nil {
+		t.Fatal("mapped a claim onto a field which does not exist")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	for in, want := range map[string]string{
+		"Bearer abc":  "abc",
+		"bearer abc ": "abc",
+		"Basic abc":   "",
+		"Bearer":      "",
+		"":            "",
+	} {
+		if got := BearerToken(in); got != want {
+			t.Errorf("BearerToken(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+`
@@ -0,0 +1,315 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+var cmdGenCLI string = "ddd-gen ports cli"
+
+const (
+	cobraPkg        = "github.com/spf13/cobra"
+	pflagPkg        = "github.com/spf13/pflag"
+	yamlPkg         = "gopkg.in/yaml.v2"
+	protoreflectPkg = "google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CLIUse returns the name of the subcommand of a command or a topic
+func CLIUse(name string) string {
+	return strings.ReplaceAll(ToSnakeCase(name), "_", "-")
+}
+
+func cliNewCommand(cmd string) string { return "new" + cmd + "Command" }
+
+func addCLIFlags(f *File) {
+	f.Const().Defs(
+		Comment("ActorFlag is the persistent flag which carries the protojson encoded actor"),
+		Id("ActorFlag").Op("=").Lit("actor"),
+		Comment("TargetFlag is the persistent flag which carries the target identifier"),
+		Id("TargetFlag").Op("=").Lit("target"),
+		Comment("FileFlag is the persistent flag which names a json or yaml file with the command"),
+		Id("FileFlag").Op("=").Lit("file"),
+	)
+
+	addErrNoActor(f)
+
+	f.Comment("fieldFlag returns the flag name of a command field")
+	f.Func().Id("fieldFlag").Params(Id("fd").Qual(protoreflectPkg, "FieldDescriptor")).String().Block(
+		Return(Qual("strings", "ReplaceAll").Call(String().Parens(Id("fd").Dot("Name").Call()), Lit("_"), Lit("-"))),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "CLI", "addFieldFlags")
+	f.Comment("addFieldFlags adds a flag for each field of a command, message, list and map fields take json values")
+	f.Comment("fields which collide with the persistent flags can only be set through FileFlag")
+	f.Func().Id("addFieldFlags").Params(
+		Id("fs").Op("*").Qual(pflagPkg, "FlagSet"),
+		Id("md").Qual(protoreflectPkg, "MessageDescriptor"),
+	).Block(
+		Id("fields").Op(":=").Id("md").Dot("Fields").Call(),
+		For(Id("i").Op(":=").Lit(0), Id("i").Op("<").Id("fields").Dot("Len").Call(), Id("i").Op("++")).Block(
+			Id("fd").Op(":=").Id("fields").Dot("Get").Call(Id("i")),
+			Switch(Id("fieldFlag").Call(Id("fd"))).Block(
+				Case(Id("ActorFlag"), Id("TargetFlag"), Id("FileFlag")).Block(
+					Continue(),
+				),
+			),
+			Id("usage").Op(":=").Id("fd").Dot("Kind").Call().Dot("String").Call(),
+			Switch().Block(
+				Case(Id("fd").Dot("IsMap").Call()).Block(
+					Id("usage").Op("=").Lit("json encoded map"),
+				),
+				Case(Id("fd").Dot("IsList").Call()).Block(
+					Id("usage").Op("=").Lit("json encoded list of ").Op("+").Id("usage"),
+				),
+				Case(Id("fd").Dot("Message").Call().Op("!=").Nil()).Block(
+					Id("usage").Op("=").Lit("json encoded ").Op("+").String().Parens(Id("fd").Dot("Message").Call().Dot("FullName").Call()),
+				),
+			),
+			Id("fs").Dot("String").Call(Id("fieldFlag").Call(Id("fd")), Lit(""), Id("usage")),
+		),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "CLI", "readCommand")
+	f.Comment("readCommand reads x from FileFlag, if set, and overrides its fields with the field flags")
+	f.Func().Id("readCommand").Params(
+		Id("c").Op("*").Qual(cobraPkg, "Command"),
+		Id("x").Qual(protoPkg, "Message"),
+	).Error().Block(
+		Id("values").Op(":=").Map(String()).Interface().Values(),
+		If(
+			List(Id("file"), Id("_")).Op(":=").Id("c").Dot("Flags").Call().Dot("GetString").Call(Id("FileFlag")),
+			Id("file").Op("!=").Lit(""),
+		).Block(
+			List(Id("b"), Err()).Op(":=").Qual("io/ioutil", "ReadFile").Call(Id("file")),
+			If(Err().Op("!=").Nil()).Block(
+				Return(Err()),
+			),
+			Switch(Qual("path/filepath", "Ext").Call(Id("file"))).Block(
+				Case(Lit(".yaml"), Lit(".yml")).Block(
+					Var().Id("v").Interface(),
+					If(Err().Op(":=").Qual(yamlPkg, "Unmarshal").Call(Id("b"), Op("&").Id("v")), Err().Op("!=").Nil()).Block(
+						Return(Err()),
+					),
+					List(Id("m"), Id("ok")).Op(":=").Id("jsonValue").Call(Id("v")).Assert(Map(String()).Interface()),
+					If(Op("!").Id("ok")).Block(
+						Return(Qual("fmt", "Errorf").Call(Lit("%s: not a yaml mapping"), Id("file"))),
+					),
+					Id("values").Op("=").Id("m"),
+				),
+				Default().Block(
+					If(Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(Id("b"), Op("&").Id("values")), Err().Op("!=").Nil()).Block(
+						Return(Err()),
+					),
+				),
+			),
+		),
+		Id("fields").Op(":=").Id("x").Dot("ProtoReflect").Call().Dot("Descriptor").Call().Dot("Fields").Call(),
+		For(Id("i").Op(":=").Lit(0), Id("i").Op("<").Id("fields").Dot("Len").Call(), Id("i").Op("++")).Block(
+			Id("fd").Op(":=").Id("fields").Dot("Get").Call(Id("i")),
+			Id("flag").Op(":=").Id("c").Dot("LocalFlags").Call().Dot("Lookup").Call(Id("fieldFlag").Call(Id("fd"))),
+			If(Id("flag").Op("==").Nil().Op("||").Op("!").Id("flag").Dot("Changed")).Block(
+				Continue(),
+			),
+			Comment("the file may use either the json or the proto name of the field"),
+			Delete(Id("values"), Id("fd").Dot("JSONName").Call()),
+			Delete(Id("values"), String().Parens(Id("fd").Dot("Name").Call())),
+			Id("values").Index(String().Parens(Id("fd").Dot("Name").Call())).Op("=").Id("flagValue").Call(Id("fd"), Id("flag").Dot("Value").Dot("String").Call()),
+		),
+		List(Id("b"), Err()).Op(":=").Qual("encoding/json", "Marshal").Call(Id("values")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Err()),
+		),
+		Return(Qual(protojsonPkg, "Unmarshal").Call(Id("b"), Id("x"))),
+	)
+
+	f.Comment("flagValue returns the json value of a field flag: strings, bytes and enums are taken")
+	f.Comment("verbatim, all other values are taken as json if valid (protojson reports invalid values)")
+	f.Func().Id("flagValue").Params(
+		Id("fd").Qual(protoreflectPkg, "FieldDescriptor"),
+		Id("s").String(),
+	).Interface().Block(
+		If(Op("!").Id("fd").Dot("IsList").Call().Op("&&").Op("!").Id("fd").Dot("IsMap").Call()).Block(
+			Switch(Id("fd").Dot("Kind").Call()).Block(
+				Case(
+					Qual(protoreflectPkg, "StringKind"),
+					Qual(protoreflectPkg, "BytesKind"),
+					Qual(protoreflectPkg, "EnumKind"),
+				).Block(
+					Return(Id("s")),
+				),
+			),
+		),
+		If(Qual("encoding/json", "Valid").Call(Index().Byte().Parens(Id("s")))).Block(
+			Return(Qual("encoding/json", "RawMessage").Call(Id("s"))),
+		),
+		Return(Id("s")),
+	)
+
+	f.Comment("jsonValue converts a decoded yaml value into a value which can be json encoded")
+	f.Func().Id("jsonValue").Params(Id("v").Interface()).Interface().Block(
+		Switch(Id("v").Op(":=").Id("v").Assert(Type())).Block(
+			Case(Map(Interface()).Interface()).Block(
+				Id("m").Op(":=").Make(Map(String()).Interface(), Len(Id("v"))),
+				For(List(Id("k"), Id("e")).Op(":=").Range().Id("v")).Block(
+					Id("m").Index(Qual("fmt", "Sprint").Call(Id("k"))).Op("=").Id("jsonValue").Call(Id("e")),
+				),
+				Return(Id("m")),
+			),
+			Case(Index().Interface()).Block(
+				For(List(Id("i"), Id("e")).Op(":=").Range().Id("v")).Block(
+					Id("v").Index(Id("i")).Op("=").Id("jsonValue").Call(Id("e")),
+				),
+				Return(Id("v")),
+			),
+			Default().Block(
+				Return(Id("v")),
+			),
+		),
+	)
+}
+
+func addCLIActorAndTarget(f *File, objects Objects) {
+	log.Printf("%s: generating '%s()'\n", "CLI", "actorAndTarget")
+	f.Comment("actorAndTarget reads the actor and the target off the persistent flags")
+	f.Func().Id("actorAndTarget").Params(
+		Id("c").Op("*").Qual(cobraPkg, "Command"),
+	).Params(
+		Op("*").Qual(objects.Actor.Qual, objects.Actor.Id),
+		Op("*").Qual(objects.Target.Qual, objects.Target.Id),
+		Error(),
+	).Block(
+		List(Id("a"), Id("_")).Op(":=").Id("c").Dot("Flags").Call().Dot("GetString").Call(Id("ActorFlag")),
+		If(Id("a").Op("==").Lit("")).Block(
+			Return(Nil(), Nil(), Id("ErrNoActor")),
+		),
+		Id("actor").Op(":=").Op("&").Qual(objects.Actor.Qual, objects.Actor.Id).Values(),
+		If(Err().Op(":=").Qual(protojsonPkg, "Unmarshal").Call(Index().Byte().Parens(Id("a")), Id("actor")), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Nil(), Qual("fmt", "Errorf").Call(Lit("--%s: %w"), Id("ActorFlag"), Err())),
+		),
+		List(Id("t"), Id("_")).Op(":=").Id("c").Dot("Flags").Call().Dot("GetString").Call(Id("TargetFlag")),
+		List(Id("target"), Err()).Op(":=").Qual(objects.Target.Qual, "Parse"+objects.Target.Id).Call(Id("t")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), Nil(), Qual("fmt", "Errorf").Call(Lit("--%s: %w"), Id("TargetFlag"), Err())),
+		),
+		Return(Id("actor"), Id("target"), Nil()),
+	)
+}
+
+func addCLICommands(f *File, typ string, cmds []Command, objects Objects) {
+	f.Comment("CommandsFunc knows how to wire the command handler wrappers against the configured adapters")
+	f.Comment("it is only called once a command is run, so that showing help does not require any adapter")
+	f.Type().Id("CommandsFunc").Func().Params(
+		Id("ctx").Qual("context", "Context"),
+	).Params(
+		Op("*").Qual(objects.Commands.Qual, objects.Commands.Id),
+		Error(),
+	)
+
+	var topics []string
+	byTopic := map[string][]Command{}
+	for _, cmd := range cmds {
+		if _, ok := byTopic[cmd.Topic]; !ok {
+			topics = append(topics, cmd.Topic)
+		}
+		byTopic[cmd.Topic] = append(byTopic[cmd.Topic], cmd)
+	}
+	sort.Strings(topics)
+
+	log.Printf("%s: generating '%s()'\n", typ, "New"+typ)
+	f.Commentf("New%s returns the command which runs the application commands, grouped under their topic", typ)
+	f.Func().Id("New"+typ).Params(
+		Id("use").String(),
+		Id("cmds").Id("CommandsFunc"),
+	).Op("*").Qual(cobraPkg, "Command").BlockFunc(func(g *Group) {
+		g.If(Id("cmds").Op("==").Nil()).Block(
+			Panic(Lit("no 'cmds' provided!")),
+		)
+		g.Id("root").Op(":=").Op("&").Qual(cobraPkg, "Command").Values(Dict{
+			Id("Use"):          Id("use"),
+			Id("Short"):        Lit("Runs application commands"),
+			Id("SilenceUsage"): True(),
+		})
+		g.Id("root").Dot("PersistentFlags").Call().Dot("String").Call(Id("ActorFlag"), Lit(""), Lit("protojson encoded actor, e.g. '{\"user\": \"ops\"}'"))
+		g.Id("root").Dot("PersistentFlags").Call().Dot("String").Call(Id("TargetFlag"), Lit(""), Lit("target identifier"))
+		g.Id("root").Dot("PersistentFlags").Call().Dot("StringP").Call(Id("FileFlag"), Lit("f"), Lit(""), Lit("json or yaml file with the command, field flags take precedence"))
+		for _, topic := range topics {
+			v := lowerFirst(topic) + "Topic"
+			g.Id(v).Op(":=").Op("&").Qual(cobraPkg, "Command").Values(Dict{
+				Id("Use"):   Lit(CLIUse(topic)),
+				Id("Short"): Lit(fmt.Sprintf("Runs %s commands", topic)),
+			})
+			for _, cmd := range byTopic[topic] {
+				g.Id(v).Dot("AddCommand").Call(Id(cliNewCommand(cmd.Name)).Call(Id("cmds")))
+			}
+			g.Id("root").Dot("AddCommand").Call(Id(v))
+		}
+		g.Return(Id("root"))
+	})
+
+	for _, cmd := range cmds {
+		log.Printf("%s: generating '%s()'\n", typ, cliNewCommand(cmd.Name))
+		f.Commentf("%s returns the command which hands %s to its command handler wrapper", cliNewCommand(cmd.Name), cmd.Name)
+		f.Func().Id(cliNewCommand(cmd.Name)).Params(
+			Id("cmds").Id("CommandsFunc"),
+		).Op("*").Qual(cobraPkg, "Command").Block(
+			Id("c").Op(":=").Op("&").Qual(cobraPkg, "Command").Values(Dict{
+				Id("Use"):   Lit(CLIUse(cmd.Name)),
+				Id("Short"): Lit(fmt.Sprintf("Runs %s", cmd.Name)),
+				Id("Args"):  Qual(cobraPkg, "NoArgs"),
+				Id("RunE"): Func().Params(
+					Id("c").Op("*").Qual(cobraPkg, "Command"),
+					Id("args").Index().String(),
+				).Error().Block(
+					List(Id("actor"), Id("target"), Err()).Op(":=").Id("actorAndTarget").Call(Id("c")),
+					If(Err().Op("!=").Nil()).Block(
+						Return(Err()),
+					),
+					Id("x").Op(":=").Op("&").Qual(objects.Domain, cmd.Name).Values(),
+					If(Err().Op(":=").Id("readCommand").Call(Id("c"), Id("x")), Err().Op("!=").Nil()).Block(
+						Return(Err()),
+					),
+					List(Id("w"), Err()).Op(":=").Id("cmds").Call(Id("c").Dot("Context").Call()),
+					If(Err().Op("!=").Nil()).Block(
+						Return(Err()),
+					),
+					If(
+						Err().Op(":=").Id("w").Dot(cmd.Name).Dot(appgen.CommandHandlerMethod).Call(
+							Id("c").Dot("Context").Call(),
+							Op("*").Id("x"),
+							Id("actor"),
+							Id("target"),
+						),
+						Err().Op("!=").Nil(),
+					).Block(
+						Return(Qual("fmt", "Errorf").Call(Lit("%w (%s)"), Err(), Qual(objects.Errors, "KindOf").Call(Err()))),
+					),
+					Qual("fmt", "Fprintf").Call(Id("c").Dot("OutOrStdout").Call(), Lit(cmd.Name+": ok\n")),
+					Return(Nil()),
+				),
+			}),
+			Id("addFieldFlags").Call(Id("c").Dot("Flags").Call(), Parens(Op("&").Qual(objects.Domain, cmd.Name).Values()).Dot("ProtoReflect").Call().Dot("Descriptor").Call()),
+			Return(Id("c")),
+		)
+	}
+}
+
+// Composers ...
+
+func GenCLI(pkgName, typ string, cmds []Command, objects Objects) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenCLI))
+	ret.Line()
+	addCLIFlags(ret)
+	addCLIActorAndTarget(ret, objects)
+	addCLICommands(ret, typ, cmds, objects)
+	return ret
+}
@@ -0,0 +1,22 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+type QualId struct{ Id, Qual string }
+
+// Command is an entry of the struct of the command handler wrappers
+type Command struct {
+	Name       string // field name, equals the domain command
+	Topic      string
+	WithPolicy bool
+}
+
+// Objects are the application objects a port hands to the command handler wrappers
+type Objects struct {
+	Domain   string // import path of the domain commands
+	Commands QualId
+	Actor    QualId
+	Target   QualId
+	Errors   string // import path of the errors generated by 'ddd-gen app errors'
+}
@@ -0,0 +1,402 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+var cmdGenConsumer string = "ddd-gen ports consumer"
+
+func addConsumerBroker(f *File) {
+	f.Comment("Message is a message received from a broker")
+	f.Type().Id("Message").Struct(
+		Id("Id").String(),
+		Id("Body").Index().Byte(),
+		Id("Deliveries").Int().Comment("number of times the message was received, including this one"),
+	)
+
+	f.Comment("Broker knows how to receive and settle messages")
+	f.Type().Id("Broker").Interface(
+		Comment("Receive knows how to block until a message is available or ctx is done, counting its deliveries"),
+		Id("Receive").Params(Id("ctx").Qual("context", "Context")).Params(Id("Message"), Error()),
+		Comment("Ack knows how to settle a message as handled"),
+		Id("Ack").Params(Id("ctx").Qual("context", "Context"), Id("id").String()).Error(),
+		Comment("Nack knows how to settle a message as not handled, it is redelivered if requeue is set"),
+		Id("Nack").Params(Id("ctx").Qual("context", "Context"), Id("id").String(), Id("requeue").Bool()).Error(),
+	)
+}
+
+func addConsumerEnvelope(f *File, cmds []Command, objects Objects) {
+	f.Comment("ErrUnknownCommand signals an envelope of a command without command handler wrapper")
+	f.Var().Id("ErrUnknownCommand").Op("=").Qual("errors", "New").Call(Lit("unknown command"))
+
+	f.Comment("Envelope carries a command together with its actor and target")
+	f.Type().Id("Envelope").Struct(
+		Comment("Type is the name of the command, e.g. BlockAccount"),
+		Id("Type").String().Tag(map[string]string{"json": "type"}),
+		Comment("Payload is the proto encoded command"),
+		Id("Payload").Index().Byte().Tag(map[string]string{"json": "payload"}),
+		Comment("Actor is the proto encoded actor"),
+		Id("Actor").Index().Byte().Tag(map[string]string{"json": "actor"}),
+		Comment("Target is the target identifier"),
+		Id("Target").String().Tag(map[string]string{"json": "target"}),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "Envelope", "EncodeEnvelope")
+	f.Comment("EncodeEnvelope encodes cmd, a pointer to a domain command, together with its actor and target")
+	f.Func().Id("EncodeEnvelope").Params(
+		Id("cmd").Qual(protoPkg, "Message"),
+		Id("actor").Op("*").Qual(objects.Actor.Qual, objects.Actor.Id),
+		Id("target").Op("*").Qual(objects.Target.Qual, objects.Target.Id),
+	).Params(Index().Byte(), Error()).Block(
+		Var().Id("typ").String(),
+		Switch(Id("cmd").Assert(Type())).BlockFunc(func(g *Group) {
+			for _, cmd := range cmds {
+				g.Case(Op("*").Qual(objects.Domain, cmd.Name)).Block(
+					Id("typ").Op("=").Lit(cmd.Name),
+				)
+			}
+			g.Default().Block(
+				Return(Nil(), Qual("fmt", "Errorf").Call(Lit("%w: %T"), Id("ErrUnknownCommand"), Id("cmd"))),
+			)
+		}),
+		List(Id("payload"), Err()).Op(":=").Qual(protoPkg, "Marshal").Call(Id("cmd")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), Err()),
+		),
+		List(Id("a"), Err()).Op(":=").Qual(protoPkg, "Marshal").Call(Id("actor")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), Err()),
+		),
+		Return(Qual("encoding/json", "Marshal").Call(Id("Envelope").Values(Dict{
+			Id("Type"):    Id("typ"),
+			Id("Payload"): Id("payload"),
+			Id("Actor"):   Id("a"),
+			Id("Target"):  Id("target").Dot(appgen.DistinguishableMethod).Call(),
+		}))),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "Envelope", "DecodeEnvelope")
+	f.Comment("DecodeEnvelope decodes an envelope encoded by EncodeEnvelope")
+	f.Func().Id("DecodeEnvelope").Params(
+		Id("b").Index().Byte(),
+	).Params(Id("Envelope"), Error()).Block(
+		Var().Id("env").Id("Envelope"),
+		Err().Op(":=").Qual("encoding/json", "Unmarshal").Call(Id("b"), Op("&").Id("env")),
+		Return(Id("env"), Err()),
+	)
+}
+
+func addConsumer(f *File, typ string, cmds []Command, objects Objects) {
+	f.Comment("ErrorFunc is called with the messages which could not be handled")
+	f.Type().Id("ErrorFunc").Func().Params(Id("m").Id("Message"), Err().Error())
+
+	f.Commentf("%s hands the commands received from a broker to the command handler wrappers", typ)
+	f.Type().Id(typ).Struct(
+		Id("b").Id("Broker"),
+		Id("cmds").Op("*").Qual(objects.Commands.Qual, objects.Commands.Id),
+		Id("maxDeliveries").Int(),
+		Id("onErr").Id("ErrorFunc"),
+	)
+
+	short := cmdShortForm(typ)
+	log.Printf("%s: generating '%s()'\n", typ, "New"+typ)
+	f.Commentf("New%s returns %s, which gives up on a message once it was delivered maxDeliveries times", typ, typ)
+	f.Func().Id("New"+typ).Params(
+		Id("b").Id("Broker"),
+		Id("cmds").Op("*").Qual(objects.Commands.Qual, objects.Commands.Id),
+		Id("maxDeliveries").Int(),
+		Id("onErr").Id("ErrorFunc"),
+	).Op("*").Id(typ).BlockFunc(func(g *Group) {
+		for _, a := range []string{"b", "cmds", "onErr"} {
+			g.If(Id(a).Op("==").Nil()).Block(
+				Panic(Lit(fmt.Sprintf("no '%s' provided!", a))),
+			)
+		}
+		g.If(Id("maxDeliveries").Op("<").Lit(1)).Block(
+			Panic(Lit("'maxDeliveries' must be at least 1!")),
+		)
+		g.Return(Op("&").Id(typ).Values(Dict{
+			Id("b"):             Id("b"),
+			Id("cmds"):          Id("cmds"),
+			Id("maxDeliveries"): Id("maxDeliveries"),
+			Id("onErr"):         Id("onErr"),
+		}))
+	})
+
+	log.Printf("%s: generating '%s()'\n", typ, "Run")
+	f.Comment("Run receives and handles messages until ctx is done or the broker fails")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Run").Params(
+		Id("ctx").Qual("context", "Context"),
+	).Error().Block(
+		For().Block(
+			List(Id("m"), Err()).Op(":=").Id(short).Dot("b").Dot("Receive").Call(Id("ctx")),
+			If(Err().Op("!=").Nil()).Block(
+				If(Id("ctx").Dot("Err").Call().Op("!=").Nil()).Block(
+					Return(Nil()),
+				),
+				Return(Err()),
+			),
+			If(Err().Op(":=").Id(short).Dot("Handle").Call(Id("ctx"), Id("m")), Err().Op("!=").Nil()).Block(
+				Return(Err()),
+			),
+		),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, "Handle")
+	f.Comment("Handle handles m and settles it: it is acked once handled, nacked for redelivery on")
+	f.Comment("retryable errors, and nacked for good (e.g. dead-lettered) on any other error or once it")
+	f.Comment("was delivered maxDeliveries times; only settling errors are returned, handling errors are")
+	f.Comment("passed to the ErrorFunc")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("Handle").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("m").Id("Message"),
+	).Error().Block(
+		Err().Op(":=").Id(short).Dot("dispatch").Call(Id("ctx"), Id("m").Dot("Body")),
+		If(Err().Op("==").Nil()).Block(
+			Return(Id(short).Dot("b").Dot("Ack").Call(Id("ctx"), Id("m").Dot("Id"))),
+		),
+		Id("requeue").Op(":=").Id("Retryable").Call(Err()),
+		If(Id("requeue").Op("&&").Id("m").Dot("Deliveries").Op(">=").Id(short).Dot("maxDeliveries")).Block(
+			Id("requeue").Op("=").False(),
+			Err().Op("=").Qual("fmt", "Errorf").Call(Lit("giving up after %d deliveries: %w"), Id("m").Dot("Deliveries"), Err()),
+		),
+		Id(short).Dot("onErr").Call(Id("m"), Err()),
+		Return(Id(short).Dot("b").Dot("Nack").Call(Id("ctx"), Id("m").Dot("Id"), Id("requeue"))),
+	)
+
+	f.Comment("dispatch decodes an envelope and hands its command to the command handler wrapper")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("dispatch").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("b").Index().Byte(),
+	).Error().Block(
+		List(Id("env"), Err()).Op(":=").Id("DecodeEnvelope").Call(Id("b")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Err()),
+		),
+		Id("actor").Op(":=").Op("&").Qual(objects.Actor.Qual, objects.Actor.Id).Values(),
+		If(Err().Op(":=").Qual(protoPkg, "Unmarshal").Call(Id("env").Dot("Actor"), Id("actor")), Err().Op("!=").Nil()).Block(
+			Return(Err()),
+		),
+		List(Id("target"), Err()).Op(":=").Qual(objects.Target.Qual, "Parse"+objects.Target.Id).Call(Id("env").Dot("Target")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Err()),
+		),
+		Switch(Id("env").Dot("Type")).BlockFunc(func(g *Group) {
+			for _, cmd := range cmds {
+				g.Case(Lit(cmd.Name)).Block(
+					Id("x").Op(":=").Op("&").Qual(objects.Domain, cmd.Name).Values(),
+					If(Err().Op(":=").Qual(protoPkg, "Unmarshal").Call(Id("env").Dot("Payload"), Id("x")), Err().Op("!=").Nil()).Block(
+						Return(Err()),
+					),
+					Return(Id(short).Dot("cmds").Dot(cmd.Name).Dot(appgen.CommandHandlerMethod).Call(
+						Id("ctx"),
+						Op("*").Id("x"),
+						Id("actor"),
+						Id("target"),
+					)),
+				)
+			}
+			g.Default().Block(
+				Return(Qual("fmt", "Errorf").Call(Lit("%w: %s"), Id("ErrUnknownCommand"), Id("env").Dot("Type"))),
+			)
+		}),
+	)
+
+	f.Comment("Retryable reports whether err may go away on redelivery, which is the case for storage errors")
+	f.Comment("authorization, target identification and domain errors, as well as malformed envelopes, are final")
+	f.Func().Id("Retryable").Params(Err().Error()).Bool().Block(
+		Switch(Qual(objects.Errors, "KindOf").Call(Err())).Block(
+			Case(ListFunc(func(g *Group) {
+				for _, kind := range appgen.RetryableErrorKinds {
+					g.Qual(objects.Errors, appgen.ErrorKindConst(kind))
+				}
+			})).Block(
+				Return(True()),
+			),
+		),
+		Return(False()),
+	)
+}
+
+func addMemoryBroker(f *File) {
+	f.Comment("MemoryBroker is an in-memory Broker for tests")
+	f.Comment("nacked messages which are not requeued are kept as dead letters")
+	f.Type().Id("MemoryBroker").Struct(
+		Id("mu").Qual("sync", "Mutex"),
+		Id("seq").Uint64(),
+		Id("queue").Index().Id("Message"),
+		Id("pending").Map(String()).Id("Message"),
+		Id("dead").Index().Id("Message"),
+		Id("notify").Chan().Struct(),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "MemoryBroker", "NewMemoryBroker")
+	f.Comment("NewMemoryBroker returns MemoryBroker")
+	f.Func().Id("NewMemoryBroker").Params().Op("*").Id("MemoryBroker").Block(
+		Return(Op("&").Id("MemoryBroker").Values(Dict{
+			Id("pending"): Make(Map(String()).Id("Message")),
+			Id("notify"):  Make(Chan().Struct(), Lit(1)),
+		})),
+	)
+
+	f.Comment("Publish enqueues body and returns the id of its message")
+	f.Func().Params(
+		Id("b").Op("*").Id("MemoryBroker"),
+	).Id("Publish").Params(Id("body").Index().Byte()).String().Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Id("b").Dot("seq").Op("++"),
+		Id("m").Op(":=").Id("Message").Values(Dict{
+			Id("Id"):   Qual("strconv", "FormatUint").Call(Id("b").Dot("seq"), Lit(10)),
+			Id("Body"): Id("body"),
+		}),
+		Id("b").Dot("queue").Op("=").Append(Id("b").Dot("queue"), Id("m")),
+		Id("b").Dot("mu").Dot("Unlock").Call(),
+		Id("b").Dot("signal").Call(),
+		Return(Id("m").Dot("Id")),
+	)
+
+	f.Comment("signal wakes up a receiver")
+	f.Func().Params(
+		Id("b").Op("*").Id("MemoryBroker"),
+	).Id("signal").Params().Block(
+		Select().Block(
+			Case(Id("b").Dot("notify").Op("<-").Struct().Values()).Block(),
+			Default().Block(),
+		),
+	)
+
+	f.Comment("Receive implements Broker")
+	f.Func().Params(
+		Id("b").Op("*").Id("MemoryBroker"),
+	).Id("Receive").Params(
+		Id("ctx").Qual("context", "Context"),
+	).Params(Id("Message"), Error()).Block(
+		For().Block(
+			If(Err().Op(":=").Id("ctx").Dot("Err").Call(), Err().Op("!=").Nil()).Block(
+				Return(Id("Message").Values(), Err()),
+			),
+			Id("b").Dot("mu").Dot("Lock").Call(),
+			If(Len(Id("b").Dot("queue")).Op(">").Lit(0)).Block(
+				Id("m").Op(":=").Id("b").Dot("queue").Index(Lit(0)),
+				Id("b").Dot("queue").Op("=").Id("b").Dot("queue").Index(Lit(1), Empty()),
+				Id("m").Dot("Deliveries").Op("++"),
+				Id("b").Dot("pending").Index(Id("m").Dot("Id")).Op("=").Id("m"),
+				Id("more").Op(":=").Len(Id("b").Dot("queue")).Op(">").Lit(0),
+				Id("b").Dot("mu").Dot("Unlock").Call(),
+				If(Id("more")).Block(
+					Id("b").Dot("signal").Call(),
+				),
+				Return(Id("m"), Nil()),
+			),
+			Id("b").Dot("mu").Dot("Unlock").Call(),
+			Select().Block(
+				Case(Op("<-").Id("ctx").Dot("Done").Call()).Block(
+					Return(Id("Message").Values(), Id("ctx").Dot("Err").Call()),
+				),
+				Case(Op("<-").Id("b").Dot("notify")).Block(),
+			),
+		),
+	)
+
+	f.Comment("Ack implements Broker")
+	f.Func().Params(
+		Id("b").Op("*").Id("MemoryBroker"),
+	).Id("Ack").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("id").String(),
+	).Error().Block(
+		List(Id("_"), Err()).Op(":=").Id("b").Dot("settle").Call(Id("id")),
+		Return(Err()),
+	)
+
+	f.Comment("Nack implements Broker")
+	f.Func().Params(
+		Id("b").Op("*").Id("MemoryBroker"),
+	).Id("Nack").Params(
+		Id("ctx").Qual("context", "Context"),
+		Id("id").String(),
+		Id("requeue").Bool(),
+	).Error().Block(
+		List(Id("m"), Err()).Op(":=").Id("b").Dot("settle").Call(Id("id")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Err()),
+		),
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		If(Id("requeue")).Block(
+			Id("b").Dot("queue").Op("=").Append(Id("b").Dot("queue"), Id("m")),
+		).Else().Block(
+			Id("b").Dot("dead").Op("=").Append(Id("b").Dot("dead"), Id("m")),
+		),
+		Id("b").Dot("mu").Dot("Unlock").Call(),
+		If(Id("requeue")).Block(
+			Id("b").Dot("signal").Call(),
+		),
+		Return(Nil()),
+	)
+
+	f.Comment("settle removes a received message from the pending messages")
+	f.Func().Params(
+		Id("b").Op("*").Id("MemoryBroker"),
+	).Id("settle").Params(
+		Id("id").String(),
+	).Params(Id("Message"), Error()).Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+		List(Id("m"), Id("ok")).Op(":=").Id("b").Dot("pending").Index(Id("id")),
+		If(Op("!").Id("ok")).Block(
+			Return(Id("Message").Values(), Qual("fmt", "Errorf").Call(Lit("message %s is not pending"), Id("id"))),
+		),
+		Delete(Id("b").Dot("pending"), Id("id")),
+		Return(Id("m"), Nil()),
+	)
+
+	f.Comment("Len returns the number of queued and of pending (received, but not yet settled) messages")
+	f.Func().Params(
+		Id("b").Op("*").Id("MemoryBroker"),
+	).Id("Len").Params().Params(Id("queued"), Id("pending").Int()).Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+		Return(Len(Id("b").Dot("queue")), Len(Id("b").Dot("pending"))),
+	)
+
+	f.Comment("Dead returns the messages which were nacked without requeue")
+	f.Func().Params(
+		Id("b").Op("*").Id("MemoryBroker"),
+	).Id("Dead").Params().Index().Id("Message").Block(
+		Id("b").Dot("mu").Dot("Lock").Call(),
+		Defer().Id("b").Dot("mu").Dot("Unlock").Call(),
+		Return(Append(Index().Id("Message").Values(), Id("b").Dot("dead").Op("..."))),
+	)
+}
+
+// Composers ...
+
+func GenConsumer(pkgName, typ string, cmds []Command, objects Objects) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenConsumer))
+	ret.Line()
+	addConsumerBroker(ret)
+	addConsumerEnvelope(ret, cmds, objects)
+	addConsumer(ret, typ, cmds, objects)
+	addMemoryBroker(ret)
+
+	ret.Comment("compile time assertions")
+	ret.Var().Defs(
+		Id("_").Id("Broker").Op("=").Parens(Op("*").Id("MemoryBroker")).Call(Nil()),
+	)
+	return ret
+}
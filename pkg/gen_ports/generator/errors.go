@@ -0,0 +1,33 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	. "github.com/dave/jennifer/jen"
+)
+
+const errwrapPkg = "github.com/hashicorp/errwrap"
+
+func addErrNoActor(f *File) {
+	f.Comment("ErrNoActor signals that a call does not carry an actor")
+	f.Var().Id("ErrNoActor").Op("=").Qual("errors", "New").Call(Lit("no actor"))
+}
+
+// addErrorCode adds errorCode, which ports use to expose the code of an
+// application error without exposing its wrapped causes
+func addErrorCode(f *File) {
+	f.Comment("errorCode returns the code of the outermost application error in err")
+	f.Func().Id("errorCode").Params(Err().Error()).String().Block(
+		Id("code").Op(":=").Lit(""),
+		Qual(errwrapPkg, "Walk").Call(Err(), Func().Params(Err().Error()).Block(
+			If(
+				List(Id("c"), Id("ok")).Op(":=").Err().Assert(Interface(Id("Code").Params().String())),
+				Id("ok").Op("&&").Id("code").Op("==").Lit(""),
+			).Block(
+				Id("code").Op("=").Id("c").Dot("Code").Call(),
+			),
+		)),
+		Return(Id("code")),
+	)
+}
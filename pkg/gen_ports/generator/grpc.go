@@ -0,0 +1,228 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+var cmdGenGRPC string = "ddd-gen ports grpc"
+
+const (
+	grpcCodesPkg    = "google.golang.org/grpc/codes"
+	grpcStatusPkg   = "google.golang.org/grpc/status"
+	grpcMetadataPkg = "google.golang.org/grpc/metadata"
+	protoPkg        = "google.golang.org/protobuf/proto"
+	emptyPkg        = "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// grpcCodes maps the kinds of application errors to grpc status codes
+var grpcCodes = map[string]string{
+	"Authorization":        "PermissionDenied",
+	"TargetIdentification": "InvalidArgument",
+	"StorageLoading":       "Unavailable",
+	"StorageSaving":        "Aborted",
+	"Domain":               "FailedPrecondition",
+}
+
+func grpcRequest(cmd string) string           { return cmd + "Request" }
+func grpcServiceServer(service string) string { return service + "Server" }
+func grpcUnimplemented(service string) string { return "Unimplemented" + service + "Server" }
+
+func addGRPCActorExtractor(f *File, objects Objects) {
+	f.Comment("ActorMetadataKey is the grpc metadata key which carries the binary encoded actor")
+	f.Const().Id("ActorMetadataKey").Op("=").Lit("actor-bin")
+
+	addErrNoActor(f)
+
+	f.Comment("ActorExtractor knows how to extract the actor of a call")
+	f.Type().Id("ActorExtractor").Interface(
+		Id("ExtractActor").Params(Id("ctx").Qual("context", "Context")).Params(
+			Op("*").Qual(objects.Actor.Qual, objects.Actor.Id),
+			Error(),
+		),
+	)
+
+	f.Commentf("MetadataActorExtractor extracts the actor from the incoming metadata under ActorMetadataKey")
+	f.Comment("the actor is expected to be authenticated upstream (e.g. by an interceptor or a proxy)")
+	f.Type().Id("MetadataActorExtractor").Struct()
+
+	log.Printf("%s: generating '%s()'\n", "MetadataActorExtractor", "ExtractActor")
+	f.Comment("ExtractActor implements ActorExtractor")
+	f.Func().Params(
+		Id("MetadataActorExtractor"),
+	).Id("ExtractActor").Params(
+		Id("ctx").Qual("context", "Context"),
+	).Params(
+		Op("*").Qual(objects.Actor.Qual, objects.Actor.Id),
+		Error(),
+	).Block(
+		List(Id("md"), Id("ok")).Op(":=").Qual(grpcMetadataPkg, "FromIncomingContext").Call(Id("ctx")),
+		If(Op("!").Id("ok")).Block(
+			Return(Nil(), Id("ErrNoActor")),
+		),
+		Id("vs").Op(":=").Id("md").Dot("Get").Call(Id("ActorMetadataKey")),
+		If(Len(Id("vs")).Op("==").Lit(0)).Block(
+			Return(Nil(), Id("ErrNoActor")),
+		),
+		Id("actor").Op(":=").Op("&").Qual(objects.Actor.Qual, objects.Actor.Id).Values(),
+		If(
+			Err().Op(":=").Qual(protoPkg, "Unmarshal").Call(Index().Byte().Parens(Id("vs").Index(Lit(0))), Id("actor")),
+			Err().Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Err()),
+		),
+		Return(Id("actor"), Nil()),
+	)
+}
+
+func addGRPCStatusFromError(f *File, objects Objects) {
+	log.Printf("%s: generating '%s()'\n", "StatusFromError", "StatusFromError")
+	f.Comment("StatusFromError maps the errors of the command handler wrappers to grpc status errors")
+	f.Comment("only the code of the application error is exposed, wrapped causes (e.g. of storage) are not")
+	f.Comment("domain errors are exposed in full, since they explain the rejection to the caller")
+	f.Func().Id("StatusFromError").Params(Err().Error()).Error().Block(
+		Switch(Qual(objects.Errors, "KindOf").Call(Err())).BlockFunc(func(g *Group) {
+			for _, kind := range appgen.ErrorKinds {
+				msg := Id("errorCode").Call(Err())
+				if kind.Name == "Domain" {
+					msg = Err().Dot("Error").Call()
+				}
+				g.Case(Qual(objects.Errors, appgen.ErrorKindConst(kind.Name))).Block(
+					Return(Qual(grpcStatusPkg, "Error").Call(Qual(grpcCodesPkg, grpcCodes[kind.Name]), msg)),
+				)
+			}
+			g.Default().Block(
+				Return(Qual(grpcStatusPkg, "Error").Call(Qual(grpcCodesPkg, "Internal"), Lit("internal error"))),
+			)
+		}),
+	)
+}
+
+func addGRPCServer(f *File, typ string, cmds []Command, objects Objects) {
+	service := objects.Commands.Id
+	f.Commentf("%s implements %s by handing requests to the command handler wrappers", typ, grpcServiceServer(service))
+	f.Type().Id(typ).Struct(
+		Id(grpcUnimplemented(service)),
+		Id("cmds").Op("*").Qual(objects.Commands.Qual, objects.Commands.Id),
+		Id("actor").Id("ActorExtractor"),
+	)
+
+	log.Printf("%s: generating '%s()'\n", typ, "New"+typ)
+	f.Commentf("New%s returns %s", typ, typ)
+	f.Func().Id("New"+typ).Params(
+		Id("cmds").Op("*").Qual(objects.Commands.Qual, objects.Commands.Id),
+		Id("actor").Id("ActorExtractor"),
+	).Op("*").Id(typ).BlockFunc(func(g *Group) {
+		for _, a := range []string{"cmds", "actor"} {
+			g.If(Id(a).Op("==").Nil()).Block(
+				Panic(Lit(fmt.Sprintf("no '%s' provided!", a))),
+			)
+		}
+		g.Return(Op("&").Id(typ).Values(Dict{
+			Id("cmds"):  Id("cmds"),
+			Id("actor"): Id("actor"),
+		}))
+	})
+
+	short := cmdShortForm(typ)
+	for _, cmd := range cmds {
+		log.Printf("%s: generating '%s()'\n", typ, cmd.Name)
+		f.Commentf("%s hands the request to the %s command handler wrapper (topic %s)", cmd.Name, cmd.Name, cmd.Topic)
+		f.Func().Params(
+			Id(short).Op("*").Id(typ),
+		).Id(cmd.Name).Params(
+			Id("ctx").Qual("context", "Context"),
+			Id("req").Op("*").Id(grpcRequest(cmd.Name)),
+		).Params(
+			Op("*").Qual(emptyPkg, "Empty"),
+			Error(),
+		).Block(
+			List(Id("actor"), Err()).Op(":=").Id(short).Dot("actor").Dot("ExtractActor").Call(Id("ctx")),
+			If(Err().Op("!=").Nil()).Block(
+				Return(Nil(), Qual(grpcStatusPkg, "Error").Call(Qual(grpcCodesPkg, "Unauthenticated"), Err().Dot("Error").Call())),
+			),
+			List(Id("target"), Err()).Op(":=").Qual(objects.Target.Qual, "Parse"+objects.Target.Id).Call(Id("req").Dot("GetTarget").Call()),
+			If(Err().Op("!=").Nil()).Block(
+				Return(Nil(), Qual(grpcStatusPkg, "Error").Call(Qual(grpcCodesPkg, "InvalidArgument"), Err().Dot("Error").Call())),
+			),
+			Id("x").Op(":=").Id("req").Dot("GetCommand").Call(),
+			If(Id("x").Op("==").Nil()).Block(
+				Return(Nil(), Qual(grpcStatusPkg, "Error").Call(Qual(grpcCodesPkg, "InvalidArgument"), Lit("no command provided"))),
+			),
+			If(
+				Err().Op(":=").Id(short).Dot("cmds").Dot(cmd.Name).Dot(appgen.CommandHandlerMethod).Call(
+					Id("ctx"),
+					Op("*").Id("x"),
+					Id("actor"),
+					Id("target"),
+				),
+				Err().Op("!=").Nil(),
+			).Block(
+				Return(Nil(), Id("StatusFromError").Call(Err())),
+			),
+			Return(Op("&").Qual(emptyPkg, "Empty").Values(), Nil()),
+		)
+	}
+}
+
+// GenGRPCProto generates the grpc service definition of the commands
+// commandsProto is the import path of the proto file which defines the domain commands
+// in protoPackage, goPackage is the import path of the generated go code
+func GenGRPCProto(protoPackage, goPackage, commandsProto, commandsProtoPackage string, cmds []Command, objects Objects) []byte {
+	service := objects.Commands.Id
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by '%s': DO NOT EDIT.\n\n", cmdGenGRPC)
+	fmt.Fprintf(&b, "syntax = \"proto3\";\npackage %s;\n\n", protoPackage)
+	fmt.Fprintf(&b, "option go_package = %q;\n\n", goPackage)
+	fmt.Fprintf(&b, "import \"google/protobuf/empty.proto\";\nimport %q;\n\n", commandsProto)
+
+	log.Printf("%s: generating service '%s'\n", cmdGenGRPC, service)
+	fmt.Fprintf(&b, "// %s hands commands to the command handler wrappers\n", service)
+	fmt.Fprintf(&b, "// the actor is carried as binary encoded metadata under 'actor-bin'\n")
+	fmt.Fprintf(&b, "service %s {\n", service)
+	for i, cmd := range cmds {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "  // %s (topic %s)\n", cmd.Name, cmd.Topic)
+		fmt.Fprintf(&b, "  rpc %s(%s) returns (google.protobuf.Empty);\n", cmd.Name, grpcRequest(cmd.Name))
+	}
+	b.WriteString("}\n")
+
+	for _, cmd := range cmds {
+		fmt.Fprintf(&b, "\n// %s requests %s on a target\n", grpcRequest(cmd.Name), cmd.Name)
+		fmt.Fprintf(&b, "message %s {\n", grpcRequest(cmd.Name))
+		fmt.Fprintf(&b, "  // target identifier, as parsed by Parse%s\n", objects.Target.Id)
+		b.WriteString("  string target = 1;\n")
+		fmt.Fprintf(&b, "  %s.%s command = 2;\n", commandsProtoPackage, cmd.Name)
+		b.WriteString("}\n")
+	}
+	return b.Bytes()
+}
+
+// Composers ...
+
+func GenGRPC(pkgName, typ string, cmds []Command, objects Objects) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenGRPC))
+	ret.Line()
+	addGRPCActorExtractor(ret, objects)
+	addGRPCStatusFromError(ret, objects)
+	addErrorCode(ret)
+	addGRPCServer(ret, typ, cmds, objects)
+
+	ret.Comment("compile time assertions")
+	ret.Var().Defs(
+		Id("_").Id(grpcServiceServer(objects.Commands.Id)).Op("=").Parens(Op("*").Id(typ)).Call(Nil()),
+		Id("_").Id("ActorExtractor").Op("=").Id("MetadataActorExtractor").Values(),
+	)
+	return ret
+}
@@ -0,0 +1,317 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	. "github.com/dave/jennifer/jen"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+)
+
+var cmdGenHTTP string = "ddd-gen ports http"
+
+const (
+	httpPkg      = "net/http"
+	protojsonPkg = "google.golang.org/protobuf/encoding/protojson"
+)
+
+type httpStatusCode struct {
+	Ident string // identifier in net/http
+	Code  int
+}
+
+// httpStatus maps the kinds of errors to http status codes, the kinds of application errors
+// are complemented by the kinds of errors which the http port itself raises
+var httpStatus = map[string]httpStatusCode{
+	"Authorization":        {"StatusForbidden", http.StatusForbidden},
+	"TargetIdentification": {"StatusBadRequest", http.StatusBadRequest},
+	"StorageLoading":       {"StatusServiceUnavailable", http.StatusServiceUnavailable},
+	"StorageSaving":        {"StatusConflict", http.StatusConflict},
+	"Domain":               {"StatusUnprocessableEntity", http.StatusUnprocessableEntity},
+	"Unknown":              {"StatusInternalServerError", http.StatusInternalServerError},
+	"Authentication":       {"StatusUnauthorized", http.StatusUnauthorized},
+	"Request":              {"StatusBadRequest", http.StatusBadRequest},
+}
+
+// HTTPRoute returns the route of a command, grouped under its topic
+func HTTPRoute(cmd Command) string {
+	return "/" + strings.ToLower(cmd.Topic) + "/" + strings.ReplaceAll(ToSnakeCase(cmd.Name), "_", "-")
+}
+
+func httpRouteConst(cmd string) string { return cmd + "Route" }
+
+func addHTTPExtractors(f *File, objects Objects) {
+	f.Comment("ActorHeader is the request header which carries the protojson encoded actor")
+	f.Const().Id("ActorHeader").Op("=").Lit("X-Actor")
+
+	f.Comment("TargetParam is the query parameter which carries the target identifier")
+	f.Const().Id("TargetParam").Op("=").Lit("target")
+
+	addErrNoActor(f)
+
+	f.Comment("ActorExtractor knows how to extract the actor of a request")
+	f.Type().Id("ActorExtractor").Interface(
+		Id("ExtractActor").Params(Id("r").Op("*").Qual(httpPkg, "Request")).Params(
+			Op("*").Qual(objects.Actor.Qual, objects.Actor.Id),
+			Error(),
+		),
+	)
+
+	f.Comment("TargetExtractor knows how to extract the target of a request")
+	f.Type().Id("TargetExtractor").Interface(
+		Id("ExtractTarget").Params(Id("r").Op("*").Qual(httpPkg, "Request")).Params(
+			Op("*").Qual(objects.Target.Qual, objects.Target.Id),
+			Error(),
+		),
+	)
+
+	f.Comment("HeaderActorExtractor extracts the actor from the ActorHeader of a request")
+	f.Comment("the actor is expected to be authenticated upstream (e.g. by a middleware or a proxy)")
+	f.Type().Id("HeaderActorExtractor").Struct()
+
+	log.Printf("%s: generating '%s()'\n", "HeaderActorExtractor", "ExtractActor")
+	f.Comment("ExtractActor implements ActorExtractor")
+	f.Func().Params(
+		Id("HeaderActorExtractor"),
+	).Id("ExtractActor").Params(
+		Id("r").Op("*").Qual(httpPkg, "Request"),
+	).Params(
+		Op("*").Qual(objects.Actor.Qual, objects.Actor.Id),
+		Error(),
+	).Block(
+		Id("v").Op(":=").Id("r").Dot("Header").Dot("Get").Call(Id("ActorHeader")),
+		If(Id("v").Op("==").Lit("")).Block(
+			Return(Nil(), Id("ErrNoActor")),
+		),
+		Id("actor").Op(":=").Op("&").Qual(objects.Actor.Qual, objects.Actor.Id).Values(),
+		If(
+			Err().Op(":=").Qual(protojsonPkg, "Unmarshal").Call(Index().Byte().Parens(Id("v")), Id("actor")),
+			Err().Op("!=").Nil(),
+		).Block(
+			Return(Nil(), Err()),
+		),
+		Return(Id("actor"), Nil()),
+	)
+
+	f.Commentf("QueryTargetExtractor extracts the target from the TargetParam of a request through Parse%s", objects.Target.Id)
+	f.Type().Id("QueryTargetExtractor").Struct()
+
+	log.Printf("%s: generating '%s()'\n", "QueryTargetExtractor", "ExtractTarget")
+	f.Comment("ExtractTarget implements TargetExtractor")
+	f.Func().Params(
+		Id("QueryTargetExtractor"),
+	).Id("ExtractTarget").Params(
+		Id("r").Op("*").Qual(httpPkg, "Request"),
+	).Params(
+		Op("*").Qual(objects.Target.Qual, objects.Target.Id),
+		Error(),
+	).Block(
+		Return(Qual(objects.Target.Qual, "Parse"+objects.Target.Id).Call(
+			Id("r").Dot("URL").Dot("Query").Call().Dot("Get").Call(Id("TargetParam")),
+		)),
+	)
+}
+
+func addHTTPErrors(f *File, objects Objects) {
+	f.Comment("ErrorBody is the json encoded body of all error responses")
+	f.Type().Id("ErrorBody").Struct(
+		Comment("Kind classifies the error, e.g. Authorization or Request"),
+		Id("Kind").String().Tag(map[string]string{"json": "kind"}),
+		Comment("Code identifies application errors, e.g. ErrNotAuthorizedToBlockAccount"),
+		Id("Code").String().Tag(map[string]string{"json": "code,omitempty"}),
+		Id("Message").String().Tag(map[string]string{"json": "message"}),
+	)
+
+	log.Printf("%s: generating '%s()'\n", "ErrorBody", "WriteError")
+	f.Comment("WriteError maps the errors of the command handler wrappers to http responses")
+	f.Comment("only the code of the application error is exposed, wrapped causes (e.g. of storage) are not")
+	f.Comment("domain errors are exposed in full, since they explain the rejection to the caller")
+	f.Func().Id("WriteError").Params(
+		Id("w").Qual(httpPkg, "ResponseWriter"),
+		Err().Error(),
+	).Block(
+		Id("kind").Op(":=").Qual(objects.Errors, "KindOf").Call(Err()),
+		Id("body").Op(":=").Id("ErrorBody").Values(Dict{
+			Id("Kind"): Id("kind").Dot("String").Call(),
+			Id("Code"): Id("errorCode").Call(Err()),
+		}),
+		Switch(Id("kind")).BlockFunc(func(g *Group) {
+			for _, kind := range appgen.ErrorKinds {
+				msg := Id("body").Dot("Code")
+				if kind.Name == "Domain" {
+					msg = Err().Dot("Error").Call()
+				}
+				g.Case(Qual(objects.Errors, appgen.ErrorKindConst(kind.Name))).Block(
+					Id("body").Dot("Message").Op("=").Add(msg),
+					Id("writeError").Call(Id("w"), Qual(httpPkg, httpStatus[kind.Name].Ident), Id("body")),
+				)
+			}
+			g.Default().Block(
+				Id("body").Dot("Code").Op("=").Lit(""),
+				Id("body").Dot("Message").Op("=").Lit("internal error"),
+				Id("writeError").Call(Id("w"), Qual(httpPkg, httpStatus["Unknown"].Ident), Id("body")),
+			)
+		}),
+	)
+
+	f.Comment("writeError writes body with status")
+	f.Func().Id("writeError").Params(
+		Id("w").Qual(httpPkg, "ResponseWriter"),
+		Id("status").Int(),
+		Id("body").Id("ErrorBody"),
+	).Block(
+		Id("w").Dot("Header").Call().Dot("Set").Call(Lit("Content-Type"), Lit("application/json")),
+		Id("w").Dot("WriteHeader").Call(Id("status")),
+		Qual("encoding/json", "NewEncoder").Call(Id("w")).Dot("Encode").Call(Id("body")),
+	)
+}
+
+func addHTTPHandler(f *File, typ string, cmds []Command, objects Objects) {
+	f.Comment("routes of the commands, grouped under their topic")
+	f.Const().DefsFunc(func(g *Group) {
+		for _, cmd := range cmds {
+			g.Id(httpRouteConst(cmd.Name)).Op("=").Lit(HTTPRoute(cmd))
+		}
+	})
+
+	f.Commentf("%s hands requests to the command handler wrappers, one route per command", typ)
+	f.Type().Id(typ).Struct(
+		Id("mux").Op("*").Qual(httpPkg, "ServeMux"),
+		Id("cmds").Op("*").Qual(objects.Commands.Qual, objects.Commands.Id),
+		Id("actor").Id("ActorExtractor"),
+		Id("target").Id("TargetExtractor"),
+	)
+
+	short := cmdShortForm(typ)
+	log.Printf("%s: generating '%s()'\n", typ, "New"+typ)
+	f.Commentf("New%s returns %s", typ, typ)
+	f.Func().Id("New"+typ).Params(
+		Id("cmds").Op("*").Qual(objects.Commands.Qual, objects.Commands.Id),
+		Id("actor").Id("ActorExtractor"),
+		Id("target").Id("TargetExtractor"),
+	).Op("*").Id(typ).BlockFunc(func(g *Group) {
+		for _, a := range []string{"cmds", "actor", "target"} {
+			g.If(Id(a).Op("==").Nil()).Block(
+				Panic(Lit(fmt.Sprintf("no '%s' provided!", a))),
+			)
+		}
+		g.Id(short).Op(":=").Op("&").Id(typ).Values(Dict{
+			Id("mux"):    Qual(httpPkg, "NewServeMux").Call(),
+			Id("cmds"):   Id("cmds"),
+			Id("actor"):  Id("actor"),
+			Id("target"): Id("target"),
+		})
+		for _, cmd := range cmds {
+			g.Id(short).Dot("mux").Dot("HandleFunc").Call(Id(httpRouteConst(cmd.Name)), Id(short).Dot(cmd.Name))
+		}
+		g.Return(Id(short))
+	})
+
+	f.Comment("ServeHTTP implements http.Handler")
+	f.Func().Params(
+		Id(short).Op("*").Id(typ),
+	).Id("ServeHTTP").Params(
+		Id("w").Qual(httpPkg, "ResponseWriter"),
+		Id("r").Op("*").Qual(httpPkg, "Request"),
+	).Block(
+		Id(short).Dot("mux").Dot("ServeHTTP").Call(Id("w"), Id("r")),
+	)
+
+	for _, cmd := range cmds {
+		log.Printf("%s: generating '%s()'\n", typ, cmd.Name)
+		f.Commentf("%s hands a POST request with a json encoded %s to its command handler wrapper", cmd.Name, cmd.Name)
+		f.Func().Params(
+			Id(short).Op("*").Id(typ),
+		).Id(cmd.Name).Params(
+			Id("w").Qual(httpPkg, "ResponseWriter"),
+			Id("r").Op("*").Qual(httpPkg, "Request"),
+		).Block(
+			If(Id("r").Dot("Method").Op("!=").Qual(httpPkg, "MethodPost")).Block(
+				Id("w").Dot("Header").Call().Dot("Set").Call(Lit("Allow"), Qual(httpPkg, "MethodPost")),
+				Id("writeError").Call(Id("w"), Qual(httpPkg, "StatusMethodNotAllowed"), Id("ErrorBody").Values(Dict{
+					Id("Kind"):    Lit("Request"),
+					Id("Message"): Lit("method not allowed"),
+				})),
+				Return(),
+			),
+			List(Id("actor"), Err()).Op(":=").Id(short).Dot("actor").Dot("ExtractActor").Call(Id("r")),
+			If(Err().Op("!=").Nil()).Block(
+				Id("writeError").Call(Id("w"), Qual(httpPkg, httpStatus["Authentication"].Ident), Id("ErrorBody").Values(Dict{
+					Id("Kind"):    Lit("Authentication"),
+					Id("Message"): Err().Dot("Error").Call(),
+				})),
+				Return(),
+			),
+			List(Id("target"), Err()).Op(":=").Id(short).Dot("target").Dot("ExtractTarget").Call(Id("r")),
+			If(Err().Op("!=").Nil()).Block(
+				Id("writeError").Call(Id("w"), Qual(httpPkg, httpStatus["TargetIdentification"].Ident), Id("ErrorBody").Values(Dict{
+					Id("Kind"):    Lit("TargetIdentification"),
+					Id("Message"): Err().Dot("Error").Call(),
+				})),
+				Return(),
+			),
+			Id("x").Op(":=").Op("&").Qual(objects.Domain, cmd.Name).Values(),
+			If(Err().Op(":=").Id("decode").Call(Id("r"), Id("x")), Err().Op("!=").Nil()).Block(
+				Id("writeError").Call(Id("w"), Qual(httpPkg, httpStatus["Request"].Ident), Id("ErrorBody").Values(Dict{
+					Id("Kind"):    Lit("Request"),
+					Id("Message"): Err().Dot("Error").Call(),
+				})),
+				Return(),
+			),
+			If(
+				Err().Op(":=").Id(short).Dot("cmds").Dot(cmd.Name).Dot(appgen.CommandHandlerMethod).Call(
+					Id("r").Dot("Context").Call(),
+					Op("*").Id("x"),
+					Id("actor"),
+					Id("target"),
+				),
+				Err().Op("!=").Nil(),
+			).Block(
+				Id("WriteError").Call(Id("w"), Err()),
+				Return(),
+			),
+			Id("w").Dot("WriteHeader").Call(Qual(httpPkg, "StatusNoContent")),
+		)
+	}
+
+	f.Comment("decode decodes the json encoded command of a request body, an empty body is an empty command")
+	f.Func().Id("decode").Params(
+		Id("r").Op("*").Qual(httpPkg, "Request"),
+		Id("x").Qual(protoPkg, "Message"),
+	).Error().Block(
+		List(Id("b"), Err()).Op(":=").Qual("io/ioutil", "ReadAll").Call(Id("r").Dot("Body")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Err()),
+		),
+		If(Len(Id("b")).Op("==").Lit(0)).Block(
+			Return(Nil()),
+		),
+		Return(Qual(protojsonPkg, "Unmarshal").Call(Id("b"), Id("x"))),
+	)
+}
+
+// Composers ...
+
+func GenHTTP(pkgName, typ string, cmds []Command, objects Objects) *File {
+	ret := NewFile(pkgName)
+	ret.HeaderComment(fmt.Sprintf("Code generated by '%s': DO NOT EDIT.", cmdGenHTTP))
+	ret.Line()
+	addHTTPExtractors(ret, objects)
+	addHTTPErrors(ret, objects)
+	addErrorCode(ret)
+	addHTTPHandler(ret, typ, cmds, objects)
+
+	ret.Comment("compile time assertions")
+	ret.Var().Defs(
+		Id("_").Qual(httpPkg, "Handler").Op("=").Parens(Op("*").Id(typ)).Call(Nil()),
+		Id("_").Id("ActorExtractor").Op("=").Id("HeaderActorExtractor").Values(),
+		Id("_").Id("TargetExtractor").Op("=").Id("QueryTargetExtractor").Values(),
+	)
+	return ret
+}
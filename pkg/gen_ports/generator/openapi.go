@@ -0,0 +1,303 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	yaml "gopkg.in/yaml.v2"
+
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+	"github.com/xoe-labs/ddd-gen/pkg/jsonschema"
+)
+
+var cmdGenOpenAPI string = "ddd-gen ports openapi"
+
+const openAPIVersion = "3.0.3"
+
+// protoRegistry indexes the messages and enums of the file descriptors by full name
+type protoRegistry struct {
+	msgs  map[string]*descriptorpb.DescriptorProto
+	enums map[string]*descriptorpb.EnumDescriptorProto
+	pkgs  map[string]string // package of the domain commands by command name
+	docs  map[string]string
+}
+
+func newProtoRegistry(fds []*descriptorpb.FileDescriptorProto, docs map[string]string) *protoRegistry {
+	r := &protoRegistry{
+		msgs:  make(map[string]*descriptorpb.DescriptorProto),
+		enums: make(map[string]*descriptorpb.EnumDescriptorProto),
+		pkgs:  make(map[string]string),
+		docs:  docs,
+	}
+	var walk func(prefix string, msgs []*descriptorpb.DescriptorProto, enums []*descriptorpb.EnumDescriptorProto)
+	walk = func(prefix string, msgs []*descriptorpb.DescriptorProto, enums []*descriptorpb.EnumDescriptorProto) {
+		for _, e := range enums {
+			r.enums[prefix+e.GetName()] = e
+		}
+		for _, m := range msgs {
+			r.msgs[prefix+m.GetName()] = m
+			walk(prefix+m.GetName()+".", m.GetNestedType(), m.GetEnumType())
+		}
+	}
+	for _, fd := range fds {
+		prefix := ""
+		if fd.GetPackage() != "" {
+			prefix = fd.GetPackage() + "."
+		}
+		for _, m := range fd.GetMessageType() {
+			r.pkgs[m.GetName()] = prefix
+		}
+		walk(prefix, fd.GetMessageType(), fd.GetEnumType())
+	}
+	return r
+}
+
+// schemas adds the schema of the message or enum name, and of all types it refers to
+func (r *protoRegistry) schemas(name string, schemas map[string]jsonschema.Obj) {
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	if e, ok := r.enums[name]; ok {
+		var values []string
+		for _, v := range e.GetValue() {
+			values = append(values, v.GetName())
+		}
+		schemas[name] = r.described(name, jsonschema.Obj{{Key: "type", Value: "string"}, {Key: "enum", Value: values}})
+		return
+	}
+	m, ok := r.msgs[name]
+	if !ok {
+		return
+	}
+	schemas[name] = nil // break cycles
+	var props jsonschema.Obj
+	for _, fd := range m.GetField() {
+		props = append(props, jsonschema.KV{Key: fd.GetJsonName(), Value: r.described(name+"."+fd.GetName(), r.fieldSchema(fd, schemas))})
+	}
+	s := jsonschema.Obj{{Key: "type", Value: "object"}}
+	if len(props) > 0 {
+		s = append(s, jsonschema.KV{Key: "properties", Value: props})
+	}
+	schemas[name] = r.described(name, s)
+}
+
+func (r *protoRegistry) fieldSchema(fd *descriptorpb.FieldDescriptorProto, schemas map[string]jsonschema.Obj) jsonschema.Obj {
+	typeName := strings.TrimPrefix(fd.GetTypeName(), ".")
+	if entry, ok := r.msgs[typeName]; ok && entry.GetOptions().GetMapEntry() {
+		// map keys are always encoded as json object keys
+		return jsonschema.Obj{
+			{Key: "type", Value: "object"},
+			{Key: "additionalProperties", Value: r.fieldSchema(entry.GetField()[1], schemas)},
+		}
+	}
+	var s jsonschema.Obj
+	switch fd.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		if wk, ok := jsonschema.WellKnown(protoreflect.FullName(typeName)); ok {
+			s = wk
+		} else if _, ok := r.msgs[typeName]; ok {
+			r.schemas(typeName, schemas)
+			s = jsonschema.Ref(typeName)
+		} else {
+			s = jsonschema.Obj{{Key: "type", Value: "object"}} // defined outside of the domain package
+		}
+	case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		if _, ok := r.enums[typeName]; ok {
+			r.schemas(typeName, schemas)
+			s = jsonschema.Ref(typeName)
+		} else {
+			s = jsonschema.Obj{{Key: "type", Value: "string"}}
+		}
+	default:
+		// kinds are numbered like the field types of the descriptor
+		s = jsonschema.Scalar(protoreflect.Kind(fd.GetType()))
+	}
+	if fd.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+		return jsonschema.Obj{{Key: "type", Value: "array"}, {Key: "items", Value: s}}
+	}
+	return s
+}
+
+// described adds the description of name to s
+func (r *protoRegistry) described(name string, s jsonschema.Obj) jsonschema.Obj {
+	return jsonschema.Described(r.docs[name], s)
+}
+
+// errorCodes returns the codes of the sentinel errors of a command handler wrapper by kind
+func errorCodes(cmd Command, variants appgen.Variants) map[string][]string {
+	codes := map[string][]string{}
+	for _, se := range appgen.SentinelErrors(cmd.Name, cmd.WithPolicy, variants) {
+		codes[se.Kind] = append(codes[se.Kind], se.Name)
+	}
+	return codes
+}
+
+func openAPIOperation(cmd Command, name string, variants appgen.Variants, r *protoRegistry) jsonschema.Obj {
+	op := jsonschema.Obj{
+		{Key: "tags", Value: []string{cmd.Topic}},
+		{Key: "operationId", Value: cmd.Name},
+	}
+	if doc := r.docs[name]; doc != "" {
+		lines := strings.SplitN(doc, "\n", 2)
+		op = append(op, jsonschema.KV{Key: "summary", Value: lines[0]})
+		if len(lines) > 1 {
+			op = append(op, jsonschema.KV{Key: "description", Value: doc})
+		}
+	}
+	op = append(op,
+		jsonschema.KV{Key: "parameters", Value: []jsonschema.Obj{
+			{{Key: "$ref", Value: "#/components/parameters/Target"}},
+			{{Key: "$ref", Value: "#/components/parameters/Actor"}},
+		}},
+		jsonschema.KV{Key: "requestBody", Value: jsonschema.Obj{
+			{Key: "content", Value: jsonschema.Obj{
+				{Key: "application/json", Value: jsonschema.Obj{{Key: "schema", Value: jsonschema.Ref(name)}}},
+			}},
+		}},
+	)
+
+	// group the kinds of errors by status
+	codes := errorCodes(cmd, variants)
+	kinds := map[int][]string{}
+	for _, kind := range appgen.ErrorKinds {
+		if _, ok := codes[kind.Name]; !ok {
+			// e.g. without policy nor tenancy, the command handler wrapper does not authorize
+			continue
+		}
+		status := httpStatus[kind.Name].Code
+		kinds[status] = append(kinds[status], kind.Name)
+	}
+	for _, kind := range []string{"Unknown", "Authentication", "Request"} {
+		status := httpStatus[kind].Code
+		kinds[status] = append(kinds[status], kind)
+	}
+	var statuses []int
+	for status := range kinds {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+
+	responses := jsonschema.Obj{{Key: "204", Value: jsonschema.Obj{{Key: "description", Value: cmd.Name + " was handled"}}}}
+	for _, status := range statuses {
+		var desc []string
+		for _, kind := range kinds[status] {
+			if cs, ok := codes[kind]; ok {
+				desc = append(desc, fmt.Sprintf("%s: %s", kind, strings.Join(cs, ", ")))
+			} else {
+				desc = append(desc, kind)
+			}
+		}
+		responses = append(responses, jsonschema.KV{Key: strconv.Itoa(status), Value: jsonschema.Obj{
+			{Key: "description", Value: strings.Join(desc, "; ")},
+			{Key: "content", Value: jsonschema.Obj{
+				{Key: "application/json", Value: jsonschema.Obj{{Key: "schema", Value: jsonschema.Ref("ErrorBody")}}},
+			}},
+		}})
+	}
+	return append(op, jsonschema.KV{Key: "responses", Value: responses})
+}
+
+func openAPIComponents(schemas map[string]jsonschema.Obj, r *protoRegistry) jsonschema.Obj {
+	kinds := []string{}
+	for _, kind := range appgen.ErrorKinds {
+		kinds = append(kinds, kind.Name)
+	}
+	kinds = append(kinds, "Unknown", "Authentication", "Request")
+	schemas["ErrorBody"] = jsonschema.Obj{
+		{Key: "type", Value: "object"},
+		{Key: "required", Value: []string{"kind", "message"}},
+		{Key: "properties", Value: jsonschema.Obj{
+			{Key: "kind", Value: jsonschema.Obj{
+				{Key: "type", Value: "string"},
+				{Key: "enum", Value: kinds},
+				{Key: "description", Value: "classifies the error"},
+			}},
+			{Key: "code", Value: jsonschema.Obj{
+				{Key: "type", Value: "string"},
+				{Key: "description", Value: "identifies application errors, e.g. ErrNotAuthorizedToBlockAccount"},
+			}},
+			{Key: "message", Value: jsonschema.Obj{{Key: "type", Value: "string"}}},
+		}},
+	}
+	var names []string
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var ss jsonschema.Obj
+	for _, name := range names {
+		ss = append(ss, jsonschema.KV{Key: name, Value: schemas[name]})
+	}
+	return jsonschema.Obj{
+		{Key: "parameters", Value: jsonschema.Obj{
+			{Key: "Actor", Value: jsonschema.Obj{
+				{Key: "name", Value: "X-Actor"},
+				{Key: "in", Value: "header"},
+				{Key: "required", Value: true},
+				{Key: "description", Value: "protojson encoded actor, authenticated upstream"},
+				{Key: "schema", Value: jsonschema.Obj{{Key: "type", Value: "string"}}},
+			}},
+			{Key: "Target", Value: jsonschema.Obj{
+				{Key: "name", Value: "target"},
+				{Key: "in", Value: "query"},
+				{Key: "required", Value: true},
+				{Key: "description", Value: "target identifier"},
+				{Key: "schema", Value: jsonschema.Obj{{Key: "type", Value: "string"}}},
+			}},
+		}},
+		{Key: "schemas", Value: ss},
+	}
+}
+
+// Composers ...
+
+// GenOpenAPI generates the OpenAPI document of the http port, the output is deterministic:
+// paths follow the order of the commands, components are sorted by name
+func GenOpenAPI(title, version string, cmds []Command, variants appgen.Variants, fds []*descriptorpb.FileDescriptorProto, docs map[string]string) ([]byte, error) {
+	r := newProtoRegistry(fds, docs)
+	schemas := make(map[string]jsonschema.Obj)
+
+	var tags []jsonschema.Obj
+	seen := map[string]bool{}
+	var paths jsonschema.Obj
+	for _, cmd := range cmds {
+		prefix, ok := r.pkgs[cmd.Name]
+		if !ok {
+			return nil, fmt.Errorf("no proto message for command %s", cmd.Name)
+		}
+		log.Printf("%s: generating path '%s'\n", cmdGenOpenAPI, HTTPRoute(cmd))
+		r.schemas(prefix+cmd.Name, schemas)
+		paths = append(paths, jsonschema.KV{Key: HTTPRoute(cmd), Value: jsonschema.Obj{
+			{Key: "post", Value: openAPIOperation(cmd, prefix+cmd.Name, variants, r)},
+		}})
+		if !seen[cmd.Topic] {
+			seen[cmd.Topic] = true
+			tags = append(tags, jsonschema.Obj{{Key: "name", Value: cmd.Topic}})
+		}
+	}
+
+	doc := jsonschema.Obj{
+		{Key: "openapi", Value: openAPIVersion},
+		{Key: "info", Value: jsonschema.Obj{{Key: "title", Value: title}, {Key: "version", Value: version}}},
+		{Key: "tags", Value: tags},
+		{Key: "paths", Value: paths},
+		{Key: "components", Value: openAPIComponents(schemas, r)},
+	}
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Code generated by '%s': DO NOT EDIT.\n", cmdGenOpenAPI)
+	buf.Write(b)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,50 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package generator
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Utils ...
+
+func cmdShortForm(s string) string {
+	re := regexp.MustCompile(`[A-Z]`)
+	var b bytes.Buffer
+	for _, el := range re.FindAllString(s, -1) {
+		b.WriteString(strings.ToLower(el))
+	}
+	return b.String()
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+var (
+	matchFirstLetterFollowedByCapWord = regexp.MustCompile("(.)([A-Z][a-z]+)")
+	matchAllLowCapTransition          = regexp.MustCompile("([a-z0-9])([A-Z])")
+)
+
+// ToSnakeCase converts a go identifier to a snake case SQL identifier
+func ToSnakeCase(str string) string {
+	snake := matchFirstLetterFollowedByCapWord.ReplaceAllString(str, "${1}_${2}")
+	snake = matchAllLowCapTransition.ReplaceAllString(snake, "${1}_${2}")
+	return strings.ToLower(snake)
+}
+
+// fieldVar avoids collisions of field names with the identifiers of generated code
+func fieldVar(field string, reserved ...string) string {
+	for _, r := range reserved {
+		if field == r {
+			return field + "Fld"
+		}
+	}
+	return field
+}
@@ -0,0 +1,49 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_ports
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports/generator"
+)
+
+var protoPackagePattern = regexp.MustCompile(`(?m)^package\s+([\w.]+)\s*;`)
+
+// GenGRPC generates the grpc service definition and its server into the current working directory
+// commandsProto is the import path of the proto file of the domain commands, relative to protoPath
+func GenGRPC(typ, protoPackage, protoPath, commandsProto string, conf *Config) error {
+	if commandsProto == "" {
+		return fmt.Errorf("'commands-proto' is empty")
+	}
+	cwd, goPackage, pkgPath, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	cmds, err := loadCommands(conf.Commands)
+	if err != nil {
+		return err
+	}
+	src, err := ioutil.ReadFile(path.Join(cwd, protoPath, commandsProto))
+	if err != nil {
+		return err
+	}
+	matches := protoPackagePattern.FindSubmatch(src)
+	if matches == nil {
+		return fmt.Errorf("'%s' does not declare a package", commandsProto)
+	}
+	if protoPackage == "" {
+		protoPackage = goPackage
+	}
+	objects := conf.objects()
+	pb := generator.GenGRPCProto(protoPackage, pkgPath, commandsProto, string(matches[1]), cmds, objects)
+	if err := saveBytes(pb, genPath(cwd, "grpc_gen.proto")); err != nil {
+		return err
+	}
+	gf := generator.GenGRPC(goPackage, typ, cmds, objects)
+	return save(gf, genPath(cwd, "grpc_gen.go"))
+}
@@ -0,0 +1,69 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_ports
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+
+	"github.com/dave/jennifer/jen"
+	"golang.org/x/tools/go/packages"
+)
+
+// initMain resolves the package which invokes the generator
+func initMain(typ string) (cwd, goPackage, pkgPath string, err error) {
+	if typ == "" {
+		return "", "", "", fmt.Errorf("'typ' is empty")
+	}
+	cwd, err = os.Getwd()
+	if err != nil {
+		return "", "", "", err
+	}
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName}, cwd)
+	if err != nil {
+		return "", "", "", err
+	}
+	goPackage, pkgPath = pkgs[0].Name, pkgs[0].PkgPath
+	// Prefer the package of the file with go:generate comment
+	if env := os.Getenv("GOPACKAGE"); env != "" {
+		goPackage = env
+	}
+	log.Printf("Generating port in package: %s\n", goPackage)
+	return cwd, goPackage, pkgPath, nil
+}
+
+// save replaces genFile with f
+func save(f *jen.File, genFile string) error {
+	if fileExists(genFile) {
+		if err := os.Remove(genFile); err != nil {
+			return err
+		}
+	}
+	return f.Save(genFile)
+}
+
+// saveBytes replaces genFile with b, used for non-go files
+func saveBytes(b []byte, genFile string) error {
+	if fileExists(genFile) {
+		if err := os.Remove(genFile); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(genFile, b, 0644)
+}
+
+func genPath(cwd, name string) string {
+	return path.Join(cwd, name)
+}
+
+func fileExists(filename string) bool {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return !info.IsDir()
+}
@@ -0,0 +1,33 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_ports
+
+import (
+	appgen "github.com/xoe-labs/ddd-gen/pkg/gen_app/generator"
+	"github.com/xoe-labs/ddd-gen/pkg/gen_ports/generator"
+)
+
+// GenOpenAPI generates the OpenAPI document of the http port into the current working directory
+// the schemas are derived from the protoc-gen-go generated code of the domain commands
+// the variants must match the ones of the application layer, they determine the sentinel errors
+func GenOpenAPI(typ, version string, useTenancy, useScheduling bool, conf *Config) error {
+	cwd, _, _, err := initMain(typ)
+	if err != nil {
+		return err
+	}
+	cmds, err := loadCommands(conf.Commands)
+	if err != nil {
+		return err
+	}
+	fds, docs, err := loadProtos(conf.Domain)
+	if err != nil {
+		return err
+	}
+	variants := appgen.Variants{UseTenancy: useTenancy, UseScheduling: useScheduling}
+	b, err := generator.GenOpenAPI(typ, version, cmds, variants, fds, docs)
+	if err != nil {
+		return err
+	}
+	return saveBytes(b, genPath(cwd, "openapi_gen.yaml"))
+}
@@ -0,0 +1,188 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package gen_ports
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var (
+	rawDescPattern   = regexp.MustCompile(`^file_\w+_rawDesc$`)
+	protoNamePattern = regexp.MustCompile(`(?:^|,)name=(\w+)`)
+)
+
+// loadProtos reads the file descriptors which protoc-gen-go embeds into the
+// generated go code of a package, together with the doc comments of the
+// generated types and fields, keyed by the full proto name
+func loadProtos(pkgPath string) ([]*descriptorpb.FileDescriptorProto, map[string]string, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedFiles}, pkgPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, nil, fmt.Errorf("package '%s' not found", pkgPath)
+	}
+	var fds []*descriptorpb.FileDescriptorProto
+	docs := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, file := range pkgs[0].GoFiles {
+		if !strings.HasSuffix(file, ".pb.go") {
+			continue
+		}
+		astFile, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw, err := rawDesc(astFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if raw == nil {
+			continue
+		}
+		fd := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(raw, fd); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		fds = append(fds, fd)
+		collectDocs(astFile, goNames(fd), docs)
+	}
+	if len(fds) == 0 {
+		return nil, nil, fmt.Errorf("no protoc-gen-go generated files in '%s'", pkgPath)
+	}
+	return fds, docs, nil
+}
+
+// rawDesc returns the raw file descriptor of a protoc-gen-go generated file, depending
+// on the version of protoc-gen-go, it is either a byte slice or a string
+func rawDesc(astFile *ast.File) ([]byte, error) {
+	for _, decl := range astFile.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if !ok || len(vs.Names) != 1 || len(vs.Values) != 1 || !rawDescPattern.MatchString(vs.Names[0].Name) {
+				continue
+			}
+			switch v := vs.Values[0].(type) {
+			case *ast.CompositeLit:
+				b := make([]byte, 0, len(v.Elts))
+				for _, elt := range v.Elts {
+					lit, ok := elt.(*ast.BasicLit)
+					if !ok {
+						return nil, fmt.Errorf("unexpected raw descriptor element")
+					}
+					n, err := strconv.ParseUint(lit.Value, 0, 8)
+					if err != nil {
+						return nil, err
+					}
+					b = append(b, byte(n))
+				}
+				return b, nil
+			default:
+				s, err := stringLit(v)
+				if err != nil {
+					return nil, err
+				}
+				return []byte(s), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// stringLit evaluates a (concatenated) string literal
+func stringLit(expr ast.Expr) (string, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", fmt.Errorf("unexpected raw descriptor literal")
+		}
+		return strconv.Unquote(e.Value)
+	case *ast.BinaryExpr:
+		x, err := stringLit(e.X)
+		if err != nil {
+			return "", err
+		}
+		y, err := stringLit(e.Y)
+		if err != nil {
+			return "", err
+		}
+		return x + y, nil
+	case *ast.ParenExpr:
+		return stringLit(e.X)
+	}
+	return "", fmt.Errorf("unexpected raw descriptor expression")
+}
+
+// goNames maps the go type names generated by protoc-gen-go to the full proto names
+func goNames(fd *descriptorpb.FileDescriptorProto) map[string]string {
+	names := make(map[string]string)
+	var walk func(prefix, goPrefix string, msgs []*descriptorpb.DescriptorProto, enums []*descriptorpb.EnumDescriptorProto)
+	walk = func(prefix, goPrefix string, msgs []*descriptorpb.DescriptorProto, enums []*descriptorpb.EnumDescriptorProto) {
+		for _, e := range enums {
+			names[goPrefix+e.GetName()] = prefix + e.GetName()
+		}
+		for _, m := range msgs {
+			names[goPrefix+m.GetName()] = prefix + m.GetName()
+			walk(prefix+m.GetName()+".", goPrefix+m.GetName()+"_", m.GetNestedType(), m.GetEnumType())
+		}
+	}
+	prefix := ""
+	if fd.GetPackage() != "" {
+		prefix = fd.GetPackage() + "."
+	}
+	walk(prefix, "", fd.GetMessageType(), fd.GetEnumType())
+	return names
+}
+
+// collectDocs collects the doc comments of the generated types and their fields
+func collectDocs(astFile *ast.File, names map[string]string, docs map[string]string) {
+	for _, decl := range astFile.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			full, ok := names[ts.Name.Name]
+			if !ok {
+				continue
+			}
+			doc := ts.Doc
+			if doc == nil {
+				doc = gd.Doc
+			}
+			if doc != nil {
+				docs[full] = strings.TrimSpace(doc.Text())
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				if field.Tag == nil || field.Doc == nil {
+					continue
+				}
+				tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+				if m := protoNamePattern.FindStringSubmatch(tag.Get("protobuf")); m != nil {
+					docs[full+"."+m[1]] = strings.TrimSpace(field.Doc.Text())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright © 2020 David Arnold <dar@xoe.solutions>
+// SPDX-License-Identifier: MIT
+
+package jsonschema
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestVendoredCopy asserts that the copy vendored into the protoc-gen-ddd module
+// matches this package, apart from the comments above its imports
+func TestVendoredCopy(t *testing.T) {
+	want, err := ioutil.ReadFile("jsonschema.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join("..", "..", "cmd", "protoc-gen-ddd", "jsonschema", "jsonschema.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imports := []byte("\nimport (")
+	if !bytes.Equal(got[bytes.Index(got, imports):], want[bytes.Index(want, imports):]) {
+		t.Fatal("cmd/protoc-gen-ddd/jsonschema is out of sync with pkg/jsonschema")
+	}
+}